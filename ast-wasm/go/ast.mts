@@ -1,6 +1,8 @@
 import { fileURLToPath } from "node:url";
 import { dirname, join } from "node:path";
 import { mkdirSync, readFileSync, writeFileSync } from "node:fs";
+import { decodeAst } from "./ast_pb.mts";
+import { getRootAst } from "./ast_fb.mts";
 
 const DIRNAME = dirname(fileURLToPath(import.meta.url));
 const OUTPUT_DIR = join(DIRNAME, "..", "output", "go");
@@ -8,6 +10,10 @@ const fileA = readFileSync(join(DIRNAME, "../example/a.tst"), "utf-8");
 const fileB = readFileSync(join(DIRNAME, "../example/b.tst"), "utf-8");
 const fileC = readFileSync(join(DIRNAME, "../example/c.tst"), "utf-8");
 
+// Serialization used to move the AST across the WASM boundary: "json",
+// "protobuf" or "flatbuffers"
+const FORMAT = process.env.AST_FORMAT ?? "json";
+
 // Import Go WASM module (will be generated)
 let wasmModule: any = null;
 
@@ -57,6 +63,23 @@ async function initWasm() {
 // WASM wrapper function that calls the Go generateAst function
 async function parse(fileContents: string): Promise<any> {
   const module = await initWasm();
+
+  if (FORMAT === "protobuf") {
+    const bytes = module.generateAstProtobuf(fileContents);
+    if (typeof bytes === 'string') {
+      throw new Error(`WASM error: ${bytes}`);
+    }
+    return decodeAst(bytes);
+  }
+
+  if (FORMAT === "flatbuffers") {
+    const bytes = module.generateAstFlatbuffers(fileContents);
+    if (typeof bytes === 'string') {
+      throw new Error(`WASM error: ${bytes}`);
+    }
+    // No decode step: accessors read straight out of the buffer
+    return getRootAst(bytes);
+  }
   
   // Call the Go WASM generateAst function
   const jsonString = module.generateAst(fileContents);
@@ -0,0 +1,239 @@
+// Accessors for the FlatBuffer returned by generateAstFlatbuffers (see
+// ast.fbs). Nothing is decoded up front: every getter reads straight out of
+// the buffer on access. toJSON() produces the same shape as generateAst so the
+// benchmark can write comparable output files.
+
+const textDecoder = new TextDecoder();
+
+class Table {
+  protected readonly view: DataView;
+  protected readonly pos: number;
+
+  constructor(view: DataView, pos: number) {
+    this.view = view;
+    this.pos = pos;
+  }
+
+  // Returns the field's offset within the table, or 0 when it is absent
+  protected offset(field: number): number {
+    const vtable = this.pos - this.view.getInt32(this.pos, true);
+    const entry = 4 + 2 * field;
+    if (entry >= this.view.getUint16(vtable, true)) {
+      return 0;
+    }
+    return this.view.getUint16(vtable + entry, true);
+  }
+
+  protected uint8(field: number): number {
+    const o = this.offset(field);
+    return o ? this.view.getUint8(this.pos + o) : 0;
+  }
+
+  protected int32(field: number): number {
+    const o = this.offset(field);
+    return o ? this.view.getInt32(this.pos + o, true) : 0;
+  }
+
+  // Follows the uoffset stored in a field, returning null when absent
+  protected indirect(field: number): number | null {
+    const o = this.offset(field);
+    if (!o) {
+      return null;
+    }
+    const slot = this.pos + o;
+    return slot + this.view.getUint32(slot, true);
+  }
+
+  protected string(field: number): string {
+    const target = this.indirect(field);
+    if (target === null) {
+      return "";
+    }
+    const length = this.view.getUint32(target, true);
+    const start = this.view.byteOffset + target + 4;
+    return textDecoder.decode(
+      new Uint8Array(this.view.buffer, start, length)
+    );
+  }
+
+  protected node(field: number): ASTNode | null {
+    const target = this.indirect(field);
+    return target === null ? null : new ASTNode(this.view, target);
+  }
+}
+
+export class Token extends Table {
+  get type() {
+    return this.uint8(0);
+  }
+  get value() {
+    return this.string(1);
+  }
+  get line() {
+    return this.int32(2);
+  }
+  get column() {
+    return this.int32(3);
+  }
+  toJSON() {
+    return {
+      type: this.type,
+      value: this.value,
+      line: this.line,
+      column: this.column,
+    };
+  }
+}
+
+export class Program extends Table {
+  get block() {
+    return this.node(0);
+  }
+  toJSON() {
+    return { block: this.block };
+  }
+}
+
+export class StatementBlock extends Table {
+  get statementsLength() {
+    const vector = this.indirect(0);
+    return vector === null ? 0 : this.view.getUint32(vector, true);
+  }
+  statement(index: number) {
+    const slot = this.indirect(0)! + 4 + 4 * index;
+    return new ASTNode(this.view, slot + this.view.getUint32(slot, true));
+  }
+  get statements() {
+    const statements: ASTNode[] = [];
+    for (let i = 0; i < this.statementsLength; i++) {
+      statements.push(this.statement(i));
+    }
+    return statements;
+  }
+  toJSON() {
+    return { statements: this.statements };
+  }
+}
+
+export class VariableStatement extends Table {
+  get identifier() {
+    return this.string(0);
+  }
+  toJSON() {
+    return { identifier: this.identifier };
+  }
+}
+
+export class IfStatement extends Table {
+  get condition() {
+    return this.node(0);
+  }
+  get block() {
+    return this.node(1);
+  }
+  get elseBlock() {
+    return this.node(2);
+  }
+  toJSON() {
+    return {
+      condition: this.condition,
+      block: this.block,
+      elseBlock: this.elseBlock,
+    };
+  }
+}
+
+export class WhileStatement extends Table {
+  get condition() {
+    return this.node(0);
+  }
+  get block() {
+    return this.node(1);
+  }
+  toJSON() {
+    return { condition: this.condition, block: this.block };
+  }
+}
+
+export class AssignmentStatement extends Table {
+  get identifier() {
+    return this.string(0);
+  }
+  get value() {
+    return this.node(1);
+  }
+  toJSON() {
+    return { identifier: this.identifier, value: this.value };
+  }
+}
+
+export class Condition extends Table {
+  get left() {
+    return this.node(0);
+  }
+  get operator() {
+    return this.string(1);
+  }
+  get right() {
+    return this.node(2);
+  }
+  toJSON() {
+    return { left: this.left, operator: this.operator, right: this.right };
+  }
+}
+
+export class Expression extends Table {
+  get leftToken() {
+    const target = this.indirect(0);
+    return target === null ? null : new Token(this.view, target);
+  }
+  get operator() {
+    return this.string(1);
+  }
+  get right() {
+    return this.node(2);
+  }
+  toJSON() {
+    return {
+      leftToken: this.leftToken,
+      operator: this.operator,
+      right: this.right,
+    };
+  }
+}
+
+// Indexed by union type, which is the node type + 1
+const dataTypes = [
+  null,
+  Program,
+  StatementBlock,
+  VariableStatement,
+  IfStatement,
+  WhileStatement,
+  AssignmentStatement,
+  Condition,
+  Expression,
+] as const;
+
+export class ASTNode extends Table {
+  get type() {
+    return this.uint8(0);
+  }
+  get data() {
+    const DataType = dataTypes[this.uint8(1)];
+    const target = this.indirect(2);
+    if (!DataType || target === null) {
+      return null;
+    }
+    return new DataType(this.view, target);
+  }
+  toJSON() {
+    return { type: this.type, data: this.data };
+  }
+}
+
+// getRootAst wraps the bytes returned by generateAstFlatbuffers
+export function getRootAst(bytes: Uint8Array): ASTNode {
+  const view = new DataView(bytes.buffer, bytes.byteOffset, bytes.byteLength);
+  return new ASTNode(view, view.getUint32(0, true));
+}
@@ -0,0 +1,159 @@
+// Decoder for the protobuf messages defined in ast.proto. Produces objects
+// with the same shape as JSON.parse(generateAst(...)) so the two serialization
+// paths can be compared on equal footing.
+
+class Reader {
+  private pos = 0;
+  private readonly bytes: Uint8Array;
+  private static readonly decoder = new TextDecoder();
+
+  constructor(bytes: Uint8Array) {
+    this.bytes = bytes;
+  }
+
+  varint(): number {
+    let result = 0;
+    let shift = 0;
+    let byte: number;
+    do {
+      byte = this.bytes[this.pos++]!;
+      result += (byte & 0x7f) * 2 ** shift;
+      shift += 7;
+    } while (byte & 0x80);
+    return result;
+  }
+
+  string(length: number): string {
+    const value = Reader.decoder.decode(
+      this.bytes.subarray(this.pos, this.pos + length)
+    );
+    this.pos += length;
+    return value;
+  }
+
+  // Reads fields until end, handing each (field, wireType, end) to visit
+  fields(end: number, visit: (field: number, wireType: number) => void) {
+    while (this.pos < end) {
+      const tag = this.varint();
+      visit(tag >>> 3, tag & 0x7);
+    }
+  }
+
+  get position() {
+    return this.pos;
+  }
+}
+
+function decodeToken(reader: Reader, end: number) {
+  const token = { type: 0, value: "", line: 0, column: 0 };
+  reader.fields(end, (field) => {
+    switch (field) {
+      case 1:
+        token.type = reader.varint();
+        break;
+      case 2:
+        token.value = reader.string(reader.varint());
+        break;
+      case 3:
+        token.line = reader.varint();
+        break;
+      case 4:
+        token.column = reader.varint();
+        break;
+    }
+  });
+  return token;
+}
+
+function decodeNode(reader: Reader, end: number): any {
+  let type = 0;
+  let data: any = null;
+  reader.fields(end, (field) => {
+    if (field === 1) {
+      type = reader.varint();
+      return;
+    }
+    const length = reader.varint();
+    data = decodeData(reader, field - 2, reader.position + length);
+  });
+  return { type, data };
+}
+
+function child(reader: Reader) {
+  const length = reader.varint();
+  return decodeNode(reader, reader.position + length);
+}
+
+function decodeData(reader: Reader, nodeType: number, end: number): any {
+  switch (nodeType) {
+    case 0: {
+      const data = { block: null as any };
+      reader.fields(end, () => (data.block = child(reader)));
+      return data;
+    }
+    case 1: {
+      const data = { statements: [] as any[] };
+      reader.fields(end, () => data.statements.push(child(reader)));
+      return data;
+    }
+    case 2: {
+      const data = { identifier: "" };
+      reader.fields(end, () => (data.identifier = reader.string(reader.varint())));
+      return data;
+    }
+    case 3: {
+      const data = { condition: null, block: null, elseBlock: null };
+      reader.fields(end, (field) => {
+        const node = child(reader);
+        if (field === 1) data.condition = node;
+        else if (field === 2) data.block = node;
+        else data.elseBlock = node;
+      });
+      return data;
+    }
+    case 4: {
+      const data = { condition: null, block: null };
+      reader.fields(end, (field) => {
+        const node = child(reader);
+        if (field === 1) data.condition = node;
+        else data.block = node;
+      });
+      return data;
+    }
+    case 5: {
+      const data = { identifier: "", value: null };
+      reader.fields(end, (field) => {
+        if (field === 1) data.identifier = reader.string(reader.varint());
+        else data.value = child(reader);
+      });
+      return data;
+    }
+    case 6: {
+      const data = { left: null, operator: "", right: null };
+      reader.fields(end, (field) => {
+        if (field === 2) data.operator = reader.string(reader.varint());
+        else if (field === 1) data.left = child(reader);
+        else data.right = child(reader);
+      });
+      return data;
+    }
+    case 7: {
+      const data = { leftToken: null as any, operator: "", right: null };
+      reader.fields(end, (field) => {
+        if (field === 1) {
+          const length = reader.varint();
+          data.leftToken = decodeToken(reader, reader.position + length);
+        } else if (field === 2) data.operator = reader.string(reader.varint());
+        else data.right = child(reader);
+      });
+      return data;
+    }
+    default:
+      throw new Error(`Unknown node type ${nodeType}`);
+  }
+}
+
+// decodeAst decodes the bytes returned by generateAstProtobuf
+export function decodeAst(bytes: Uint8Array): any {
+  return decodeNode(new Reader(bytes), bytes.length);
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+	"time"
+
+	"jsconf/internal/bench"
+)
+
+// runAstBenchmark times tokenize + parse + JSON serialization entirely inside
+// Go, so no JS<->WASM boundary crossing is included in the samples. It takes
+// { input, iterations, warmup } and returns { iterations, median, mean, p95 }
+// in milliseconds, summarized by the same bench.Summarize as the other harnesses.
+func runAstBenchmark(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeObject {
+		return js.ValueOf("Error: missing config argument")
+	}
+	config := args[0]
+
+	input, err := inputFromJS(config.Get("input"))
+	if err != nil {
+		return js.ValueOf(fmt.Sprintf("Error: %v", err))
+	}
+
+	iterations := 10
+	if v := config.Get("iterations"); v.Type() == js.TypeNumber {
+		iterations = v.Int()
+	}
+	if iterations < 1 {
+		return js.ValueOf("Error: iterations must be at least 1")
+	}
+
+	warmup := 0
+	if v := config.Get("warmup"); v.Type() == js.TypeNumber {
+		warmup = v.Int()
+	}
+
+	// Warmup iterations run the same work but are excluded from statistics
+	for i := 0; i < warmup; i++ {
+		if _, err := generateAstJSON(input); err != nil {
+			return js.ValueOf(fmt.Sprintf("Error: %v", err))
+		}
+	}
+
+	durations := make([]time.Duration, 0, iterations)
+	for i := 0; i < iterations; i++ {
+		start := time.Now()
+		_, err := generateAstJSON(input)
+		duration := time.Since(start)
+		if err != nil {
+			return js.ValueOf(fmt.Sprintf("Error: %v", err))
+		}
+		durations = append(durations, duration)
+	}
+
+	stats := bench.Summarize(durations)
+	return js.ValueOf(map[string]interface{}{
+		"iterations": iterations,
+		"median":     stats.Median,
+		"mean":       stats.Mean,
+		"p95":        stats.P95,
+	})
+}
+
+// generateAstJSON is the work measured by runAstBenchmark
+func generateAstJSON(input string) ([]byte, error) {
+	tokens := tokenize(input)
+	ast := parse(tokens, parseOptions{})
+	return json.Marshal(ast)
+}
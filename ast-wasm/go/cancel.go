@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// errParseCancelled is raised inside the parser when its handle is cancelled
+// and recovered by generateAst, so the WASM instance keeps running
+var errParseCancelled = errors.New("parse cancelled")
+
+// cancelToken is the flag the parser polls at every statement boundary
+type cancelToken struct {
+	cancelled atomic.Bool
+}
+
+var (
+	cancelTokensMu   sync.Mutex
+	cancelTokens     = map[int]*cancelToken{}
+	nextCancelHandle = 1
+)
+
+// createParseHandle allocates a handle that can be passed to generateAst as
+// options.handle and later to cancelParse
+func createParseHandle(this js.Value, args []js.Value) interface{} {
+	cancelTokensMu.Lock()
+	defer cancelTokensMu.Unlock()
+
+	handle := nextCancelHandle
+	nextCancelHandle++
+	cancelTokens[handle] = &cancelToken{}
+	return js.ValueOf(handle)
+}
+
+// cancelParse flags the parse associated with a handle for cancellation. It
+// returns false when the handle is unknown or its parse has already finished.
+// Go and JS share one thread, so JS can only call it during a parse from that
+// parse's onProgress callback; the parse stops at the next statement boundary
+// after the callback returns. Called before the parse starts, it stops the
+// parse at its first statement.
+func cancelParse(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber {
+		return js.ValueOf(false)
+	}
+
+	token := lookupCancelToken(args[0].Int())
+	if token == nil {
+		return js.ValueOf(false)
+	}
+	token.cancelled.Store(true)
+	return js.ValueOf(true)
+}
+
+func lookupCancelToken(handle int) *cancelToken {
+	cancelTokensMu.Lock()
+	defer cancelTokensMu.Unlock()
+	return cancelTokens[handle]
+}
+
+// releaseParseHandle forgets a handle once the parse using it has returned
+func releaseParseHandle(handle int) {
+	cancelTokensMu.Lock()
+	defer cancelTokensMu.Unlock()
+	delete(cancelTokens, handle)
+}
+
+// cancelAllParses flags every outstanding handle, used when shutting down
+func cancelAllParses() {
+	cancelTokensMu.Lock()
+	defer cancelTokensMu.Unlock()
+	for _, token := range cancelTokens {
+		token.cancelled.Store(true)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import "encoding/binary"
+
+// Minimal FlatBuffers writer for the tables in ast.fbs. Unlike the official
+// builder, which writes back-to-front, this one writes front-to-back in
+// pre-order: a parent table is written first with placeholder offsets that
+// are patched once each child has been appended. Children therefore always
+// live at higher addresses than their parents, which keeps every uoffset
+// positive as the format requires. Each table is preceded by its own vtable
+// and every field occupies a 4-byte slot, trading a little size for
+// simplicity.
+
+type fbWriter struct {
+	buf []byte
+}
+
+// marshalFlatbuffers encodes an AST with ast.ASTNode as the root table
+func marshalFlatbuffers(ast *ASTNode) []byte {
+	w := &fbWriter{buf: make([]byte, 4, 1024)}
+	w.putOffset(0, w.writeNode(ast))
+	return w.buf
+}
+
+func (w *fbWriter) align() {
+	for len(w.buf)%4 != 0 {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *fbWriter) grow(n int) int {
+	pos := len(w.buf)
+	w.buf = append(w.buf, make([]byte, n)...)
+	return pos
+}
+
+// putOffset stores a uoffset at pos pointing forward to target
+func (w *fbWriter) putOffset(pos, target int) {
+	binary.LittleEndian.PutUint32(w.buf[pos:], uint32(target-pos))
+}
+
+// fieldPos returns the position of a field's slot within a table
+func fieldPos(table, field int) int {
+	return table + 4 + 4*field
+}
+
+// writeTable appends a vtable followed by a table with one zeroed slot per
+// field. Fields whose present flag is false are marked absent in the vtable.
+func (w *fbWriter) writeTable(present ...bool) int {
+	w.align()
+	vtable := w.grow(4 + 2*len(present))
+	binary.LittleEndian.PutUint16(w.buf[vtable:], uint16(4+2*len(present)))
+	binary.LittleEndian.PutUint16(w.buf[vtable+2:], uint16(4+4*len(present)))
+	for i, p := range present {
+		if p {
+			binary.LittleEndian.PutUint16(w.buf[vtable+4+2*i:], uint16(4+4*i))
+		}
+	}
+
+	w.align()
+	table := w.grow(4 + 4*len(present))
+	binary.LittleEndian.PutUint32(w.buf[table:], uint32(int32(table-vtable)))
+	return table
+}
+
+func (w *fbWriter) setUint8(table, field int, v uint8) {
+	w.buf[fieldPos(table, field)] = v
+}
+
+func (w *fbWriter) setInt32(table, field int, v int32) {
+	binary.LittleEndian.PutUint32(w.buf[fieldPos(table, field):], uint32(v))
+}
+
+func (w *fbWriter) setString(table, field int, s string) {
+	if s != "" {
+		w.putOffset(fieldPos(table, field), w.writeString(s))
+	}
+}
+
+func (w *fbWriter) setNode(table, field int, n *ASTNode) {
+	if n != nil {
+		w.putOffset(fieldPos(table, field), w.writeNode(n))
+	}
+}
+
+func (w *fbWriter) writeString(s string) int {
+	w.align()
+	pos := w.grow(4)
+	binary.LittleEndian.PutUint32(w.buf[pos:], uint32(len(s)))
+	w.buf = append(w.buf, s...)
+	w.buf = append(w.buf, 0)
+	return pos
+}
+
+func (w *fbWriter) writeToken(t *Token) int {
+	table := w.writeTable(true, t.Value != "", true, true)
+	w.setUint8(table, 0, uint8(t.Type))
+	w.setInt32(table, 2, int32(t.Line))
+	w.setInt32(table, 3, int32(t.Column))
+	w.setString(table, 1, t.Value)
+	return table
+}
+
+func (w *fbWriter) writeNode(n *ASTNode) int {
+	table := w.writeTable(true, true, true)
+	w.setUint8(table, 0, uint8(n.Type))
+	w.setUint8(table, 1, uint8(n.Type)+1)
+
+	var data int
+	switch d := n.Data.(type) {
+	case *ProgramData:
+		data = w.writeTable(d.Block != nil)
+		w.setNode(data, 0, d.Block)
+	case *StatementBlockData:
+		data = w.writeTable(true)
+		w.align()
+		vector := w.grow(4 + 4*len(d.Statements))
+		binary.LittleEndian.PutUint32(w.buf[vector:], uint32(len(d.Statements)))
+		w.putOffset(fieldPos(data, 0), vector)
+		for i, statement := range d.Statements {
+			w.putOffset(vector+4+4*i, w.writeNode(statement))
+		}
+	case *VariableStatementData:
+		data = w.writeTable(d.Identifier != "")
+		w.setString(data, 0, d.Identifier)
+	case *IfStatementData:
+		data = w.writeTable(d.Condition != nil, d.Block != nil, d.ElseBlock != nil)
+		w.setNode(data, 0, d.Condition)
+		w.setNode(data, 1, d.Block)
+		w.setNode(data, 2, d.ElseBlock)
+	case *WhileStatementData:
+		data = w.writeTable(d.Condition != nil, d.Block != nil)
+		w.setNode(data, 0, d.Condition)
+		w.setNode(data, 1, d.Block)
+	case *AssignmentStatementData:
+		data = w.writeTable(d.Identifier != "", d.Value != nil)
+		w.setString(data, 0, d.Identifier)
+		w.setNode(data, 1, d.Value)
+	case *ConditionData:
+		data = w.writeTable(d.Left != nil, d.Operator != "", d.Right != nil)
+		w.setNode(data, 0, d.Left)
+		w.setString(data, 1, d.Operator)
+		w.setNode(data, 2, d.Right)
+	case *ExpressionData:
+		data = w.writeTable(d.LeftToken != nil, d.Operator != "", d.Right != nil)
+		if d.LeftToken != nil {
+			w.putOffset(fieldPos(data, 0), w.writeToken(d.LeftToken))
+		}
+		w.setString(data, 1, d.Operator)
+		w.setNode(data, 2, d.Right)
+	}
+
+	w.putOffset(fieldPos(table, 2), data)
+	return table
+}
@@ -1,3 +1,7 @@
 module jsconf/wasm-ast
 
 go 1.25.1
+
+require jsconf/internal v0.0.0
+
+replace jsconf/internal => ../../internal
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+var errUnsupportedInput = errors.New("input must be a string or a Uint8Array")
+
+// inputFromJS reads the source passed to a generateAst* export. Besides plain
+// strings it accepts UTF-8 bytes in a Uint8Array, which may be a view over a
+// SharedArrayBuffer so a coordinating worker can hand the same source to
+// several WASM workers without each one receiving its own string copy.
+func inputFromJS(v js.Value) (string, error) {
+	if v.Type() == js.TypeString {
+		return v.String(), nil
+	}
+
+	if v.Type() == js.TypeObject && v.InstanceOf(js.Global().Get("Uint8Array")) {
+		bytes := make([]byte, v.Get("length").Int())
+		js.CopyBytesToGo(bytes, v)
+		return string(bytes), nil
+	}
+
+	return "", errUnsupportedInput
+}
+
+// sharedInputSupported reports whether SharedArrayBuffer-backed input can be
+// used in this environment. Browsers only expose SharedArrayBuffer when the
+// page is cross-origin isolated; Node always has it and has no
+// crossOriginIsolated global.
+func sharedInputSupported(this js.Value, args []js.Value) interface{} {
+	global := js.Global()
+	if global.Get("SharedArrayBuffer").Type() != js.TypeFunction {
+		return js.ValueOf(false)
+	}
+
+	isolated := global.Get("crossOriginIsolated")
+	return js.ValueOf(isolated.Type() == js.TypeUndefined || isolated.Truthy())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"syscall/js"
+)
+
+var (
+	// exportedFuncs tracks every js.Func set on the global object so shutdown
+	// can unset and release them
+	exportedFuncs = map[string]js.Func{}
+
+	shutdownOnce sync.Once
+	shutdownCh   = make(chan struct{})
+
+	// terminated is the promise returned by shutdown, resolved once every
+	// export has been released and main is about to return
+	terminated        js.Value
+	resolveTerminated js.Value
+)
+
+// export registers fn on the JS global object under name
+func export(name string, fn func(this js.Value, args []js.Value) interface{}) {
+	f := js.FuncOf(fn)
+	exportedFuncs[name] = f
+	js.Global().Set(name, f)
+}
+
+// shutdown cancels any in-flight parse and asks main to release all exports
+// and exit. It returns a promise that resolves when the Go program has
+// terminated; calling it again returns the same promise.
+func shutdown(this js.Value, args []js.Value) interface{} {
+	shutdownOnce.Do(func() {
+		var executor js.Func
+		executor = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			resolveTerminated = args[0]
+			return nil
+		})
+		terminated = js.Global().Get("Promise").New(executor)
+		executor.Release()
+
+		cancelAllParses()
+		close(shutdownCh)
+	})
+	return terminated
+}
+
+// waitForShutdown blocks until shutdown is called, then releases every
+// exported func and resolves the terminated promise
+func waitForShutdown() {
+	<-shutdownCh
+
+	for name, f := range exportedFuncs {
+		js.Global().Delete(name)
+		f.Release()
+	}
+	exportedFuncs = map[string]js.Func{}
+
+	resolveTerminated.Invoke()
+}
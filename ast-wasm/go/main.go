@@ -43,6 +43,7 @@ type Token struct {
 	Value  string    `json:"value"`
 	Line   int       `json:"line"`
 	Column int       `json:"column"`
+	Offset int       `json:"-"`
 }
 
 // TokenizeState represents the state of the tokenizer
@@ -121,6 +122,10 @@ type Parser struct {
 	tokens            []Token
 	currentTokenIndex int
 	currentToken      *Token
+	nodesBuilt        int
+	statementsParsed  int
+	progress          *progressReporter
+	cancel            *cancelToken
 }
 
 // Character checking functions using direct comparisons (much faster than regex)
@@ -194,6 +199,7 @@ func tokenize(input string) []Token {
 					Value:  string(char),
 					Line:   currentLine,
 					Column: currentColumn,
+					Offset: i,
 				})
 				state = StateSearching
 			} else if char == '+' || char == '-' || char == '*' || char == '/' ||
@@ -220,6 +226,7 @@ func tokenize(input string) []Token {
 					Value:  string(char),
 					Line:   currentLine,
 					Column: currentColumn,
+					Offset: i,
 				})
 				state = StateSearching
 			} else if isDigit(char) {
@@ -261,6 +268,7 @@ func tokenize(input string) []Token {
 					Value:  tokenValue,
 					Line:   stateStartLine,
 					Column: stateStartColumn,
+					Offset: stateStart,
 				})
 				noDynamicNext = true
 				state = StateSearching
@@ -276,6 +284,7 @@ func tokenize(input string) []Token {
 					Value:  tokenValue,
 					Line:   stateStartLine,
 					Column: stateStartColumn,
+					Offset: stateStart,
 				})
 				noDynamicNext = true
 				state = StateSearching
@@ -290,6 +299,7 @@ func tokenize(input string) []Token {
 					Value:  tokenValue,
 					Line:   stateStartLine,
 					Column: stateStartColumn,
+					Offset: stateStart,
 				})
 				noDynamicNext = true
 				state = StateSearching
@@ -305,6 +315,7 @@ func tokenize(input string) []Token {
 		Value:  "EOF",
 		Line:   currentLine,
 		Column: currentColumn,
+		Offset: len(input),
 	})
 
 	return tokens
@@ -319,6 +330,23 @@ func (p *Parser) nextToken() {
 	p.currentToken = &p.tokens[p.currentTokenIndex]
 }
 
+// newNode records a freshly built node so progress reporting can count it
+func (p *Parser) newNode(node *ASTNode) *ASTNode {
+	p.nodesBuilt++
+	return node
+}
+
+// endStatement runs the per-statement hooks (cancellation, progress) once a statement has been parsed
+func (p *Parser) endStatement() {
+	p.statementsParsed++
+	if p.cancel != nil && p.cancel.cancelled.Load() {
+		panic(errParseCancelled)
+	}
+	if p.progress != nil {
+		p.progress.report(p)
+	}
+}
+
 func (p *Parser) accept(tokenType TokenType) bool {
 	if p.currentToken.Type == tokenType {
 		p.nextToken()
@@ -342,14 +370,14 @@ func (p *Parser) parseExpression() *ASTNode {
 	leftToken := *p.currentToken
 
 	if p.accept(TokenNumber) || p.accept(TokenString) || p.accept(TokenIdentifier) {
-		node := &ASTNode{
+		node := p.newNode(&ASTNode{
 			Type: NodeExpression,
 			Data: &ExpressionData{
 				LeftToken: &leftToken,
 				Operator:  "",
 				Right:     nil,
 			},
-		}
+		})
 
 		data := node.Data.(*ExpressionData)
 		if p.accept(TokenPlus) {
@@ -375,14 +403,14 @@ func (p *Parser) parseExpression() *ASTNode {
 
 func (p *Parser) parseCondition() *ASTNode {
 	leftNode := p.parseExpression()
-	node := &ASTNode{
+	node := p.newNode(&ASTNode{
 		Type: NodeCondition,
 		Data: &ConditionData{
 			Left:     leftNode,
 			Operator: "",
 			Right:    nil,
 		},
-	}
+	})
 
 	data := node.Data.(*ConditionData)
 	if p.accept(TokenGreater) {
@@ -406,12 +434,12 @@ func (p *Parser) parseStatement() *ASTNode {
 	if p.accept(TokenVar) {
 		identifier := p.currentToken.Value
 		p.expect(TokenIdentifier)
-		return &ASTNode{
+		return p.newNode(&ASTNode{
 			Type: NodeVariableStatement,
 			Data: &VariableStatementData{
 				Identifier: identifier,
 			},
-		}
+		})
 	} else if p.accept(TokenIf) {
 		p.expect(TokenLParen)
 		conditionNode := p.parseCondition()
@@ -427,14 +455,14 @@ func (p *Parser) parseStatement() *ASTNode {
 			p.expect(TokenRBrace)
 		}
 
-		return &ASTNode{
+		return p.newNode(&ASTNode{
 			Type: NodeIfStatement,
 			Data: &IfStatementData{
 				Condition: conditionNode,
 				Block:     blockNode,
 				ElseBlock: elseBlockNode,
 			},
-		}
+		})
 	} else if p.accept(TokenWhile) {
 		p.expect(TokenLParen)
 		conditionNode := p.parseCondition()
@@ -443,26 +471,26 @@ func (p *Parser) parseStatement() *ASTNode {
 		blockNode := p.parseStatementBlock()
 		p.expect(TokenRBrace)
 
-		return &ASTNode{
+		return p.newNode(&ASTNode{
 			Type: NodeWhileStatement,
 			Data: &WhileStatementData{
 				Condition: conditionNode,
 				Block:     blockNode,
 			},
-		}
+		})
 	} else if p.peek(TokenIdentifier) {
 		identifier := p.currentToken.Value
 		p.accept(TokenIdentifier)
 		p.expect(TokenEqual)
 		expressionNode := p.parseExpression()
 
-		return &ASTNode{
+		return p.newNode(&ASTNode{
 			Type: NodeAssignmentStatement,
 			Data: &AssignmentStatementData{
 				Identifier: identifier,
 				Value:      expressionNode,
 			},
-		}
+		})
 	} else {
 		panic(fmt.Sprintf("statement (%d:%d): unexpected symbol %d",
 			p.currentToken.Line, p.currentToken.Column, p.currentToken.Type))
@@ -474,17 +502,18 @@ func (p *Parser) parseStatementBlock() *ASTNode {
 
 	for {
 		statements = append(statements, p.parseStatement())
+		p.endStatement()
 		if !p.accept(TokenSemicolon) {
 			break
 		}
 	}
 
-	return &ASTNode{
+	return p.newNode(&ASTNode{
 		Type: NodeStatementBlock,
 		Data: &StatementBlockData{
 			Statements: statements,
 		},
-	}
+	})
 }
 
 func (p *Parser) parseProgram() *ASTNode {
@@ -495,52 +524,139 @@ func (p *Parser) parseProgram() *ASTNode {
 			p.currentToken.Line, p.currentToken.Column, p.currentToken.Type))
 	}
 
-	return &ASTNode{
+	return p.newNode(&ASTNode{
 		Type: NodeProgram,
 		Data: &ProgramData{
 			Block: block,
 		},
+	})
+}
+
+// parseOptions holds the optional hooks a caller can attach to a parse
+type parseOptions struct {
+	progress *progressReporter
+	cancel   *cancelToken
+	handle   int
+}
+
+// parseOptionsFromJS reads the optional options object passed to generateAst,
+// e.g. { onProgress, progressInterval, handle }
+func parseOptionsFromJS(options js.Value) parseOptions {
+	var opts parseOptions
+	if options.Type() != js.TypeObject {
+		return opts
+	}
+
+	opts.progress = newProgressReporter(options)
+	if handle := options.Get("handle"); handle.Type() == js.TypeNumber {
+		opts.handle = handle.Int()
+		opts.cancel = lookupCancelToken(opts.handle)
 	}
+	return opts
 }
 
 // parse creates an AST from tokens
-func parse(tokens []Token) *ASTNode {
+func parse(tokens []Token, opts parseOptions) *ASTNode {
 	parser := &Parser{
 		tokens:            tokens,
 		currentTokenIndex: 0,
 		currentToken:      &tokens[0],
+		progress:          opts.progress,
+		cancel:            opts.cancel,
 	}
 
-	return parser.parseProgram()
+	ast := parser.parseProgram()
+	if parser.progress != nil {
+		parser.progress.finish(parser)
+	}
+	return ast
 }
 
-// generateAst is the WASM export function that combines tokenize and parse
-func generateAst(this js.Value, args []js.Value) interface{} {
+// withParsedAst tokenizes and parses the input passed from JS, honoring the
+// optional options object, and hands the AST to serialize for the return value
+func withParsedAst(args []js.Value, serialize func(ast *ASTNode) interface{}) (result interface{}) {
 	if len(args) < 1 {
 		return js.ValueOf("Error: missing input argument")
 	}
 
-	input := args[0].String()
+	input, err := inputFromJS(args[0])
+	if err != nil {
+		return js.ValueOf(fmt.Sprintf("Error: %v", err))
+	}
+
+	var opts parseOptions
+	if len(args) > 1 {
+		opts = parseOptionsFromJS(args[1])
+	}
+	if opts.cancel != nil {
+		defer releaseParseHandle(opts.handle)
+	}
+
+	// A cancelled parse unwinds with a panic; turn it back into an error string
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errParseCancelled {
+				panic(r)
+			}
+			result = js.ValueOf(fmt.Sprintf("Error: %v", errParseCancelled))
+		}
+	}()
 
 	// Tokenize
 	tokens := tokenize(input)
 
 	// Parse
-	ast := parse(tokens)
+	ast := parse(tokens, opts)
 
-	// Serialize to JSON
-	jsonBytes, err := json.Marshal(ast)
-	if err != nil {
-		return js.ValueOf(fmt.Sprintf("Error: %v", err))
-	}
+	return serialize(ast)
+}
 
-	return js.ValueOf(string(jsonBytes))
+// generateAst is the WASM export function that combines tokenize and parse
+func generateAst(this js.Value, args []js.Value) interface{} {
+	return withParsedAst(args, func(ast *ASTNode) interface{} {
+		// Serialize to JSON
+		jsonBytes, err := json.Marshal(ast)
+		if err != nil {
+			return js.ValueOf(fmt.Sprintf("Error: %v", err))
+		}
+
+		return js.ValueOf(string(jsonBytes))
+	})
 }
 
-func main() {
-	// Register the generateAst function for WASM
-	js.Global().Set("generateAst", js.FuncOf(generateAst))
+// generateAstProtobuf is like generateAst but returns the AST as an
+// ast.ASTNode protobuf message (see ast.proto) in a Uint8Array
+func generateAstProtobuf(this js.Value, args []js.Value) interface{} {
+	return withParsedAst(args, func(ast *ASTNode) interface{} {
+		encoded := marshalProtobuf(ast)
+		bytes := js.Global().Get("Uint8Array").New(len(encoded))
+		js.CopyBytesToJS(bytes, encoded)
+		return bytes
+	})
+}
+
+// generateAstFlatbuffers is like generateAst but returns the AST as a
+// FlatBuffer (see ast.fbs) that JS can read in place without decoding
+func generateAstFlatbuffers(this js.Value, args []js.Value) interface{} {
+	return withParsedAst(args, func(ast *ASTNode) interface{} {
+		encoded := marshalFlatbuffers(ast)
+		bytes := js.Global().Get("Uint8Array").New(len(encoded))
+		js.CopyBytesToJS(bytes, encoded)
+		return bytes
+	})
+}
 
-	// Keep the program running
-	select {}
+func main() {
+	// Register the WASM exports
+	export("generateAst", generateAst)
+	export("generateAstProtobuf", generateAstProtobuf)
+	export("generateAstFlatbuffers", generateAstFlatbuffers)
+	export("createParseHandle", createParseHandle)
+	export("cancelParse", cancelParse)
+	export("sharedInputSupported", sharedInputSupported)
+	export("runAstBenchmark", runAstBenchmark)
+	export("shutdown", shutdown)
+
+	// Keep the program running until shutdown() is called
+	waitForShutdown()
 }
@@ -0,0 +1,54 @@
+package main
+
+import "syscall/js"
+
+// defaultProgressInterval is how many statements are parsed between progress
+// callbacks when the caller doesn't specify an interval
+const defaultProgressInterval = 1000
+
+// progressReporter invokes a JS callback every N statements with the number of
+// input bytes consumed and AST nodes built so far
+type progressReporter struct {
+	callback js.Value
+	interval int
+}
+
+// newProgressReporter builds a reporter from the options object passed to
+// generateAst, e.g. { onProgress: (p) => ..., progressInterval: 500 }. It
+// returns nil when no callback was supplied so the parser can skip reporting.
+func newProgressReporter(options js.Value) *progressReporter {
+	callback := options.Get("onProgress")
+	if callback.Type() != js.TypeFunction {
+		return nil
+	}
+
+	interval := defaultProgressInterval
+	if v := options.Get("progressInterval"); v.Type() == js.TypeNumber && v.Int() > 0 {
+		interval = v.Int()
+	}
+
+	return &progressReporter{
+		callback: callback,
+		interval: interval,
+	}
+}
+
+// report is called at every statement boundary and forwards to JS on interval
+func (r *progressReporter) report(p *Parser) {
+	if p.statementsParsed%r.interval != 0 {
+		return
+	}
+	r.send(p.currentToken.Offset, p.nodesBuilt)
+}
+
+// finish sends a final update once parsing has consumed the entire input
+func (r *progressReporter) finish(p *Parser) {
+	r.send(p.currentToken.Offset, p.nodesBuilt)
+}
+
+func (r *progressReporter) send(bytesConsumed, nodesBuilt int) {
+	r.callback.Invoke(map[string]interface{}{
+		"bytesConsumed": bytesConsumed,
+		"nodesBuilt":    nodesBuilt,
+	})
+}
@@ -0,0 +1,190 @@
+package main
+
+// Hand-written protobuf encoder for the messages in ast.proto. Encoding is
+// done in two passes: the first walks the tree in pre-order and records the
+// size of every node's data message, the second walks it again in the same
+// order and writes bytes, so each length prefix is known before its payload.
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+type protoEncoder struct {
+	sizes []int
+	next  int
+	buf   []byte
+}
+
+// marshalProtobuf encodes an AST as an ast.ASTNode message
+func marshalProtobuf(ast *ASTNode) []byte {
+	e := &protoEncoder{}
+	size := e.nodeSize(ast)
+	e.buf = make([]byte, 0, size)
+	e.appendNode(ast)
+	return e.buf
+}
+
+func varintSize(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+// All field numbers in ast.proto are below 16, so every tag is one byte
+func varintFieldSize(v uint64) int {
+	if v == 0 {
+		return 0
+	}
+	return 1 + varintSize(v)
+}
+
+func bytesFieldSize(n int) int {
+	return 1 + varintSize(uint64(n)) + n
+}
+
+func stringFieldSize(s string) int {
+	if s == "" {
+		return 0
+	}
+	return bytesFieldSize(len(s))
+}
+
+func tokenSize(t *Token) int {
+	return varintFieldSize(uint64(t.Type)) +
+		stringFieldSize(t.Value) +
+		varintFieldSize(uint64(t.Line)) +
+		varintFieldSize(uint64(t.Column))
+}
+
+// nodeMessageSize is the size of an ASTNode message given its data size
+func nodeMessageSize(nodeType NodeType, dataSize int) int {
+	return varintFieldSize(uint64(nodeType)) + bytesFieldSize(dataSize)
+}
+
+// nodeSize records the data size of n and its descendants in pre-order and
+// returns the size of the encoded ASTNode message
+func (e *protoEncoder) nodeSize(n *ASTNode) int {
+	index := len(e.sizes)
+	e.sizes = append(e.sizes, 0)
+
+	var size int
+	switch data := n.Data.(type) {
+	case *ProgramData:
+		size = e.childSize(data.Block)
+	case *StatementBlockData:
+		for _, statement := range data.Statements {
+			size += e.childSize(statement)
+		}
+	case *VariableStatementData:
+		size = stringFieldSize(data.Identifier)
+	case *IfStatementData:
+		size = e.childSize(data.Condition) + e.childSize(data.Block) + e.childSize(data.ElseBlock)
+	case *WhileStatementData:
+		size = e.childSize(data.Condition) + e.childSize(data.Block)
+	case *AssignmentStatementData:
+		size = stringFieldSize(data.Identifier) + e.childSize(data.Value)
+	case *ConditionData:
+		size = e.childSize(data.Left) + stringFieldSize(data.Operator) + e.childSize(data.Right)
+	case *ExpressionData:
+		size = bytesFieldSize(tokenSize(data.LeftToken)) + stringFieldSize(data.Operator) + e.childSize(data.Right)
+	}
+
+	e.sizes[index] = size
+	return nodeMessageSize(n.Type, size)
+}
+
+func (e *protoEncoder) childSize(n *ASTNode) int {
+	if n == nil {
+		return 0
+	}
+	return bytesFieldSize(e.nodeSize(n))
+}
+
+func (e *protoEncoder) appendVarint(v uint64) {
+	for v >= 0x80 {
+		e.buf = append(e.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	e.buf = append(e.buf, byte(v))
+}
+
+func (e *protoEncoder) appendTag(field int, wireType int) {
+	e.buf = append(e.buf, byte(field<<3|wireType))
+}
+
+func (e *protoEncoder) appendVarintField(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.appendTag(field, wireVarint)
+	e.appendVarint(v)
+}
+
+func (e *protoEncoder) appendStringField(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.appendTag(field, wireBytes)
+	e.appendVarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *protoEncoder) appendToken(field int, t *Token) {
+	e.appendTag(field, wireBytes)
+	e.appendVarint(uint64(tokenSize(t)))
+	e.appendVarintField(1, uint64(t.Type))
+	e.appendStringField(2, t.Value)
+	e.appendVarintField(3, uint64(t.Line))
+	e.appendVarintField(4, uint64(t.Column))
+}
+
+func (e *protoEncoder) appendChild(field int, n *ASTNode) {
+	if n == nil {
+		return
+	}
+	e.appendTag(field, wireBytes)
+	e.appendVarint(uint64(nodeMessageSize(n.Type, e.sizes[e.next])))
+	e.appendNode(n)
+}
+
+func (e *protoEncoder) appendNode(n *ASTNode) {
+	size := e.sizes[e.next]
+	e.next++
+
+	e.appendVarintField(1, uint64(n.Type))
+	e.appendTag(int(n.Type)+2, wireBytes)
+	e.appendVarint(uint64(size))
+
+	switch data := n.Data.(type) {
+	case *ProgramData:
+		e.appendChild(1, data.Block)
+	case *StatementBlockData:
+		for _, statement := range data.Statements {
+			e.appendChild(1, statement)
+		}
+	case *VariableStatementData:
+		e.appendStringField(1, data.Identifier)
+	case *IfStatementData:
+		e.appendChild(1, data.Condition)
+		e.appendChild(2, data.Block)
+		e.appendChild(3, data.ElseBlock)
+	case *WhileStatementData:
+		e.appendChild(1, data.Condition)
+		e.appendChild(2, data.Block)
+	case *AssignmentStatementData:
+		e.appendStringField(1, data.Identifier)
+		e.appendChild(2, data.Value)
+	case *ConditionData:
+		e.appendChild(1, data.Left)
+		e.appendStringField(2, data.Operator)
+		e.appendChild(3, data.Right)
+	case *ExpressionData:
+		e.appendToken(1, data.LeftToken)
+		e.appendStringField(2, data.Operator)
+		e.appendChild(3, data.Right)
+	}
+}
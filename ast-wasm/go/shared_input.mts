@@ -0,0 +1,29 @@
+// Helpers for handing one source to several Go WASM workers. When
+// SharedArrayBuffer is usable the UTF-8 bytes are written once into shared
+// memory and every worker receives a view over the same buffer; otherwise
+// (e.g. a page that isn't cross-origin isolated) each worker gets the plain
+// string, which generateAst accepts as before.
+
+const encoder = new TextEncoder();
+
+export type SharedSource = Uint8Array | string;
+
+export function canShareInput(): boolean {
+  if (typeof SharedArrayBuffer !== "function") {
+    return false;
+  }
+  const isolated = (globalThis as any).crossOriginIsolated;
+  return isolated === undefined || isolated === true;
+}
+
+// toSharedSource encodes source into a SharedArrayBuffer-backed view that can
+// be posted to workers without copying, falling back to the string itself
+export function toSharedSource(source: string): SharedSource {
+  if (!canShareInput()) {
+    return source;
+  }
+  const bytes = encoder.encode(source);
+  const shared = new Uint8Array(new SharedArrayBuffer(bytes.length));
+  shared.set(bytes);
+  return shared;
+}
@@ -10,7 +10,7 @@ const BASE_DIR = dirname(fileURLToPath(import.meta.url));
 const commands = {
   go: {
     setupCommands: [
-      `GOOS=js GOARCH=wasm go build -o main.wasm main.go`,
+      `GOOS=js GOARCH=wasm go build -o main.wasm .`,
     ],
     command: "npx",
     args: ["tsx", "ast.mts"],
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"embed"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"time"
+
+	"jsconf/internal/bench"
 )
 
 // TokenType represents the type of a token
@@ -517,6 +521,11 @@ func parse(tokens []Token) *ASTNode {
 	return parser.parseProgram()
 }
 
+// profiles are the bundled configurations selected with -bench -profile
+//
+//go:embed profiles/*.json
+var profiles embed.FS
+
 // readFile reads the entire content of a file
 func readFile(filename string) (string, error) {
 	file, err := os.Open(filename)
@@ -534,6 +543,19 @@ func readFile(filename string) (string, error) {
 }
 
 func main() {
+	benchFlag := flag.Bool("bench", false, "time the registered benchmarks with the shared harness, configured by the other flags, instead of printing one run's totals")
+	var harness bench.Flags
+	harness.Define(flag.CommandLine)
+	flag.Parse()
+
+	if *benchFlag {
+		if err := bench.RunSuite(bench.Suite{Name: "ast", Dataset: "../example/{a,b,c}.tst", Profiles: profiles}, harness); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create output directory
 	outputDir := "../output/go"
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
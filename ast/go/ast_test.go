@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"jsconf/internal/bench"
+)
+
+// These wrap the phases timed by -bench in testing.B so the usual go test
+// flags work, e.g. go test -bench . -benchmem -cpuprofile cpu.out. Each runs
+// once per example file.
+
+var exampleFiles = []string{"a.tst", "b.tst", "c.tst"}
+
+func forEachExample(b *testing.B, run func(b *testing.B, source string)) {
+	for _, name := range exampleFiles {
+		source, err := readFile("../example/" + name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(source)))
+			run(b, source)
+		})
+	}
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	forEachExample(b, func(b *testing.B, source string) {
+		for b.Loop() {
+			tokenize(source)
+		}
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	forEachExample(b, func(b *testing.B, source string) {
+		tokens := tokenize(source)
+		for b.Loop() {
+			parse(tokens)
+		}
+	})
+}
+
+func BenchmarkMarshalAST(b *testing.B) {
+	forEachExample(b, func(b *testing.B, source string) {
+		ast := parse(tokenize(source))
+		for b.Loop() {
+			if _, err := json.MarshalIndent(ast, "", "  "); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	for _, name := range bench.ProfileNames {
+		data, err := bench.ReadProfile(profiles, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var config bench.SuiteConfig
+		if err = bench.DecodeConfig(data, &config); err == nil {
+			err = config.Validate()
+		}
+		if err != nil {
+			t.Errorf("profile %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"jsconf/internal/bench"
+)
+
+// The example files are registered with the shared harness for -bench, each
+// parsed and marshaled separately
+func init() {
+	for _, file := range []string{"a.tst", "b.tst", "c.tst"} {
+		bench.Register("Parse "+file, []string{"parse", file}, func() bench.Benchmark {
+			return &astBenchmark{name: "Parse " + file, source: readExample(file)}
+		})
+		bench.Register("Marshal "+file, []string{"marshal", file}, func() bench.Benchmark {
+			return &astBenchmark{name: "Marshal " + file, source: readExample(file), marshal: true}
+		})
+	}
+}
+
+// readExample reads an example file for a benchmark
+func readExample(name string) string {
+	source, err := readFile("../example/" + name)
+	if err != nil {
+		panic(fmt.Sprintf("Could not read example/%s: %v", name, err))
+	}
+	return source
+}
+
+// astBenchmark times one phase of processing an example file for -bench.
+// Parsing tokenizes and parses the source, and marshaling serializes the AST
+// parsed during Setup.
+type astBenchmark struct {
+	name    string
+	source  string
+	marshal bool
+	ast     *ASTNode
+	astJSON []byte
+}
+
+func (b *astBenchmark) Name() string {
+	return b.name
+}
+
+func (b *astBenchmark) Setup() {
+	b.astJSON = nil
+	if b.marshal {
+		if b.ast == nil {
+			b.ast = parse(tokenize(b.source))
+		}
+	} else {
+		b.ast = nil
+	}
+}
+
+func (b *astBenchmark) Run() {
+	if !b.marshal {
+		b.ast = parse(tokenize(b.source))
+		return
+	}
+	astJSON, err := json.MarshalIndent(b.ast, "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("Could not serialize AST: %v", err))
+	}
+	b.astJSON = astJSON
+}
+
+func (b *astBenchmark) Verify() error {
+	if b.ast == nil || b.ast.Type != NodeProgram {
+		return fmt.Errorf("expected a program node")
+	}
+	if b.marshal && !json.Valid(b.astJSON) {
+		return fmt.Errorf("serialized AST is not valid JSON")
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFindRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, rootMarker), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "sort", "go")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, start := range []string{root, dir} {
+		got, err := findRoot(start)
+		if err != nil || got != root {
+			t.Errorf("findRoot(%s) = %s, %v, want %s", start, got, err, root)
+		}
+	}
+	if _, err := findRoot(filepath.Dir(root)); err == nil {
+		t.Error("findRoot found a root outside the repository")
+	}
+}
+
+func TestSuites(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := findRoot(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range suiteNames {
+		s, err := lookupSuite(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(root, s.dir, "go.mod")); err != nil {
+			t.Errorf("suite %s: %v", name, err)
+		}
+	}
+}
+
+func TestParseSweep(t *testing.T) {
+	cells, err := parseSweep("50,off", "1,4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sweepCell{{"50", "1"}, {"50", "4"}, {"off", "1"}, {"off", "4"}}
+	if !slices.Equal(cells, want) {
+		t.Errorf("parseSweep = %v, want %v", cells, want)
+	}
+	if got := cells[3].path("out.json"); got != "out.gogcoff.procs4.json" {
+		t.Errorf("path = %s, want out.gogcoff.procs4.json", got)
+	}
+	if got := cells[3].label(); got != "GOGC off, GOMAXPROCS 4" {
+		t.Errorf("label = %q, want %q", got, "GOGC off, GOMAXPROCS 4")
+	}
+
+	if cells, err := parseSweep("", "2"); err != nil || len(cells) != 1 || cells[0].path("out.json") != "out.procs2.json" {
+		t.Errorf("parseSweep with only GOMAXPROCS = %v, %v, want one cell", cells, err)
+	}
+	for _, bad := range [][2]string{{"-1", ""}, {"50,50", ""}, {"", "0"}, {"", "four"}} {
+		if _, err := parseSweep(bad[0], bad[1]); err == nil {
+			t.Errorf("parseSweep(%q, %q) accepted invalid values", bad[0], bad[1])
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module jsconf/benchctl
+
+go 1.25.1
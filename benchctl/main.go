@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+const usage = `Usage:
+  benchctl run suite [flags] [-- suite flags]
+  benchctl list [suite...]
+  benchctl compare [flags] baseline.json current.json
+  benchctl report [flags] results.json...
+  benchctl merge [flags] [label=]results.json...
+  benchctl generate-data [flags]
+
+benchctl runs the benchmark suites (sort and ast) and the tools that read
+their results from anywhere in the repository. Paths are relative to the
+current directory, and each suite reads its config.json unless -config or
+-profile is given.
+
+run runs a suite with the shared harness flags, plus any flags of its own
+after --, e.g. benchctl run sort -results out.json -- -algos quick. With
+-repeat 5 it runs the whole suite five times, keeping each repetition next
+to -results, e.g. out.rep1.json, and pools them into -results. With
+-gogc 50,100,off and -gomaxprocs 1,4 it runs the suite with every
+combination of those settings, keeping each next to -results, e.g.
+out.gogc100.procs4.json, then prints a grid of the medians and merges them
+into -results.
+list lists the benchmarks each suite, or the named ones, would run.
+compare, report and merge take the same flags as the tools of those names.
+generate-data writes a generated sort dataset for every language to read.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	wd, err := os.Getwd()
+	if err == nil {
+		var root string
+		if root, err = findRoot(wd); err == nil {
+			err = dispatch(root, os.Args[1], os.Args[2:])
+		}
+	}
+	// A tool that failed has already said why, so only its status is passed on
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func dispatch(root, command string, args []string) error {
+	switch command {
+	case "run":
+		return runSuite(root, args)
+	case "list":
+		return listSuites(root, args)
+	case "compare", "report", "merge":
+		cmd, err := tool(root, command, "", args...)
+		if err != nil {
+			return err
+		}
+		return cmd.Run()
+	case "generate-data":
+		return generateData(root, args)
+	}
+	fmt.Fprint(os.Stderr, usage)
+	os.Exit(2)
+	return nil
+}
+
+// lookupSuite returns the suite called name
+func lookupSuite(name string) (suite, error) {
+	s, ok := suites[name]
+	if !ok {
+		return suite{}, fmt.Errorf("unknown suite %q, expected one of %s", name, strings.Join(suiteNames, ", "))
+	}
+	return s, nil
+}
+
+// runFlags are the flags run accepts for every suite and passes on to it
+var runFlags = []struct {
+	name, usage string
+	// path flags are made absolute before the suite runs in its directory
+	path bool
+}{
+	{"config", "read the suite's settings from this file (default the suite's config.json)", true},
+	{"profile", "read the suite's settings from this bundled configuration instead of -config: quick, full or ci", false},
+	{"results", "write results as JSON to this file", true},
+	{"out-csv", "write one row per benchmark iteration as CSV to this file", true},
+	{"out-benchstat", "write every sample in the go test -bench format read by benchstat to this file", true},
+	{"cpuprofile-dir", "write a pprof CPU profile of each benchmark's timed iterations to this directory", true},
+	{"memprofile-dir", "write heap profiles from before and after each benchmark's timed iterations to this directory", true},
+	{"trace-dir", "write an execution trace of each benchmark's timed iterations to this directory", true},
+	{"push-url", "POST the results document to this URL", false},
+	{"log-format", "log format: text, or json for one object per record on stderr", false},
+}
+
+// headerFlag collects the repeatable -push-header flag
+type headerFlag []string
+
+func (h *headerFlag) String() string     { return strings.Join(*h, ", ") }
+func (h *headerFlag) Set(s string) error { *h = append(*h, s); return nil }
+
+func runSuite(root string, args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("run: expected a suite, one of %s", strings.Join(suiteNames, ", "))
+	}
+	s, err := lookupSuite(args[0])
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("run "+args[0], flag.ExitOnError)
+	values := make([]*string, len(runFlags))
+	for i, f := range runFlags {
+		values[i] = flags.String(f.name, "", f.usage)
+	}
+	var headers headerFlag
+	flags.Var(&headers, "push-header", "header sent with -push-url as \"Name: value\" (repeatable)")
+	repeat := flags.Int("repeat", 1, "run the whole suite this many times and pool the repetitions into -results, reporting how benchmarks varied between runs apart from within them")
+	gogc := flags.String("gogc", "", "sweep the suite over these comma-separated GOGC values, e.g. 50,100,200,off, keeping each setting's results next to -results and merging them into it")
+	gomaxprocs := flags.String("gomaxprocs", "", "sweep the suite over these comma-separated GOMAXPROCS values, e.g. 1,2,4, with every -gogc value")
+	rebootBetween := flags.Bool("reboot-between", false, "with -repeat, prompt between repetitions so the machine can be rebooted; running the same command again resumes")
+	flags.Parse(args[1:])
+	sweeping := *gogc != "" || *gomaxprocs != ""
+	if sweeping && *repeat > 1 {
+		return errors.New("-repeat can't be combined with -gogc or -gomaxprocs")
+	}
+
+	suiteArgs := append([]string{}, s.args...)
+	var results string
+	for i, f := range runFlags {
+		value := *values[i]
+		if value == "" {
+			continue
+		}
+		if f.path {
+			if value, err = absPath(value); err != nil {
+				return err
+			}
+		}
+		if f.name == "results" {
+			results = value
+			continue
+		}
+		if (*repeat > 1 || sweeping) && slices.Contains(repeatRejects, f.name) {
+			return fmt.Errorf("-%s can't be combined with -repeat, -gogc or -gomaxprocs, which only write -results", f.name)
+		}
+		suiteArgs = append(suiteArgs, "-"+f.name+"="+value)
+	}
+	for _, h := range headers {
+		suiteArgs = append(suiteArgs, "-push-header="+h)
+	}
+	suiteArgs = append(suiteArgs, flags.Args()...)
+
+	dir := filepath.Join(root, s.dir)
+	if sweeping {
+		cells, err := parseSweep(*gogc, *gomaxprocs)
+		if err != nil {
+			return err
+		}
+		if results == "" || results == "-" {
+			return errors.New("-gogc and -gomaxprocs need -results, next to which each setting's results are kept")
+		}
+		return runSweep(root, s.dir, suiteArgs, results, cells)
+	}
+	if *repeat <= 1 {
+		if results != "" {
+			suiteArgs = append(suiteArgs, "-results="+results)
+		}
+		return runTool(root, s.dir, dir, nil, suiteArgs...)
+	}
+	if results == "" || results == "-" {
+		return errors.New("-repeat needs -results, next to which each repetition is kept")
+	}
+
+	// Each repetition is kept in its own file, so after a reboot the
+	// repetitions already complete are skipped
+	reps := make([]string, *repeat)
+	for i := range reps {
+		reps[i] = strings.TrimSuffix(results, ".json") + fmt.Sprintf(".rep%d.json", i+1)
+	}
+	for i, rep := range reps {
+		if complete(rep) {
+			fmt.Fprintf(os.Stderr, "Repetition %d of %d is already in %s\n", i+1, *repeat, rep)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Repetition %d of %d\n", i+1, *repeat)
+		if err := runTool(root, s.dir, dir, nil, slices.Concat(suiteArgs, []string{"-results=" + rep})...); err != nil {
+			return err
+		}
+		if *rebootBetween && i < len(reps)-1 {
+			fmt.Fprintf(os.Stderr, "Repetition %d of %d is done. Reboot now and run the same command again to resume, or press Enter to go on without rebooting.\n", i+1, *repeat)
+			bufio.NewReader(os.Stdin).ReadString('\n')
+		}
+	}
+	cmd, err := tool(root, "merge", "", slices.Concat([]string{"-repetitions", "-o", results}, reps)...)
+	if err != nil {
+		return err
+	}
+	return cmd.Run()
+}
+
+// repeatRejects are the run flags whose outputs -repeat would overwrite
+// with each repetition
+var repeatRejects = []string{"out-csv", "out-benchstat", "push-url"}
+
+// complete reports whether path holds a results document that wasn't
+// interrupted
+func complete(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var results struct {
+		Partial bool `json:"partial"`
+	}
+	return json.Unmarshal(data, &results) == nil && !results.Partial
+}
+
+// runTool runs the suite or tool at dir in workDir with args, adding env
+// to its environment, and waits for it. Suites write partial results when interrupted, so ctrl-C reaches the
+// suite directly, and SIGTERM is passed on.
+func runTool(root, dir, workDir string, env []string, args ...string) error {
+	cmd, err := tool(root, dir, workDir, args...)
+	if err != nil {
+		return err
+	}
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGTERM {
+				cmd.Process.Signal(sig)
+			}
+		}
+	}()
+	return cmd.Wait()
+}
+
+// listSuites prints each benchmark of the named suites, or all of them, as
+// "suite<TAB>benchmark", followed by its tags for suites that have them
+func listSuites(root string, names []string) error {
+	if len(names) == 0 {
+		names = suiteNames
+	}
+	for _, name := range names {
+		s, err := lookupSuite(name)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(root, s.dir)
+		cmd, err := tool(root, s.dir, dir, slices.Concat(s.args, []string{"-list"})...)
+		if err != nil {
+			return err
+		}
+		cmd.Stdout = nil
+		out, err := cmd.Output()
+		if err != nil {
+			return err
+		}
+		lines := bufio.NewScanner(strings.NewReader(string(out)))
+		for lines.Scan() {
+			fmt.Printf("%s\t%s\n", name, lines.Text())
+		}
+	}
+	return nil
+}
+
+// defaultDataPath is the dataset read by every language's sort benchmark
+const defaultDataPath = "sort/data.json"
+
+// generateData has the sort suite generate a dataset, as configured by the
+// flags or by the dataset section of -config, and write it with its seed
+func generateData(root string, args []string) error {
+	flags := flag.NewFlagSet("generate-data", flag.ExitOnError)
+	output := flags.String("o", "", "write the dataset to this file (default the shared "+defaultDataPath+")")
+	configPath := flags.String("config", "", "take the dataset settings from this sort config file instead of the flags below")
+	generator := flags.String("generator", "uniform", "dataset generator, e.g. uniform, nearly-sorted or few-unique")
+	size := flags.Int("size", 0, "number of elements (default the sort suite's)")
+	seed := flags.Int64("seed", 0, "generator seed (default picked from the clock and printed)")
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		return fmt.Errorf("generate-data: unexpected arguments %s", strings.Join(flags.Args(), " "))
+	}
+
+	path := filepath.Join(root, defaultDataPath)
+	if *output != "" {
+		var err error
+		if path, err = absPath(*output); err != nil {
+			return err
+		}
+	}
+	s := suites["sort"]
+	dir := filepath.Join(root, s.dir)
+	if *configPath != "" {
+		config, err := absPath(*configPath)
+		if err != nil {
+			return err
+		}
+		cmd, err := tool(root, s.dir, dir, "-config="+config, "-emit-data="+path)
+		if err != nil {
+			return err
+		}
+		return cmd.Run()
+	}
+
+	config, err := json.Marshal(map[string]any{
+		"iterations": 1,
+		"dataset":    map[string]any{"generator": *generator, "size": *size, "seed": *seed},
+	})
+	if err != nil {
+		return err
+	}
+	cmd, err := tool(root, s.dir, dir, "-config=-", "-emit-data="+path)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = strings.NewReader(string(config))
+	return cmd.Run()
+}
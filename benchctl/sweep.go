@@ -0,0 +1,219 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// sweepCell is one combination of runtime settings in a sweep, either of
+// which may be left empty to run with the environment's own
+type sweepCell struct {
+	gogc, gomaxprocs string
+}
+
+// env returns the environment variables selecting the cell's settings
+func (c sweepCell) env() []string {
+	var env []string
+	if c.gogc != "" {
+		env = append(env, "GOGC="+c.gogc)
+	}
+	if c.gomaxprocs != "" {
+		env = append(env, "GOMAXPROCS="+c.gomaxprocs)
+	}
+	return env
+}
+
+// label names the cell in the merged results document
+func (c sweepCell) label() string {
+	var parts []string
+	if c.gogc != "" {
+		parts = append(parts, "GOGC "+c.gogc)
+	}
+	if c.gomaxprocs != "" {
+		parts = append(parts, "GOMAXPROCS "+c.gomaxprocs)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// path returns the file the cell's results are kept in, next to results,
+// e.g. out.gogc100.procs4.json
+func (c sweepCell) path(results string) string {
+	path := strings.TrimSuffix(results, ".json")
+	if c.gogc != "" {
+		path += ".gogc" + c.gogc
+	}
+	if c.gomaxprocs != "" {
+		path += ".procs" + c.gomaxprocs
+	}
+	return path + ".json"
+}
+
+// parseSweep returns every combination of the comma-separated GOGC and
+// GOMAXPROCS values, GOMAXPROCS varying fastest
+func parseSweep(gogc, gomaxprocs string) ([]sweepCell, error) {
+	gogcs, err := parseSweepList("gogc", gogc, func(v string) bool {
+		n, err := strconv.Atoi(v)
+		return v == "off" || err == nil && n >= 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	procs, err := parseSweepList("gomaxprocs", gomaxprocs, func(v string) bool {
+		n, err := strconv.Atoi(v)
+		return err == nil && n >= 1
+	})
+	if err != nil {
+		return nil, err
+	}
+	var cells []sweepCell
+	for _, g := range gogcs {
+		for _, p := range procs {
+			cells = append(cells, sweepCell{gogc: g, gomaxprocs: p})
+		}
+	}
+	return cells, nil
+}
+
+// parseSweepList splits the values of the sweep flag name, checking each
+// with valid. An empty list is a single empty value, which leaves the
+// setting alone.
+func parseSweepList(name, list string, valid func(string) bool) ([]string, error) {
+	if list == "" {
+		return []string{""}, nil
+	}
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if !valid(v) {
+			return nil, fmt.Errorf("-%s: invalid value %q", name, v)
+		}
+		if slices.Contains(values, v) {
+			return nil, fmt.Errorf("-%s: %s is listed twice", name, v)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// runSweep runs the suite at dir once for each cell with its settings in
+// the environment, keeping each cell's results next to results, and merges
+// them into results labelled by their settings. Cells already complete from
+// an earlier, interrupted sweep are skipped. A grid of the medians is
+// printed to stderr.
+func runSweep(root, dir string, suiteArgs []string, results string, cells []sweepCell) error {
+	paths := make([]string, len(cells))
+	for i, cell := range cells {
+		paths[i] = cell.path(results)
+		if complete(paths[i]) {
+			fmt.Fprintf(os.Stderr, "%s is already in %s\n", cell.label(), paths[i])
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Running with %s (%d of %d)\n", cell.label(), i+1, len(cells))
+		err := runTool(root, dir, filepath.Join(root, dir), cell.env(), slices.Concat(suiteArgs, []string{"-results=" + paths[i]})...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writeGrid(os.Stderr, cells, paths); err != nil {
+		return err
+	}
+	args := []string{"-o", results}
+	for i, cell := range cells {
+		args = append(args, cell.label()+"="+paths[i])
+	}
+	cmd, err := tool(root, "merge", "", args...)
+	if err != nil {
+		return err
+	}
+	return cmd.Run()
+}
+
+// gridResults is the part of a results document writeGrid reads
+type gridResults struct {
+	Suite string `json:"suite"`
+	Runs  []struct {
+		Name       string `json:"name"`
+		Size       int    `json:"size"`
+		Benchmarks []struct {
+			Name     string  `json:"name"`
+			Median   float64 `json:"median"`
+			TimedOut bool    `json:"timedOut"`
+		} `json:"benchmarks"`
+	} `json:"runs"`
+}
+
+// writeGrid writes a table of each benchmark's median in milliseconds for
+// every cell, a row per benchmark and GOMAXPROCS value and a column per
+// GOGC value, for each run in the first cell's results
+func writeGrid(w io.Writer, cells []sweepCell, paths []string) error {
+	docs := make([]gridResults, len(paths))
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &docs[i]); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	var gogcs, procs []string
+	for _, cell := range cells {
+		if !slices.Contains(gogcs, cell.gogc) {
+			gogcs = append(gogcs, cell.gogc)
+		}
+		if !slices.Contains(procs, cell.gomaxprocs) {
+			procs = append(procs, cell.gomaxprocs)
+		}
+	}
+	median := func(doc gridResults, name string, size int, benchmark string) string {
+		for _, r := range doc.Runs {
+			if r.Name != name || r.Size != size {
+				continue
+			}
+			for _, b := range r.Benchmarks {
+				if b.Name == benchmark && !b.TimedOut && b.Median > 0 {
+					return strconv.FormatFloat(b.Median, 'f', 3, 64)
+				}
+			}
+		}
+		return "–"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, run := range docs[0].Runs {
+		title := docs[0].Suite
+		if run.Name != "" {
+			title += " " + run.Name
+		}
+		if run.Size > 0 {
+			title += fmt.Sprintf(", %d elements", run.Size)
+		}
+		fmt.Fprintf(w, "\n%s, median ms:\n", title)
+		header := "Benchmark\tGOMAXPROCS"
+		for _, g := range gogcs {
+			header += "\tGOGC " + cmp.Or(g, "default")
+		}
+		fmt.Fprintln(tw, header)
+		for _, b := range run.Benchmarks {
+			for _, p := range procs {
+				row := b.Name + "\t" + cmp.Or(p, "default")
+				for _, g := range gogcs {
+					cell := slices.Index(cells, sweepCell{gogc: g, gomaxprocs: p})
+					row += "\t" + median(docs[cell], run.Name, run.Size, b.Name)
+				}
+				fmt.Fprintln(tw, row)
+			}
+		}
+		tw.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// suite is a benchmark suite run by benchctl
+type suite struct {
+	// dir is the suite's Go module relative to the repository root. The
+	// suite runs there, so its default paths like ../config.json resolve.
+	dir string
+	// args select the shared harness, e.g. ast's -bench
+	args []string
+}
+
+var suites = map[string]suite{
+	"sort": {dir: "sort/go"},
+	"ast":  {dir: "ast/go", args: []string{"-bench"}},
+}
+
+// suiteNames are the suites in the order they are listed
+var suiteNames = []string{"sort", "ast"}
+
+// rootMarker is the file that identifies the repository root
+const rootMarker = "results.schema.json"
+
+// findRoot returns the repository root, the first of dir and its parents
+// holding rootMarker
+func findRoot(dir string) (string, error) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, rootMarker)); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("not inside the benchmarks repository: no " + rootMarker + " found in any parent directory")
+		}
+		dir = parent
+	}
+}
+
+// build compiles the Go main module at dir, relative to root, into the user
+// cache directory and returns the binary's path. The go command's build
+// cache makes rebuilding an unchanged tool quick.
+func build(root, dir string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	bin := filepath.Join(cache, "jsconf-benchctl", strings.ReplaceAll(dir, "/", "-"))
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	cmd := exec.Command("go", "build", "-o", bin, ".")
+	cmd.Dir = filepath.Join(root, dir)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("building %s: %w", dir, err)
+	}
+	return bin, nil
+}
+
+// tool builds the tool at dir and returns a command running it with args
+// in workDir, passing through the standard streams
+func tool(root, dir, workDir string, args ...string) (*exec.Cmd, error) {
+	bin, err := build(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = workDir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd, nil
+}
+
+// absPath makes a relative path absolute, so it refers to the same file
+// when a suite runs in its own directory. "-" is left alone since it means
+// stdin or stdout.
+func absPath(path string) (string, error) {
+	if path == "" || path == "-" {
+		return path, nil
+	}
+	return filepath.Abs(path)
+}
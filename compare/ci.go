@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// ciSummary is the machine-readable summary written in -ci mode
+type ciSummary struct {
+	Baseline string `json:"baseline"`
+	Current  string `json:"current"`
+	// Threshold is the relative slowdown past which a benchmark regressed,
+	// e.g. 0.05
+	Threshold float64 `json:"threshold"`
+	// Alpha is the significance level a regression's change had to reach
+	Alpha      float64     `json:"alpha"`
+	Benchmarks int         `json:"benchmarks"`
+	Passed     bool        `json:"passed"`
+	Violations []violation `json:"violations"`
+	// GeomeanSpeedup is the geometric mean of how many times faster current
+	// is than baseline, over the benchmarks both have medians for
+	GeomeanSpeedup float64 `json:"geomeanSpeedup,omitempty"`
+}
+
+// violation is a benchmark that fails -ci. Kind is "regression" when it slowed
+// down past the threshold, "timeout" when it timed out where the baseline
+// didn't, "verification" when its output failed verification, or "panic" or
+// "crash" when it panicked or its process died. Times are medians in
+// milliseconds.
+type violation struct {
+	Kind      string   `json:"kind"`
+	Run       string   `json:"run"`
+	Benchmark string   `json:"benchmark"`
+	Baseline  *float64 `json:"baseline,omitempty"`
+	Current   *float64 `json:"current,omitempty"`
+	Change    *float64 `json:"change,omitempty"`
+	// PValue is the Mann-Whitney U p-value of a regression, where both
+	// files have iterations to test
+	PValue *float64 `json:"pValue,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+func newCISummary(baselinePath, currentPath string, deltas []delta, threshold, alpha float64) ciSummary {
+	summary := ciSummary{
+		Baseline:   baselinePath,
+		Current:    currentPath,
+		Threshold:  threshold,
+		Alpha:      alpha,
+		Benchmarks: len(deltas),
+		Violations: []violation{},
+	}
+	summary.GeomeanSpeedup, _ = geomeanSpeedup(deltas)
+	for _, d := range deltas {
+		v := violation{Run: d.run, Benchmark: d.name}
+		switch {
+		case d.failed:
+			v.Kind = "verification"
+			if d.current.Failure != nil {
+				v.Kind = d.current.Failure.Kind
+			}
+			v.Error = d.current.Failed()
+		case d.regressed && d.current.TimedOut:
+			v.Kind = "timeout"
+			v.Baseline = &d.baseline.Median
+		case d.regressed:
+			v.Kind = "regression"
+			v.Baseline, v.Current, v.Change = &d.baseline.Median, &d.current.Median, &d.change
+			if d.tested {
+				v.PValue = &d.p
+			}
+		default:
+			continue
+		}
+		summary.Violations = append(summary.Violations, v)
+	}
+	summary.Passed = len(summary.Violations) == 0
+	return summary
+}
+
+func (s ciSummary) write(path string) error {
+	out, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0o644)
+}
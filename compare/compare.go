@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"jsconf/internal/bench"
+)
+
+// delta is one benchmark's change between the baseline and current results.
+// Runs are matched by suite, name and size, and benchmarks by name.
+type delta struct {
+	run  string
+	name string
+	// baseline and current are nil where only one of the files has the
+	// benchmark
+	baseline *bench.BenchmarkSummary
+	current  *bench.BenchmarkSummary
+	// change is the relative change in median, e.g. 0.1 when current is
+	// 10% slower
+	change float64
+	// p is the Mann-Whitney U p-value of the iterations of baseline and
+	// current, where tested is set because both have at least two.
+	// significant is set when p is below alpha, or when untested.
+	// tooFew is set when the iterations are too few for any p to be below
+	// alpha, so not being significant says nothing about the change.
+	p           float64
+	tested      bool
+	significant bool
+	tooFew      bool
+	// regressed is set when current is slower than baseline by more than the
+	// threshold and significantly, or timed out where baseline didn't
+	regressed bool
+	// failed is set when current panicked or failed verification
+	failed bool
+}
+
+// compare lines up the benchmarks of current against baseline, in the order
+// they appear in current followed by any only in baseline. threshold is the
+// relative slowdown, e.g. 0.05, past which a benchmark has regressed, and
+// alpha the significance level its change has to reach.
+func compare(baseline, current bench.Results, threshold, alpha float64) []delta {
+	type runKey struct {
+		name string
+		size int
+	}
+	type benchmarkKey struct {
+		run  runKey
+		name string
+	}
+	baselines := map[benchmarkKey]*bench.BenchmarkSummary{}
+	for i := range baseline.Runs {
+		run := &baseline.Runs[i]
+		for j := range run.Benchmarks {
+			baselines[benchmarkKey{runKey{run.Name, run.Size}, run.Benchmarks[j].Name}] = &run.Benchmarks[j]
+		}
+	}
+
+	var deltas []delta
+	seen := map[benchmarkKey]bool{}
+	for i := range current.Runs {
+		run := &current.Runs[i]
+		for j := range run.Benchmarks {
+			key := benchmarkKey{runKey{run.Name, run.Size}, run.Benchmarks[j].Name}
+			seen[key] = true
+			deltas = append(deltas, newDelta(run.Title(current.Suite), baselines[key], &run.Benchmarks[j], threshold, alpha))
+		}
+	}
+	for _, run := range baseline.Runs {
+		for j := range run.Benchmarks {
+			if !seen[benchmarkKey{runKey{run.Name, run.Size}, run.Benchmarks[j].Name}] {
+				deltas = append(deltas, newDelta(run.Title(baseline.Suite), &run.Benchmarks[j], nil, threshold, alpha))
+			}
+		}
+	}
+	return deltas
+}
+
+func newDelta(run string, baseline, current *bench.BenchmarkSummary, threshold, alpha float64) delta {
+	d := delta{run: run, baseline: baseline, current: current, significant: true}
+	if current != nil {
+		d.name = current.Name
+	} else {
+		d.name = baseline.Name
+	}
+	if current != nil && current.Failed() != "" {
+		d.failed = true
+		return d
+	}
+	if baseline == nil || current == nil || baseline.TimedOut || baseline.Failed() != "" {
+		return d
+	}
+	if current.TimedOut {
+		d.regressed = true
+		return d
+	}
+	if baseline.Median > 0 {
+		d.change = current.Median/baseline.Median - 1
+	}
+	// Two runs' medians always differ a little, so a change only counts
+	// when their iterations tell the runs apart
+	if len(baseline.Iterations) > 1 && len(current.Iterations) > 1 {
+		d.tested = true
+		d.p = bench.MannWhitneyU(baseline.Iterations, current.Iterations)
+		d.significant = d.p < alpha
+		d.tooFew = bench.MannWhitneyUMinP(len(baseline.Iterations), len(current.Iterations)) >= alpha
+	}
+	d.regressed = d.change > threshold && d.significant
+	return d
+}
+
+// status describes d for the table
+func (d delta) status() string {
+	switch {
+	case d.failed:
+		return "FAILED"
+	case d.baseline == nil:
+		return "new"
+	case d.current == nil:
+		return "removed"
+	case d.regressed:
+		return "REGRESSION"
+	case d.tested && d.tooFew:
+		return "too few iterations"
+	case d.tested && !d.significant:
+		return "not significant"
+	}
+	return ""
+}
+
+func formatMedian(b *bench.BenchmarkSummary) string {
+	switch {
+	case b == nil:
+		return "-"
+	case b.TimedOut:
+		return "timed out"
+	case b.Failed() != "":
+		return "failed"
+	}
+	return bench.FormatMilliseconds(b.Median)
+}
+
+// comparable reports whether both files have a median for d
+func (d delta) comparable() bool {
+	return d.baseline != nil && d.current != nil && !d.baseline.TimedOut && !d.current.TimedOut &&
+		d.baseline.Failed() == "" && d.current.Failed() == "" && d.baseline.Median > 0 && d.current.Median > 0
+}
+
+func (d delta) formatChange() string {
+	if !d.comparable() {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", d.change*100)
+}
+
+// speedup is how many times faster current is than baseline
+func (d delta) speedup() float64 {
+	return d.baseline.Median / d.current.Median
+}
+
+func (d delta) formatSpeedup() string {
+	if !d.comparable() {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f×", d.speedup())
+}
+
+func (d delta) formatP() string {
+	switch {
+	case !d.tested:
+		return "-"
+	case d.p < 0.001:
+		return "<0.001"
+	}
+	return fmt.Sprintf("%.3f", d.p)
+}
+
+// formatChangeP is the change with its p-value where it was tested
+func (d delta) formatChangeP() string {
+	if !d.tested {
+		return d.formatChange()
+	}
+	if d.p < 0.001 {
+		return fmt.Sprintf("%s, p<0.001", d.formatChange())
+	}
+	return fmt.Sprintf("%s, p=%.3f", d.formatChange(), d.p)
+}
+
+// clockDropThreshold is how far below the baseline's the lowest CPU clock
+// of a regressed benchmark has to be for the regression to be put down to
+// throttling
+const clockDropThreshold = 0.1
+
+// lowestMHz is the lowest CPU clock read around b, or 0 where none was
+func lowestMHz(b *bench.BenchmarkSummary) float64 {
+	lowest := 0.0
+	for _, clocks := range []*bench.ClockSample{b.ClocksBefore, b.ClocksAfter} {
+		if clocks != nil && clocks.MinMHz > 0 && (lowest == 0 || clocks.MinMHz < lowest) {
+			lowest = clocks.MinMHz
+		}
+	}
+	return lowest
+}
+
+// clockDrop describes how the CPU clock dropped for current against the
+// baseline, when it dropped past clockDropThreshold
+func (d delta) clockDrop() string {
+	if d.baseline == nil || d.current == nil {
+		return ""
+	}
+	baseline, current := lowestMHz(d.baseline), lowestMHz(d.current)
+	if baseline == 0 || current == 0 || current >= baseline*(1-clockDropThreshold) {
+		return ""
+	}
+	return fmt.Sprintf("; the CPU clock dropped to %.0fMHz from %.0fMHz, which suggests throttling", current, baseline)
+}
+
+// geomeanSpeedup is the geometric mean of the speedups of the benchmarks
+// both files have medians for, and how many there were
+func geomeanSpeedup(deltas []delta) (float64, int) {
+	var speedups []float64
+	for _, d := range deltas {
+		if d.comparable() {
+			speedups = append(speedups, d.speedup())
+		}
+	}
+	return bench.GeometricMean(speedups), len(speedups)
+}
+
+// writeComparison writes a table of medians and changes per run, followed by
+// the benchmarks that failed or regressed past threshold
+func writeComparison(w io.Writer, deltas []delta, threshold float64) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	run := ""
+	for _, d := range deltas {
+		if d.run != run {
+			if run != "" {
+				fmt.Fprintln(tw)
+			}
+			run = d.run
+			fmt.Fprintf(tw, "%s\nBenchmark\tBaseline\tCurrent\tChange\tSpeedup\tp\t\n", run)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.name, formatMedian(d.baseline), formatMedian(d.current),
+			d.formatChange(), d.formatSpeedup(), d.formatP(), d.status())
+	}
+	tw.Flush()
+	if geomean, n := geomeanSpeedup(deltas); n > 0 {
+		fmt.Fprintf(w, "\nGeometric mean speedup: %.2f× over %d benchmarks\n", geomean, n)
+	}
+
+	var regressions, failures []delta
+	for _, d := range deltas {
+		if d.regressed {
+			regressions = append(regressions, d)
+		}
+		if d.failed {
+			failures = append(failures, d)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintf(w, "\n%d of %d benchmarks failed:\n", len(failures), len(deltas))
+		for _, d := range failures {
+			fmt.Fprintf(w, "  %s (%s): %s\n", d.name, d.run, d.current.Failed())
+		}
+	}
+	if len(regressions) == 0 {
+		fmt.Fprintf(w, "\nNo benchmarks regressed by more than %g%%\n", threshold*100)
+		return
+	}
+	fmt.Fprintf(w, "\n%d of %d benchmarks regressed by more than %g%%:\n", len(regressions), len(deltas), threshold*100)
+	for _, d := range regressions {
+		if d.current.TimedOut {
+			fmt.Fprintf(w, "  %s (%s): timed out, was %s%s\n", d.name, d.run, formatMedian(d.baseline), d.clockDrop())
+			continue
+		}
+		fmt.Fprintf(w, "  %s (%s): %s -> %s (%s)%s\n", d.name, d.run, formatMedian(d.baseline), formatMedian(d.current), d.formatChangeP(), d.clockDrop())
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"jsconf/internal/bench"
+)
+
+func TestCompare(t *testing.T) {
+	results := func(benchmarks ...bench.BenchmarkSummary) bench.Results {
+		return bench.Results{Suite: "sort", Runs: []bench.ResultsRun{{Size: 1000, Benchmarks: benchmarks}}}
+	}
+	baseline := results(
+		bench.BenchmarkSummary{Name: "Quicksort", Median: 2, ClocksBefore: &bench.ClockSample{MinMHz: 3000}},
+		bench.BenchmarkSummary{Name: "Radix sort", Median: 1},
+		bench.BenchmarkSummary{Name: "Bubble sort", Median: 100},
+		bench.BenchmarkSummary{Name: "Heapsort", Median: 3},
+		bench.BenchmarkSummary{Name: "Shell sort", Median: 1.4, Iterations: []float64{1, 1.2, 1.4, 1.6, 1.8}},
+		bench.BenchmarkSummary{Name: "Counting sort", Median: 1.02, Iterations: []float64{1, 1.01, 1.02, 1.03, 1.04}},
+		bench.BenchmarkSummary{Name: "Merge sort", Median: 1, Iterations: []float64{0.9, 1, 1.1}},
+	)
+	current := results(
+		bench.BenchmarkSummary{Name: "Quicksort", Median: 2.5, ClocksAfter: &bench.ClockSample{MinMHz: 2000}},
+		bench.BenchmarkSummary{Name: "Radix sort", Median: 1.04},
+		bench.BenchmarkSummary{Name: "Shell sort", Median: 1.5, Iterations: []float64{1.1, 1.3, 1.5, 1.7, 1.9}},
+		bench.BenchmarkSummary{Name: "Counting sort", Median: 2.02, Iterations: []float64{2, 2.01, 2.02, 2.03, 2.04}},
+		bench.BenchmarkSummary{Name: "Merge sort", Median: 2, Iterations: []float64{1.9, 2, 2.1}},
+		bench.BenchmarkSummary{Name: "Bubble sort", TimedOut: true},
+		bench.BenchmarkSummary{Name: "Timsort", Median: 4},
+	)
+
+	var out strings.Builder
+	writeComparison(&out, compare(baseline, current, 0.05, 0.05), 0.05)
+	want := `sort, 1000 elements
+Benchmark      Baseline  Current    Change   Speedup  p      
+Quicksort      2.00ms    2.50ms     +25.0%   0.80×    -      REGRESSION
+Radix sort     1.00ms    1.04ms     +4.0%    0.96×    -      
+Shell sort     1.40ms    1.50ms     +7.1%    0.93×    0.690  not significant
+Counting sort  1.02ms    2.02ms     +98.0%   0.50×    0.008  REGRESSION
+Merge sort     1.00ms    2.00ms     +100.0%  0.50×    0.100  too few iterations
+Bubble sort    100.00ms  timed out  -        -        -      REGRESSION
+Timsort        -         4.00ms     -        -        -      new
+Heapsort       3.00ms    -          -        -        -      removed
+
+Geometric mean speedup: 0.71× over 5 benchmarks
+
+3 of 8 benchmarks regressed by more than 5%:
+  Quicksort (sort, 1000 elements): 2.00ms -> 2.50ms (+25.0%); the CPU clock dropped to 2000MHz from 3000MHz, which suggests throttling
+  Counting sort (sort, 1000 elements): 1.02ms -> 2.02ms (+98.0%, p=0.008)
+  Bubble sort (sort, 1000 elements): timed out, was 100.00ms
+`
+	if out.String() != want {
+		t.Errorf("writeComparison wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestCISummary(t *testing.T) {
+	results := func(benchmarks ...bench.BenchmarkSummary) bench.Results {
+		return bench.Results{Suite: "sort", Runs: []bench.ResultsRun{{Size: 1000, Benchmarks: benchmarks}}}
+	}
+	baseline := results(
+		bench.BenchmarkSummary{Name: "Quicksort", Median: 2},
+		bench.BenchmarkSummary{Name: "Radix sort", Median: 1},
+		bench.BenchmarkSummary{Name: "Bubble sort", Median: 100},
+	)
+	current := results(
+		bench.BenchmarkSummary{Name: "Quicksort", Median: 3},
+		bench.BenchmarkSummary{Name: "Radix sort", VerifyError: "Mismatch at index 3"},
+		bench.BenchmarkSummary{Name: "Bubble sort", TimedOut: true},
+	)
+
+	summary := newCISummary("base.json", "current.json", compare(baseline, current, 0.05, 0.05), 0.05, 0.05)
+	if summary.Passed {
+		t.Error("summary passed with violations")
+	}
+	var kinds []string
+	for _, v := range summary.Violations {
+		kinds = append(kinds, v.Kind)
+	}
+	if want := []string{"regression", "verification", "timeout"}; !slices.Equal(kinds, want) {
+		t.Errorf("violations = %v, want %v", kinds, want)
+	}
+	if v := summary.Violations[0]; *v.Change != 0.5 || *v.Baseline != 2 || *v.Current != 3 {
+		t.Errorf("regression = %+v, want a change of 0.5 from 2ms to 3ms", v)
+	}
+	if v := summary.Violations[1]; v.Error != "Mismatch at index 3" {
+		t.Errorf("verification error = %q", v.Error)
+	}
+
+	if summary := newCISummary("base.json", "base.json", compare(baseline, baseline, 0.05, 0.05), 0.05, 0.05); !summary.Passed {
+		t.Errorf("comparing against itself has violations %+v", summary.Violations)
+	}
+}
+
+func TestGitHubComment(t *testing.T) {
+	results := func(benchmarks ...bench.BenchmarkSummary) bench.Results {
+		return bench.Results{Suite: "sort", Runs: []bench.ResultsRun{{Size: 1000, Benchmarks: benchmarks}}}
+	}
+	baseline := results(
+		bench.BenchmarkSummary{Name: "Quicksort", Median: 2},
+		bench.BenchmarkSummary{Name: "Radix sort", Median: 1},
+		bench.BenchmarkSummary{Name: "Heapsort", Median: 3},
+	)
+	current := results(
+		bench.BenchmarkSummary{Name: "Quicksort", Median: 2.5},
+		bench.BenchmarkSummary{Name: "Radix sort", Median: 0.5},
+		bench.BenchmarkSummary{Name: "Heapsort", Median: 3.03},
+	)
+
+	var out strings.Builder
+	writeGitHubComment(&out, compare(baseline, current, 0.05, 0.05), 0.05, []string{"current.json is from an interrupted run"})
+	want := gitHubCommentMarker + `
+### 🔴 1 of 3 benchmarks regressed by more than 5% or failed
+
+Geometric mean speedup: **1.17×** over 3 benchmarks
+
+> ⚠️ current.json is from an interrupted run
+
+- 🔴 **Quicksort** (sort, 1000 elements): 2.00ms → 2.50ms (+25.0%)
+- 🟢 **Radix sort** (sort, 1000 elements): 1.00ms → 0.50ms (-50.0%)
+
+<details>
+<summary>All 3 benchmarks</summary>
+
+#### sort, 1000 elements
+
+|  | Benchmark | Baseline | Current | Change | Speedup | p |
+|---|---|--:|--:|--:|--:|--:|
+| 🔴 | Quicksort | 2.00ms | 2.50ms | +25.0% | 0.80× | - |
+| 🟢 | Radix sort | 1.00ms | 0.50ms | -50.0% | 2.00× | - |
+|  | Heapsort | 3.00ms | 3.03ms | +1.0% | 0.99× | - |
+
+</details>
+`
+	if out.String() != want {
+		t.Errorf("writeGitHubComment wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// gitHubCommentMarker starts every comment written by writeGitHubComment,
+// so a bot can find its earlier comment on a pull request and update it
+const gitHubCommentMarker = "<!-- jsconf-benchmarks compare -->"
+
+// improved reports whether current is faster than baseline by more than
+// threshold, and significantly
+func (d delta) improved(threshold float64) bool {
+	return d.comparable() && d.change < -threshold && d.significant
+}
+
+// indicator is the emoji marking d in a GitHub comment
+func (d delta) indicator(threshold float64) string {
+	switch {
+	case d.failed:
+		return "❌"
+	case d.regressed:
+		return "🔴"
+	case d.improved(threshold):
+		return "🟢"
+	case d.baseline == nil:
+		return "🆕"
+	case d.current == nil:
+		return "➖"
+	}
+	return ""
+}
+
+// writeGitHubComment writes the comparison as a markdown comment for a pull
+// request: a headline and the benchmarks that failed, regressed or improved
+// past threshold, followed by the full table collapsed in a details block.
+// warnings are quoted under the headline.
+func writeGitHubComment(w io.Writer, deltas []delta, threshold float64, warnings []string) {
+	var regressions, failures, improvements []delta
+	for _, d := range deltas {
+		switch {
+		case d.failed:
+			failures = append(failures, d)
+		case d.regressed:
+			regressions = append(regressions, d)
+		case d.improved(threshold):
+			improvements = append(improvements, d)
+		}
+	}
+
+	fmt.Fprintln(w, gitHubCommentMarker)
+	switch {
+	case len(failures) > 0 || len(regressions) > 0:
+		fmt.Fprintf(w, "### 🔴 %d of %d benchmarks regressed by more than %g%% or failed\n\n", len(failures)+len(regressions), len(deltas), threshold*100)
+	case len(improvements) > 0:
+		fmt.Fprintf(w, "### 🟢 %d of %d benchmarks improved by more than %g%%\n\n", len(improvements), len(deltas), threshold*100)
+	default:
+		fmt.Fprintf(w, "### ✅ No benchmarks changed by more than %g%%\n\n", threshold*100)
+	}
+	if geomean, n := geomeanSpeedup(deltas); n > 0 {
+		fmt.Fprintf(w, "Geometric mean speedup: **%.2f×** over %d benchmarks\n\n", geomean, n)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "> ⚠️ %s\n", warning)
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	for _, d := range failures {
+		fmt.Fprintf(w, "- ❌ **%s** (%s) failed: %s\n", d.name, d.run, d.current.Failed())
+	}
+	for _, d := range regressions {
+		if d.current.TimedOut {
+			fmt.Fprintf(w, "- 🔴 **%s** (%s): timed out, was %s%s\n", d.name, d.run, formatMedian(d.baseline), d.clockDrop())
+			continue
+		}
+		fmt.Fprintf(w, "- 🔴 **%s** (%s): %s → %s (%s)%s\n", d.name, d.run, formatMedian(d.baseline), formatMedian(d.current), d.formatChangeP(), d.clockDrop())
+	}
+	for _, d := range improvements {
+		fmt.Fprintf(w, "- 🟢 **%s** (%s): %s → %s (%s)\n", d.name, d.run, formatMedian(d.baseline), formatMedian(d.current), d.formatChangeP())
+	}
+	if len(failures)+len(regressions)+len(improvements) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "<details>\n<summary>All %d benchmarks</summary>\n", len(deltas))
+	run := ""
+	for _, d := range deltas {
+		if d.run != run {
+			run = d.run
+			fmt.Fprintf(w, "\n#### %s\n\n|  | Benchmark | Baseline | Current | Change | Speedup | p |\n|---|---|--:|--:|--:|--:|--:|\n", run)
+		}
+		change := d.formatChange()
+		if d.tested && d.tooFew {
+			change += " (too few iterations)"
+		} else if d.tested && !d.significant {
+			change += " (not significant)"
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s | %s |\n", d.indicator(threshold), escapeCell(d.name),
+			formatMedian(d.baseline), formatMedian(d.current), change, d.formatSpeedup(), d.formatP())
+	}
+	fmt.Fprintln(w, "\n</details>")
+}
+
+// escapeCell escapes the pipes that would split a markdown table cell
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"jsconf/internal/bench"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 5, "percentage slowdown in a median past which a benchmark has regressed")
+	ci := flag.Bool("ci", false, "exit with status 1 when any benchmark regressed or failed, and write a summary of them")
+	ciSummary := flag.String("ci-summary", "compare-summary.json", "with -ci, write the JSON summary of violations to this file")
+	alpha := flag.Float64("alpha", 0.05, "significance level: changes whose Mann-Whitney U p-value over the iterations is at least this are labelled not significant and never count as regressions")
+	format := flag.String("format", "text", "output format, text or github for a markdown comment to post on a pull request")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: compare [flags] baseline.json current.json\n\nCompares the medians of two results files and lists the benchmarks that regressed or failed.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	ciSummaryPath := ""
+	if *ci {
+		ciSummaryPath = *ciSummary
+	}
+	passed, err := run(flag.Arg(0), flag.Arg(1), *format, *threshold/100, *alpha, ciSummaryPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *ci && !passed {
+		os.Exit(1)
+	}
+}
+
+// run compares the results files and reports whether every benchmark passed,
+// writing a summary of the violations to ciSummaryPath when it is set
+func run(baselinePath, currentPath, format string, threshold, alpha float64, ciSummaryPath string) (passed bool, err error) {
+	if format != "text" && format != "github" {
+		return false, fmt.Errorf("unknown format %q, expected \"text\" or \"github\"", format)
+	}
+	if threshold < 0 {
+		return false, fmt.Errorf("threshold must not be negative, got %g%%", threshold*100)
+	}
+	if alpha <= 0 || alpha > 1 {
+		return false, fmt.Errorf("alpha must be above 0 and at most 1, got %g", alpha)
+	}
+	baseline, err := bench.ReadResults(baselinePath)
+	if err != nil {
+		return false, err
+	}
+	current, err := bench.ReadResults(currentPath)
+	if err != nil {
+		return false, err
+	}
+	if baseline.Suite != current.Suite {
+		return false, fmt.Errorf("can't compare %s results against %s results", current.Suite, baseline.Suite)
+	}
+
+	var warnings []string
+	for _, r := range []struct {
+		path    string
+		results bench.Results
+	}{{baselinePath, baseline}, {currentPath, current}} {
+		if r.results.Execution == bench.ExecutionParallel {
+			warnings = append(warnings, fmt.Sprintf("%s ran %d benchmarks in parallel, so its timings include contention between them", r.path, r.results.Parallelism))
+		}
+		if r.results.Partial {
+			warnings = append(warnings, fmt.Sprintf("%s is from an interrupted run, so benchmarks that hadn't completed are missing", r.path))
+		}
+	}
+
+	if baseline.Seed != 0 && current.Seed != 0 && baseline.Seed != current.Seed {
+		warnings = append(warnings, fmt.Sprintf("%s and %s were run with different seeds, so the data generated from them differs; pass -seed %d to rerun with the baseline's",
+			baselinePath, currentPath, baseline.Seed))
+	}
+
+	deltas := compare(baseline, current, threshold, alpha)
+	if format == "github" {
+		writeGitHubComment(os.Stdout, deltas, threshold, warnings)
+	} else {
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		writeComparison(os.Stdout, deltas, threshold)
+	}
+
+	summary := newCISummary(baselinePath, currentPath, deltas, threshold, alpha)
+	if ciSummaryPath != "" {
+		if err := summary.write(ciSummaryPath); err != nil {
+			return false, fmt.Errorf("writing CI summary: %w", err)
+		}
+	}
+	return summary.Passed, nil
+}
@@ -0,0 +1,552 @@
+// Package bench is the timing harness shared by the Go benchmarks. It runs
+// warmup and timed iterations of a Benchmark, records memory, GC and
+// optional hardware counter activity for each, verifies the output, and
+// summarises the timings.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/trace"
+	"time"
+)
+
+// Benchmark is one piece of work to time
+type Benchmark interface {
+	Name() string
+	// Setup prepares fresh input before each iteration, outside the timed
+	// region
+	Setup()
+	// Run does the work being timed
+	Run()
+	// Verify checks the output of the last Run
+	Verify() error
+}
+
+// Options controls how Run times a benchmark
+type Options struct {
+	Warmup     int
+	Iterations int
+	// Budget, when set, replaces Iterations with as many timed iterations
+	// as fit in this much wall-clock time, at least one and at most
+	// maxBudgetIterations
+	Budget time.Duration
+	// MinSampleTime, when set, calibrates how many runs each timed sample
+	// batches so it takes at least this long, so timer resolution doesn't
+	// dominate tiny workloads. Only BatchBenchmarks are batched.
+	MinSampleTime time.Duration
+	// ShouldVerify reports whether iteration i, counting warmup iterations,
+	// is verified. Nil verifies every iteration.
+	ShouldVerify func(i int) bool
+	// Quiet turns off progress reports and per-iteration output
+	Quiet bool
+	// Timeout stops a benchmark once one iteration takes longer, or zero for
+	// no timeout. Only benchmarks that call ReportProgress can be stopped
+	// partway through an iteration.
+	Timeout time.Duration
+	// PerfCounters records hardware cache and branch misses, where available
+	PerfCounters bool
+	// Outliers selects how outlying samples are detected and handled
+	Outliers OutlierConfig
+	// Confidence sets the level of the median's confidence interval and
+	// optionally a target width to keep iterating until
+	Confidence ConfidenceConfig
+	// SteadyState, when enabled, extends Warmup until iteration times
+	// settle
+	SteadyState SteadyStateConfig
+	// Cooldown sleeps before the benchmark and pauses it when its
+	// iteration times drift upward, to let a throttled CPU cool down
+	Cooldown CooldownConfig
+	// Cold evicts the CPU caches, and reorders the input when ColdData asks
+	// and the benchmark is a ShuffleBenchmark, before each iteration, with
+	// the shuffles drawn from ShuffleSeed. The result's name gets a
+	// " (cold)" suffix.
+	Cold        bool
+	ColdData    ColdDataConfig
+	ShuffleSeed int64
+	// SubtractOverhead takes the harness's overhead, as measured by
+	// HarnessOverhead, off every sample, for microbenchmarks it would
+	// otherwise dominate
+	SubtractOverhead bool
+
+	// turn, set by RunInterleaved, hands the turn to the next interleaved
+	// benchmark and waits for it to come back
+	turn func()
+	// MemoryLimit, when set, is the runtime's soft memory limit in bytes
+	// while the benchmark runs, as set by debug.SetMemoryLimit
+	MemoryLimit int64
+	// CPUProfileDir, when set, is where a pprof CPU profile of each
+	// benchmark's timed iterations is written, in a file named by
+	// ProfileFileName. Warmup isn't profiled, but each iteration's Setup is.
+	CPUProfileDir string
+	// MemProfileDir, when set, is where a heap profile of the allocations
+	// up to the end of each benchmark's timed iterations is written, with
+	// the kind "allocs", and another of those up to their start, with the
+	// kind "allocs-base", to pass to go tool pprof -base
+	MemProfileDir string
+	// TraceDir, when set, is where a runtime/trace execution trace of each
+	// benchmark's timed iterations is written, with a region per iteration,
+	// for go tool trace
+	TraceDir string
+}
+
+// Result holds the timed iterations of one benchmark. Warmup iterations
+// aren't included.
+type Result struct {
+	Name string
+	// Durations are in run order
+	Durations []time.Duration
+	// Median and Stats summarise the samples retained under the outlier
+	// policy, and are zero when the benchmark timed out
+	Median time.Duration
+	Stats  Stats
+	// Outliers is the number of Durations detected as outliers, and
+	// OutlierIndexes their indexes in Durations
+	Outliers       int
+	OutlierIndexes []int
+	// Warmup are the durations of the warmup iterations, which aren't part
+	// of the statistics
+	Warmup []time.Duration
+	// Unsteady is set when steady-state detection gave up after MaxWarmup
+	// iterations
+	Unsteady bool
+	// CI is the bootstrap confidence interval on Median
+	CI ConfidenceInterval
+	// DriftPauses is how many times the benchmark was paused to cool down
+	// after its iteration times drifted upward
+	DriftPauses int
+	// ClocksBefore and ClocksAfter are the CPU frequencies and temperature
+	// before the first iteration and after the last, where available
+	ClocksBefore *ClockSample
+	ClocksAfter  *ClockSample
+	// Cold is set when the iterations ran with cold data
+	Cold bool
+	// MemoryLimit is Options.MemoryLimit, and LimitGCs the collections
+	// during timed iterations that ended with the limit holding the heap
+	// goal below GOGC's, which the limit forced to run early
+	MemoryLimit int64
+	LimitGCs    uint32
+	// Batch is the number of runs timed together in each sample, set by
+	// calibration. Durations and Perf are per run.
+	Batch int
+	// Overhead is the harness's overhead per run in samples of Batch runs,
+	// which OverheadSubtracted says was taken off Durations. It's left zero
+	// when nothing needed it measured.
+	Overhead           time.Duration
+	OverheadSubtracted bool
+	// Perf has one entry per timed iteration when counters were recorded
+	Perf []PerfCounts
+	// Mem has one entry per timed iteration, with Allocs and Bytes per run
+	// and GCs and GCPause over the whole sample
+	Mem      []MemDelta
+	TimedOut bool
+	// VerifyError is the failure returned by Run when verification failed,
+	// in which case Median and Stats are zero
+	VerifyError string
+	// Failure records where and why the benchmark panicked or failed
+	// verification, in which case Median and Stats are zero
+	Failure *Failure
+}
+
+// Failure is why a benchmark stopped before completing its iterations
+type Failure struct {
+	// Kind is "panic" or "verification", or "crash" where a suite records
+	// a child process that died running the benchmark
+	Kind string `json:"kind"`
+	// Where is the part of the benchmark that failed, e.g. "Quicksort
+	// iteration 3 verification"
+	Where   string `json:"where"`
+	Message string `json:"message"`
+	// Stack is the goroutine's stack at a panic
+	Stack string `json:"stack,omitempty"`
+}
+
+func (f *Failure) String() string {
+	switch f.Kind {
+	case "panic":
+		return fmt.Sprintf("panicked in %s: %s", f.Where, f.Message)
+	case "crash":
+		return fmt.Sprintf("crashed in %s: %s", f.Where, f.Message)
+	}
+	return fmt.Sprintf("%s failed: %s", f.Where, f.Message)
+}
+
+// ErrVerification is wrapped by the error Run returns when the benchmark's
+// output fails verification
+var ErrVerification = errors.New("verification failed")
+
+// ErrPanicked is wrapped by the error Run returns when the benchmark
+// panicked, which Run recovers from so the rest of a suite can still run
+var ErrPanicked = errors.New("panicked")
+
+// maxBudgetIterations bounds the samples kept under a time budget, so fast
+// benchmarks don't grow the samples, and the cost of bootstrapping them,
+// without limit
+const maxBudgetIterations = 10000
+
+// MemDelta is the heap and GC activity during one timed iteration
+type MemDelta struct {
+	Allocs  uint64
+	Bytes   uint64
+	GCs     uint32
+	GCPause time.Duration
+}
+
+func memStatsDelta(before, after *runtime.MemStats) MemDelta {
+	return MemDelta{
+		Allocs:  after.Mallocs - before.Mallocs,
+		Bytes:   after.TotalAlloc - before.TotalAlloc,
+		GCs:     after.NumGC - before.NumGC,
+		GCPause: time.Duration(after.PauseTotalNs - before.PauseTotalNs),
+	}
+}
+
+// Run times b according to opts, printing a line per iteration unless quiet
+// and the median and other statistics at the end. It returns an error
+// wrapping ErrVerification if verification fails, one wrapping ErrPanicked
+// if the benchmark panics, and ErrInterrupted, leaving the benchmark
+// unfinished, if the run is interrupted. Failures are also recorded in the
+// result's Failure.
+func Run(b Benchmark, opts Options) (result Result, err error) {
+	name := b.Name()
+	if opts.Cold {
+		name += ColdSuffix
+	}
+	result = Result{Name: name, Batch: 1, Cold: opts.Cold, OverheadSubtracted: opts.SubtractOverhead}
+	// The overhead is only measured when it's subtracted, recorded for the
+	// empty benchmark, or needed below to check a short median against it
+	var overhead Overhead
+	measured := opts.SubtractOverhead || b.Name() == EmptyName
+	if measured {
+		overhead = HarnessOverhead()
+	}
+
+	// where is the part of the benchmark running, for the Failure recorded
+	// if it panics. The stack is taken before the panicking frames unwind.
+	where := name + " setup"
+	defer func() {
+		if r := recover(); r != nil {
+			result.Failure = &Failure{Kind: "panic", Where: where, Message: fmt.Sprint(r), Stack: string(debug.Stack())}
+			err = fmt.Errorf("%s: %w: %v", where, ErrPanicked, r)
+		}
+	}()
+
+	if opts.MemoryLimit > 0 {
+		result.MemoryLimit = opts.MemoryLimit
+		defer setMemoryLimit(opts.MemoryLimit)()
+	}
+
+	counters := openBenchmarkCounters(opts.PerfCounters)
+	if counters != nil {
+		defer counters.close()
+	}
+
+	var profile *cpuProfile
+	var traceFile *os.File
+	defer func() {
+		if profile != nil {
+			profile.stop()
+		}
+		if traceFile != nil {
+			trace.Stop()
+			traceFile.Close()
+		}
+	}()
+
+	coldData := opts.ColdData.WithDefaults()
+	shuffler, _ := b.(ShuffleBenchmark)
+	var rng *rand.Rand
+	if opts.Cold && coldData.Shuffle && shuffler != nil {
+		rng = rand.New(rand.NewSource(opts.ShuffleSeed))
+	}
+
+	outliers := opts.Outliers.WithDefaults()
+	confidence := opts.Confidence.WithDefaults()
+	steadyState := opts.SteadyState.WithDefaults()
+	cooling := opts.Cooldown.WithDefaults()
+	// driftSince is the first timed iteration compared with the first
+	// Window of them, moved past each pause
+	driftSince := cooling.Window
+
+	// With a target width, iterating continues past opts.Iterations until
+	// the median's interval is narrow enough. Bootstrapping is expensive, so
+	// the interval is only rechecked once the samples grow by a tenth.
+	nextCheck := opts.Iterations
+	needsMore := func() bool {
+		n := len(result.Durations)
+		if confidence.TargetWidth == 0 || n >= confidence.MaxIterations {
+			return false
+		}
+		if n < nextCheck {
+			return true
+		}
+		nextCheck = n + max(1, n/10)
+		retained, _ := ApplyOutlierPolicy(result.Durations, outliers)
+		return BootstrapMedianCI(retained, confidence.Level).RelativeWidth > confidence.TargetWidth
+	}
+
+	// warmup is the number of warmup iterations, which steady-state
+	// detection cuts short from its upper bound once the samples settle
+	warmup := opts.Warmup
+	if steadyState.Enabled {
+		warmup = max(opts.Warmup, steadyState.MaxWarmup)
+	}
+
+	// timedStart is when the first timed iteration started, for Budget
+	var timedStart time.Time
+	more := func(i int) bool {
+		switch {
+		case i < warmup:
+			return true
+		case opts.Budget > 0:
+			n := len(result.Durations)
+			return n == 0 || (n < maxBudgetIterations && time.Since(timedStart) < opts.Budget)
+		default:
+			return i < warmup+opts.Iterations || needsMore()
+		}
+	}
+
+	cooldown(time.Duration(cooling.Seconds*float64(time.Second)), fmt.Sprintf("Cooling down for %vs before %s", cooling.Seconds, name),
+		"benchmark", name, "seconds", cooling.Seconds)
+	result.ClocksBefore = SampleClocks()
+	for i := 0; more(i); i++ {
+		if i > 0 && opts.turn != nil {
+			opts.turn()
+		}
+		if Interrupted() {
+			return result, ErrInterrupted
+		}
+		if i == warmup {
+			if batcher, ok := b.(BatchBenchmark); ok && opts.MinSampleTime > 0 {
+				where = name + " calibration"
+				result.Batch = calibrate(batcher, opts.MinSampleTime)
+				slog.Debug(fmt.Sprintf("Calibrated %s to %d runs per sample", name, result.Batch),
+					"benchmark", name, "runsPerSample", result.Batch)
+			}
+			if opts.MemProfileDir != "" {
+				if err := writeHeapProfile(opts.MemProfileDir, name, "allocs-base"); err != nil {
+					return result, err
+				}
+			}
+			if opts.CPUProfileDir != "" {
+				var err error
+				if profile, err = startCPUProfile(opts.CPUProfileDir, name); err != nil {
+					return result, err
+				}
+			}
+			if opts.TraceDir != "" {
+				var err error
+				if traceFile, err = startTrace(opts.TraceDir, name); err != nil {
+					return result, err
+				}
+			}
+			timedStart = time.Now()
+		}
+		label := fmt.Sprintf("%s iteration %d", name, i-warmup+1)
+		if i < warmup {
+			label = fmt.Sprintf("%s warmup iteration %d", name, i+1)
+		}
+
+		where = label + " setup"
+		if result.Batch > 1 {
+			b.(BatchBenchmark).SetupBatch(result.Batch)
+		} else {
+			b.Setup()
+		}
+		if opts.Cold {
+			if rng != nil {
+				shuffler.Shuffle(rng)
+			}
+			bustCache(int(coldData.CacheBustMB * 1024 * 1024))
+		}
+		where = label
+		// The trace region starts outside the memory stats, since starting it
+		// allocates
+		var region *trace.Region
+		if traceFile != nil {
+			region = trace.StartRegion(context.Background(), label)
+		}
+		// ReadMemStats stops the world, so it stays outside the timed region
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if counters != nil {
+			counters.start()
+		}
+		duration, timedOut := timeIteration(b, result.Batch, label, opts.Quiet, opts.Timeout)
+		var perf PerfCounts
+		if counters != nil {
+			perf = counters.stop()
+		}
+		runtime.ReadMemStats(&after)
+		mem := memStatsDelta(&before, &after)
+		if region != nil {
+			region.End()
+		}
+		if Interrupted() {
+			return result, ErrInterrupted
+		}
+
+		// Iterations that can't be interrupted count as timed out once they
+		// finish over the limit
+		if timedOut || (opts.Timeout > 0 && duration > opts.Timeout) {
+			result.ClocksAfter = SampleClocks()
+			slog.Warn(fmt.Sprintf("%s timed out after %v, skipping %s", label, opts.Timeout, name),
+				"benchmark", name, "timeout", opts.Timeout)
+			result.TimedOut = true
+			return result, nil
+		}
+
+		if opts.ShouldVerify == nil || opts.ShouldVerify(i) {
+			where = label + " verification"
+			if err := b.Verify(); err != nil {
+				result.VerifyError = err.Error()
+				result.Failure = &Failure{Kind: "verification", Where: where, Message: err.Error()}
+				return result, fmt.Errorf("%s: %w: %w", label, ErrVerification, err)
+			}
+		}
+		if i < warmup {
+			result.Warmup = append(result.Warmup, duration)
+			if !opts.Quiet {
+				slog.Info(fmt.Sprintf("%s completed in %.2fms", label, Milliseconds(duration)),
+					"benchmark", name, "warmup", i+1, "ms", Milliseconds(duration))
+			}
+			if steadyState.Enabled && i+1 >= opts.Warmup {
+				switch {
+				case steady(result.Warmup, steadyState):
+					warmup = i + 1
+					slog.Info(fmt.Sprintf("%s reached a steady state after %d warmup iterations", name, warmup),
+						"benchmark", name, "warmup", warmup)
+				case i+1 == warmup:
+					result.Unsteady = true
+					slog.Warn(fmt.Sprintf("%s didn't reach a steady state in %d warmup iterations, timing it anyway", name, warmup),
+						"benchmark", name, "warmup", warmup)
+				}
+			}
+			continue
+		}
+		if opts.SubtractOverhead {
+			duration = max(duration-overhead.Sample(result.Batch), 0)
+		}
+		// Batched samples are recorded per run, while the memory stats
+		// printed below cover the whole batch
+		if result.Batch > 1 {
+			duration /= time.Duration(result.Batch)
+			perf.CacheMisses /= uint64(result.Batch)
+			perf.BranchMisses /= uint64(result.Batch)
+		}
+		result.Durations = append(result.Durations, duration)
+		if opts.MemoryLimit > 0 && mem.GCs > 0 && memoryLimitBinding() {
+			result.LimitGCs += mem.GCs
+		}
+		perRun := mem
+		perRun.Allocs /= uint64(result.Batch)
+		perRun.Bytes /= uint64(result.Batch)
+		result.Mem = append(result.Mem, perRun)
+		perfDetail := ""
+		if counters != nil {
+			result.Perf = append(result.Perf, perf)
+			perfDetail = fmt.Sprintf(", %d cache misses, %d branch misses", perf.CacheMisses, perf.BranchMisses)
+		}
+		if !opts.Quiet {
+			detail := fmt.Sprintf("%d allocs, %.2fMB, %d GCs, %.2fms GC pause%s",
+				mem.Allocs, float64(mem.Bytes)/(1024*1024), mem.GCs, Milliseconds(mem.GCPause), perfDetail)
+			message := fmt.Sprintf("%s completed in %.2fms (%s)", label, Milliseconds(duration), detail)
+			if result.Batch > 1 {
+				message = fmt.Sprintf("%s completed in %s per run (batch of %d: %s)",
+					label, FormatMilliseconds(Milliseconds(duration)), result.Batch, detail)
+			}
+			attrs := []any{"benchmark", name, "iteration", len(result.Durations), "ms", Milliseconds(duration),
+				"runsPerSample", result.Batch, "allocs", mem.Allocs, "bytes", mem.Bytes, "gcs", mem.GCs}
+			if counters != nil {
+				attrs = append(attrs, "cacheMisses", perf.CacheMisses, "branchMisses", perf.BranchMisses)
+			}
+			slog.Info(message, attrs...)
+		}
+
+		if result.DriftPauses < cooling.MaxPauses && drifted(result.Durations, driftSince, cooling) {
+			result.DriftPauses++
+			pause := time.Duration(cooling.PauseSeconds * float64(time.Second))
+			cooldown(pause, fmt.Sprintf("%s slowed by over %.0f%% since its first iterations, which looks like thermal throttling; pausing %vs to cool down",
+				name, cooling.DriftThreshold*100, cooling.PauseSeconds), "benchmark", name, "seconds", cooling.PauseSeconds)
+			driftSince = len(result.Durations)
+			// The pause doesn't count against the budget
+			timedStart = timedStart.Add(pause)
+		}
+	}
+
+	result.ClocksAfter = SampleClocks()
+	if result.ClocksBefore != nil || result.ClocksAfter != nil {
+		slog.Debug(fmt.Sprintf("%s ran from %v to %v", name, result.ClocksBefore, result.ClocksAfter),
+			"benchmark", name, "before", result.ClocksBefore, "after", result.ClocksAfter)
+	}
+
+	if profile != nil {
+		err := profile.stop()
+		profile = nil
+		if err != nil {
+			return result, fmt.Errorf("writing CPU profile of %s: %w", name, err)
+		}
+	}
+	if traceFile != nil {
+		trace.Stop()
+		err := traceFile.Close()
+		traceFile = nil
+		if err != nil {
+			return result, fmt.Errorf("writing trace of %s: %w", name, err)
+		}
+	}
+	if opts.MemProfileDir != "" {
+		if err := writeHeapProfile(opts.MemProfileDir, name, "allocs"); err != nil {
+			return result, err
+		}
+	}
+
+	retained, outlierCount := ApplyOutlierPolicy(result.Durations, outliers)
+	result.Median = Median(retained)
+	result.Stats = Summarize(retained)
+	result.Outliers = outlierCount
+	result.OutlierIndexes = OutlierIndexes(result.Durations, outliers)
+	result.CI = BootstrapMedianCI(retained, confidence.Level)
+	if !measured && result.Median < overheadCeiling {
+		overhead = HarnessOverhead()
+		measured = true
+	}
+	if measured {
+		result.Overhead = overhead.Sample(result.Batch) / time.Duration(result.Batch)
+	}
+	if measured && !opts.SubtractOverhead && b.Name() != EmptyName && result.Median < overheadWarning*result.Overhead {
+		slog.Warn(fmt.Sprintf("%s's median of %s is under %dx the harness's overhead of %s per run, which dominates it; set subtractOverhead to leave the overhead out",
+			name, FormatMilliseconds(Milliseconds(result.Median)), overheadWarning, FormatMilliseconds(Milliseconds(result.Overhead))),
+			"benchmark", name, "median", result.Median, "overhead", result.Overhead)
+	}
+
+	targetDetail := ""
+	if confidence.TargetWidth > 0 && result.CI.RelativeWidth > confidence.TargetWidth {
+		targetDetail = fmt.Sprintf(" [target width of %.1f%% not reached in %d iterations]",
+			confidence.TargetWidth*100, len(result.Durations))
+	}
+	batchDetail := ""
+	if result.Batch > 1 {
+		batchDetail = fmt.Sprintf(" [%d runs per sample]", result.Batch)
+	}
+	pauseDetail := ""
+	if result.DriftPauses > 0 {
+		pauseDetail = fmt.Sprintf(" [paused %d times to cool down]", result.DriftPauses)
+	}
+	if result.LimitGCs > 0 {
+		pauseDetail += fmt.Sprintf(" [memory limit forced %d GCs]", result.LimitGCs)
+	}
+	slog.Info(fmt.Sprintf("%s: %v, %v%s%s%s%s", name, result.Stats, result.CI,
+		describeOutliers(outlierCount, len(result.Durations), outliers), targetDetail, batchDetail, pauseDetail),
+		"benchmark", name, "median", result.Stats.Median, "mean", result.Stats.Mean,
+		"stddev", result.Stats.StdDev, "min", result.Stats.Min, "max", result.Stats.Max,
+		"ciLow", Milliseconds(result.CI.Low), "ciHigh", Milliseconds(result.CI.High),
+		"iterations", len(result.Durations), "outliers", outlierCount)
+	return result, nil
+}
@@ -0,0 +1,1052 @@
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	durations := []time.Duration{5, 1, 4, 2, 3}
+	for i := range durations {
+		durations[i] *= time.Millisecond
+	}
+
+	stats := Summarize(durations)
+	want := Stats{Median: 3, Mean: 3, StdDev: math.Sqrt(2.5), Min: 1, Max: 5, P90: 5, P95: 5, P99: 5}
+	if stats != want {
+		t.Errorf("Summarize = %+v, want %+v", stats, want)
+	}
+}
+
+func TestGeometricMean(t *testing.T) {
+	if got := GeometricMean([]float64{2, 0.5, 4}); math.Abs(got-math.Cbrt(4)) > 1e-12 {
+		t.Errorf("GeometricMean = %v, want %v", got, math.Cbrt(4))
+	}
+	if got := GeometricMean(nil); got != 0 {
+		t.Errorf("GeometricMean(nil) = %v, want 0", got)
+	}
+}
+
+// countingBenchmark records the order its methods are called in
+type countingBenchmark struct {
+	calls  []string
+	failAt int
+}
+
+func (b *countingBenchmark) Name() string { return "counting" }
+func (b *countingBenchmark) Setup()       { b.calls = append(b.calls, "setup") }
+func (b *countingBenchmark) Run()         { b.calls = append(b.calls, "run") }
+
+func (b *countingBenchmark) Verify() error {
+	b.calls = append(b.calls, "verify")
+	if len(b.calls)/3 == b.failAt {
+		return errors.New("wrong output")
+	}
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	b := &countingBenchmark{}
+	result, err := Run(b, Options{Warmup: 1, Iterations: 2, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Durations) != 2 || len(result.Warmup) != 1 {
+		t.Errorf("got %d durations and %d warmup, want 2 and 1", len(result.Durations), len(result.Warmup))
+	}
+	want := []string{"setup", "run", "verify", "setup", "run", "verify", "setup", "run", "verify"}
+	if !slices.Equal(b.calls, want) {
+		t.Errorf("calls = %v, want %v", b.calls, want)
+	}
+
+	b = &countingBenchmark{failAt: 2}
+	result, err = Run(b, Options{Iterations: 3, Quiet: true})
+	if !errors.Is(err, ErrVerification) {
+		t.Errorf("err = %v, want a verification error", err)
+	}
+	if result.VerifyError != "wrong output" {
+		t.Errorf("VerifyError = %q, want %q", result.VerifyError, "wrong output")
+	}
+	// The iteration before the failure is still recorded
+	if summary := result.Summarize(); len(summary.Iterations) != 1 || len(summary.Allocs) != 1 {
+		t.Errorf("summary kept %d iterations and %d allocs, want 1 each", len(summary.Iterations), len(summary.Allocs))
+	}
+}
+
+// shufflingBenchmark records the calls of countingBenchmark and Shuffle
+type shufflingBenchmark struct{ countingBenchmark }
+
+func (b *shufflingBenchmark) Shuffle(rng *rand.Rand) { b.calls = append(b.calls, "shuffle") }
+
+func TestRunCold(t *testing.T) {
+	b := &shufflingBenchmark{}
+	coldData := ColdDataConfig{Mode: "cold", Shuffle: true, CacheBustMB: 1}
+	result, err := Run(b, Options{Iterations: 2, Quiet: true, Cold: true, ColdData: coldData})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary := result.Summarize(); summary.Name != "counting (cold)" || !summary.Cold {
+		t.Errorf("summary is named %q with cold %v, want %q and true", summary.Name, summary.Cold, "counting (cold)")
+	}
+	want := []string{"setup", "shuffle", "run", "verify", "setup", "shuffle", "run", "verify"}
+	if !slices.Equal(b.calls, want) {
+		t.Errorf("calls = %v, want %v", b.calls, want)
+	}
+
+	if got := (ColdDataConfig{Mode: "both"}).Passes(); !slices.Equal(got, []bool{false, true}) {
+		t.Errorf("Passes() = %v, want a warm and a cold pass", got)
+	}
+	if err := (ColdDataConfig{Mode: "lukewarm"}).WithDefaults().Validate(); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+}
+
+func TestHarnessOverhead(t *testing.T) {
+	overhead := Overhead{PerSample: 20 * time.Nanosecond, PerRun: 2 * time.Nanosecond}
+	if got := overhead.Sample(1); got != 20*time.Nanosecond {
+		t.Errorf("Sample(1) = %v, want 20ns", got)
+	}
+	if got := overhead.Sample(11); got != 40*time.Nanosecond {
+		t.Errorf("Sample(11) = %v, want 40ns", got)
+	}
+
+	result, err := Run(NewEmptyBenchmark(), Options{Iterations: 5, Quiet: true, SubtractOverhead: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Name != EmptyName || !result.OverheadSubtracted || result.Overhead != HarnessOverhead().PerSample {
+		t.Errorf("got %s with overhead %v (subtracted %v), want %s with %v subtracted",
+			result.Name, result.Overhead, result.OverheadSubtracted, EmptyName, HarnessOverhead().PerSample)
+	}
+	if summary := result.Summarize(); !summary.OverheadSubtracted || summary.OverheadMs != Milliseconds(result.Overhead) {
+		t.Errorf("summary has overhead %vms (subtracted %v), want %vms subtracted",
+			summary.OverheadMs, summary.OverheadSubtracted, Milliseconds(result.Overhead))
+	}
+
+	// A median far over any overhead doesn't need it measured
+	slow, err := Run(&slowingBenchmark{}, Options{Iterations: 1, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slow.Overhead != 0 {
+		t.Errorf("got overhead %v for a %v median, want it left unmeasured", slow.Overhead, slow.Median)
+	}
+}
+
+// turnBenchmark logs its name to a log shared with the benchmarks it's
+// interleaved with on each run
+type turnBenchmark struct {
+	name string
+	log  *[]string
+}
+
+func (b turnBenchmark) Name() string  { return b.name }
+func (b turnBenchmark) Setup()        {}
+func (b turnBenchmark) Run()          { *b.log = append(*b.log, b.name) }
+func (b turnBenchmark) Verify() error { return nil }
+
+func TestRunInterleaved(t *testing.T) {
+	var log []string
+	benchmarks := []Benchmark{turnBenchmark{"a", &log}, turnBenchmark{"b", &log}, turnBenchmark{"c", &log}}
+	opts := []Options{{Warmup: 1, Iterations: 2, Quiet: true}, {Iterations: 1, Quiet: true}, {Iterations: 3, Quiet: true}}
+	results, errs := RunInterleaved(benchmarks, opts, 1)
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if result.Name != benchmarks[i].Name() || len(result.Durations) != opts[i].Iterations {
+			t.Errorf("result %d is %s with %d iterations, want %s with %d", i, result.Name, len(result.Durations), benchmarks[i].Name(), opts[i].Iterations)
+		}
+	}
+	// Each round has one iteration of every benchmark that isn't done, in
+	// the same order
+	if len(log) != 7 || log[0] == log[1] || log[1] == log[2] || log[0] == log[2] {
+		t.Fatalf("runs went %v, want a round of each benchmark first", log)
+	}
+	withoutB := slices.DeleteFunc(slices.Clone(log[:3]), func(name string) bool { return name == "b" })
+	want := slices.Concat(log[:3], withoutB, withoutB)
+	if !slices.Equal(log, want) {
+		t.Errorf("runs went %v, want %v", log, want)
+	}
+
+	_, errs = RunInterleaved(benchmarks[:1], []Options{{Iterations: 1, Quiet: true, TraceDir: t.TempDir()}}, 1)
+	if !errors.Is(errs[0], ErrNotInterleavable) {
+		t.Errorf("err = %v, want ErrNotInterleavable for a traced benchmark", errs[0])
+	}
+}
+
+// panickingBenchmark panics in the Run of its second iteration
+type panickingBenchmark struct{ runs int }
+
+func (b *panickingBenchmark) Name() string  { return "panicking" }
+func (b *panickingBenchmark) Setup()        {}
+func (b *panickingBenchmark) Verify() error { return nil }
+
+func (b *panickingBenchmark) Run() {
+	if b.runs++; b.runs == 2 {
+		panic("index out of range")
+	}
+}
+
+func TestRunPanic(t *testing.T) {
+	result, err := Run(&panickingBenchmark{}, Options{Iterations: 3, Quiet: true})
+	if !errors.Is(err, ErrPanicked) {
+		t.Fatalf("err = %v, want a panic error", err)
+	}
+	failure := result.Failure
+	if failure == nil || failure.Kind != "panic" || failure.Where != "panicking iteration 2" || failure.Message != "index out of range" {
+		t.Fatalf("Failure = %+v, want a panic in iteration 2", failure)
+	}
+	if !strings.Contains(failure.Stack, "panickingBenchmark).Run") {
+		t.Errorf("Stack doesn't include the panicking method:\n%s", failure.Stack)
+	}
+	summary := result.Summarize()
+	if len(summary.Iterations) != 1 || summary.Median != 0 {
+		t.Errorf("summary kept %d iterations and a median of %v, want 1 and none", len(summary.Iterations), summary.Median)
+	}
+	if got, want := summary.Failed(), "panicked in panicking iteration 2: index out of range"; got != want {
+		t.Errorf("Failed() = %q, want %q", got, want)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	b := &progressBenchmark{}
+	result, err := Run(b, Options{Iterations: 1, Quiet: true, Timeout: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.TimedOut {
+		t.Error("expected the benchmark to time out")
+	}
+}
+
+// progressBenchmark reports progress until it is stopped
+type progressBenchmark struct{}
+
+func (progressBenchmark) Name() string  { return "progress" }
+func (progressBenchmark) Setup()        {}
+func (progressBenchmark) Verify() error { return nil }
+
+func (progressBenchmark) Run() {
+	for {
+		ReportProgress(0.5)
+	}
+}
+
+func TestRunProfiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+	// Profiling must have stopped after the first run for the second to
+	// start its own
+	for range 2 {
+		opts := Options{Iterations: 2, Quiet: true, CPUProfileDir: dir, MemProfileDir: dir, TraceDir: dir}
+		result, err := Run(&countingBenchmark{}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Mem) != 2 {
+			t.Errorf("got %d memory deltas, want 2", len(result.Mem))
+		}
+	}
+	for _, kind := range []string{"cpu", "allocs", "allocs-base", "trace"} {
+		info, err := os.Stat(filepath.Join(dir, ProfileFileName("counting", kind)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() == 0 {
+			t.Errorf("%s profile is empty", kind)
+		}
+	}
+
+	if got := ProfileFileName("Radix sort (LSD, base 256)", "cpu"); got != "radix-sort-lsd-base-256.cpu.pprof" {
+		t.Errorf("ProfileFileName = %q", got)
+	}
+	if got := ProfileFileName("Parallel quicksort (4 workers)", "trace"); got != "parallel-quicksort-4-workers.trace" {
+		t.Errorf("ProfileFileName = %q", got)
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	type config struct {
+		Iterations int `json:"iterations"`
+		Dataset    struct {
+			Size int `json:"size"`
+		} `json:"dataset"`
+	}
+	tests := map[string]string{
+		`{"iterations": 3, "itrations": 4}`:   `unknown key "itrations"`,
+		`{"dataset": {"size": "big"}}`:        `dataset.size: expected int, got string`,
+		"{\n  \"iterations\": 3,\n}":          `line 3, column 1: invalid character '}' looking for beginning of object key string`,
+		`{"iterations": 3} {"iterations": 4}`: `unexpected data after the top-level object`,
+	}
+	for input, want := range tests {
+		var c config
+		err := DecodeConfig([]byte(input), &c)
+		if err == nil || err.Error() != want {
+			t.Errorf("DecodeConfig(%s) = %v, want %s", input, err, want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	defer func(saved []Registration) { registry = saved }(registry)
+	registry = nil
+	factory := func() Benchmark { return &countingBenchmark{} }
+	Register("Parse a", []string{"parse", "a"}, factory)
+	Register("Parse b", []string{"parse", "b"}, factory)
+	Register("Marshal a", []string{"marshal", "a"}, factory)
+
+	tests := []struct {
+		run      string
+		tags     []string
+		skipTags []string
+		want     []string
+	}{
+		{"", nil, nil, []string{"Parse a", "Parse b", "Marshal a"}},
+		{"^Parse", nil, nil, []string{"Parse a", "Parse b"}},
+		{"", []string{"a"}, nil, []string{"Parse a", "Marshal a"}},
+		{"", []string{"parse", "a"}, nil, []string{"Parse a"}},
+		{"b$", []string{"marshal"}, nil, nil},
+		{"", nil, []string{"marshal"}, []string{"Parse a", "Parse b"}},
+		{"", []string{"a"}, []string{"parse"}, []string{"Marshal a"}},
+		{"", nil, []string{"b", "marshal"}, []string{"Parse a"}},
+	}
+	for _, test := range tests {
+		selected, err := Select(test.run, test.tags, test.skipTags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, r := range selected {
+			names = append(names, r.Name)
+		}
+		if !slices.Equal(names, test.want) {
+			t.Errorf("Select(%q, %v, %v) = %v, want %v", test.run, test.tags, test.skipTags, names, test.want)
+		}
+	}
+
+	if _, err := Select("(", nil, nil); err == nil {
+		t.Error("Select accepted an invalid regular expression")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Register accepted a duplicate name")
+		}
+	}()
+	Register("Parse a", nil, factory)
+}
+
+func TestApplyEnv(t *testing.T) {
+	type config struct {
+		Iterations int      `json:"iterations"`
+		Budget     float64  `json:"budgetSeconds"`
+		Algos      []string `json:"algos"`
+		Sizes      []int    `json:"sizes"`
+		Quiet      bool     `json:"quiet"`
+		PushURL    string   `json:"pushURL"`
+		Outliers   struct {
+			Method string `json:"method"`
+		} `json:"outliers"`
+	}
+	env := map[string]string{
+		"BENCH_ITERATIONS":     "3",
+		"BENCH_BUDGET_SECONDS": "1.5",
+		"BENCH_ALGOS":          "quick, radix",
+		"BENCH_SIZES":          "10,100",
+		"BENCH_QUIET":          "",
+		"BENCH_PUSH_URL":       "http://example.com",
+		"BENCH_OUTLIERS":       "trim",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	c := config{Iterations: 10, Quiet: true}
+	var log strings.Builder
+	defer func(logger *slog.Logger) { slog.SetDefault(logger) }(slog.Default())
+	slog.SetDefault(slog.New(newMessageHandler(&log, slog.LevelInfo)))
+	if err := applyEnv(&c, lookup); err != nil {
+		t.Fatal(err)
+	}
+	if c.Iterations != 3 || c.Budget != 1.5 || !slices.Equal(c.Algos, []string{"quick", "radix"}) ||
+		!slices.Equal(c.Sizes, []int{10, 100}) || !c.Quiet || c.PushURL != "http://example.com" || c.Outliers.Method != "" {
+		t.Errorf("applyEnv gave %+v", c)
+	}
+	if got := strings.Count(log.String(), "Overriding"); got != 5 {
+		t.Errorf("applyEnv noted %d overrides, want 5:\n%s", got, log.String())
+	}
+
+	env = map[string]string{"BENCH_ITERATIONS": "many"}
+	want := `BENCH_ITERATIONS: expected an integer, got "many"`
+	if err := applyEnv(&c, lookup); err == nil || err.Error() != want {
+		t.Errorf("applyEnv = %v, want %s", err, want)
+	}
+}
+
+func TestApplyOutlierPolicy(t *testing.T) {
+	durations := []time.Duration{10, 11, 10, 12, 11, 100, 10, 11}
+	for _, tt := range []struct {
+		config OutlierConfig
+		want   []time.Duration
+	}{
+		{OutlierConfig{Policy: "report"}, durations},
+		{OutlierConfig{Policy: "trim"}, []time.Duration{10, 11, 10, 12, 11, 10, 11}},
+		{OutlierConfig{Policy: "winsorize"}, []time.Duration{10, 11, 10, 12, 11, 12, 10, 11}},
+		{OutlierConfig{Method: "iqr", Policy: "trim"}, []time.Duration{10, 11, 10, 12, 11, 10, 11}},
+	} {
+		retained, outliers := ApplyOutlierPolicy(durations, tt.config)
+		if outliers != 1 || !slices.Equal(retained, tt.want) {
+			t.Errorf("ApplyOutlierPolicy(%+v) = %v, %d outliers, want %v, 1 outlier", tt.config, retained, outliers, tt.want)
+		}
+		if indexes := OutlierIndexes(durations, tt.config); !slices.Equal(indexes, []int{5}) {
+			t.Errorf("OutlierIndexes(%+v) = %v, want [5]", tt.config, indexes)
+		}
+	}
+
+	// Without spread nothing is an outlier
+	same := []time.Duration{5, 5, 5, 5}
+	if _, outliers := ApplyOutlierPolicy(same, OutlierConfig{Policy: "trim"}); outliers != 0 {
+		t.Errorf("got %d outliers in identical samples, want 0", outliers)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	fast := []float64{1.0, 1.1, 1.2, 1.3, 1.4, 1.5, 1.6, 1.7, 1.8, 1.9}
+	slow := []float64{2.0, 2.1, 2.2, 2.3, 2.4, 2.5, 2.6, 2.7, 2.8, 2.9}
+	mixed := []float64{1.05, 1.15, 1.25, 1.35, 1.45, 1.55, 1.65, 1.75, 1.85, 1.95}
+	for _, tt := range []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		// Only the two splits with no overlap are as extreme, out of
+		// 20 choose 10
+		{"separated", fast, slow, 2.0 / 184756},
+		// U is 45, which is well within chance
+		{"interleaved", fast, mixed, 0.7394},
+		// Tied values share a rank, and every split of the shared ranks
+		// counts
+		{"tied", []float64{1, 2, 2, 3, 5, 8}, []float64{2, 4, 6, 7, 9}, 0.2403},
+		// Past exactMannWhitneyLimit, U is 0 against a mean of 55 and a
+		// standard deviation of 14.20
+		{"separated large", append([]float64{0.9}, fast...), slow, 0.000124},
+		{"identical", fast, fast, 1},
+		{"all tied", []float64{1, 1, 1}, []float64{1, 1}, 1},
+		{"empty", fast, nil, 1},
+	} {
+		if got := MannWhitneyU(tt.a, tt.b); math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("%s: MannWhitneyU = %.6f, want %.6f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMannWhitneyUMinP(t *testing.T) {
+	for _, tt := range []struct {
+		n1, n2 int
+		want   float64
+	}{
+		// The quick profile's three iterations can never reach p < 0.05
+		{3, 3, 0.1},
+		{4, 4, 2.0 / 70},
+		{10, 10, 2.0 / 184756},
+	} {
+		if got := MannWhitneyUMinP(tt.n1, tt.n2); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("MannWhitneyUMinP(%d, %d) = %g, want %g", tt.n1, tt.n2, got, tt.want)
+		}
+	}
+}
+
+func TestBootstrapMedianCI(t *testing.T) {
+	samples := []time.Duration{90, 95, 100, 100, 100, 105, 110, 100, 98, 102}
+	ci := BootstrapMedianCI(samples, 0.95)
+	if ci.Low > 100 || ci.High < 100 || ci.Low < 90 || ci.High > 110 {
+		t.Errorf("interval %v-%v doesn't contain the median 100 within the samples", ci.Low, ci.High)
+	}
+	if want := float64(ci.High-ci.Low) / 100; ci.RelativeWidth != want {
+		t.Errorf("RelativeWidth = %v, want %v", ci.RelativeWidth, want)
+	}
+
+	same := []time.Duration{7, 7, 7}
+	if ci := BootstrapMedianCI(same, 0.95); ci.Low != 7 || ci.High != 7 || ci.RelativeWidth != 0 {
+		t.Errorf("identical samples gave %+v, want a zero-width interval at 7", ci)
+	}
+}
+
+// sleepingBenchmark sleeps a varying time so its samples are never
+// identical
+type sleepingBenchmark struct{ runs int }
+
+func (b *sleepingBenchmark) Name() string  { return "sleeping" }
+func (b *sleepingBenchmark) Setup()        {}
+func (b *sleepingBenchmark) Verify() error { return nil }
+
+func (b *sleepingBenchmark) Run() {
+	b.runs++
+	time.Sleep(time.Duration(b.runs%3) * 50 * time.Microsecond)
+}
+
+func TestRunTargetWidth(t *testing.T) {
+	opts := Options{
+		Iterations: 3,
+		Quiet:      true,
+		Confidence: ConfidenceConfig{TargetWidth: 1e-9, MaxIterations: 12},
+	}
+	result, err := Run(&sleepingBenchmark{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Durations) != 12 {
+		t.Errorf("got %d iterations, want MaxIterations 12 for an unreachable target", len(result.Durations))
+	}
+}
+
+func TestRunBudget(t *testing.T) {
+	start := time.Now()
+	result, err := Run(&sleepingBenchmark{}, Options{Iterations: 1, Budget: 20 * time.Millisecond, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Durations) < 2 {
+		t.Errorf("got %d iterations in a 20ms budget, want several", len(result.Durations))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("20ms budget took %v", elapsed)
+	}
+}
+
+func TestSteady(t *testing.T) {
+	c := SteadyStateConfig{Window: 3, Threshold: 0.05}
+	for _, tt := range []struct {
+		samples []time.Duration
+		want    bool
+	}{
+		{[]time.Duration{100, 100}, false},
+		{[]time.Duration{500, 300, 100, 101, 99}, true},
+		{[]time.Duration{500, 300, 100, 101, 150}, false},
+	} {
+		if got := steady(tt.samples, c); got != tt.want {
+			t.Errorf("steady(%v) = %v, want %v", tt.samples, got, tt.want)
+		}
+	}
+}
+
+func TestRunSteadyState(t *testing.T) {
+	// The sleeps alternate between 0, 50 and 100µs, which never settle
+	opts := Options{
+		Warmup:      2,
+		Iterations:  3,
+		Quiet:       true,
+		SteadyState: SteadyStateConfig{Enabled: true, Window: 3, Threshold: 0.01, MaxWarmup: 6},
+	}
+	result, err := Run(&sleepingBenchmark{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Unsteady || len(result.Warmup) != 6 || len(result.Durations) != 3 {
+		t.Errorf("got unsteady %v after %d warmup and %d timed iterations, want true after 6 and 3",
+			result.Unsteady, len(result.Warmup), len(result.Durations))
+	}
+}
+
+func TestDrifted(t *testing.T) {
+	c := CooldownConfig{DriftThreshold: 0.1, Window: 2}
+	for _, tt := range []struct {
+		samples []time.Duration
+		since   int
+		want    bool
+	}{
+		{[]time.Duration{100, 100, 105, 108}, 2, false},
+		{[]time.Duration{100, 100, 120, 130}, 2, true},
+		{[]time.Duration{100, 100, 120, 130}, 3, false},
+		{[]time.Duration{100, 100, 130}, 2, false},
+	} {
+		if got := drifted(tt.samples, tt.since, c); got != tt.want {
+			t.Errorf("drifted(%v, %d) = %v, want %v", tt.samples, tt.since, got, tt.want)
+		}
+	}
+	if drifted([]time.Duration{100, 100, 200, 200}, 2, CooldownConfig{Window: 2}) {
+		t.Error("drifted without a threshold")
+	}
+}
+
+func TestRunCooldown(t *testing.T) {
+	// Each run sleeps a millisecond longer than the last, as if throttled
+	opts := Options{
+		Iterations: 8,
+		Quiet:      true,
+		Cooldown:   CooldownConfig{DriftThreshold: 0.2, Window: 2, PauseSeconds: 0.001, MaxPauses: 2},
+	}
+	result, err := Run(&slowingBenchmark{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DriftPauses != 2 || len(result.Durations) != 8 {
+		t.Errorf("got %d pauses over %d iterations, want the 2 allowed over 8", result.DriftPauses, len(result.Durations))
+	}
+}
+
+// slowingBenchmark sleeps a millisecond longer on each run
+type slowingBenchmark struct{ runs int }
+
+func (b *slowingBenchmark) Name() string  { return "slowing" }
+func (b *slowingBenchmark) Setup()        {}
+func (b *slowingBenchmark) Verify() error { return nil }
+
+func (b *slowingBenchmark) Run() {
+	b.runs++
+	time.Sleep(time.Duration(b.runs) * time.Millisecond)
+}
+
+func TestRunMemoryLimit(t *testing.T) {
+	// A 16MB live heap makes GOGC's goal 32MB, well above the limit
+	opts := Options{Iterations: 3, Quiet: true, MemoryLimit: 24 << 20}
+	result, err := Run(&hungryBenchmark{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MemoryLimit != opts.MemoryLimit || result.LimitGCs == 0 {
+		t.Errorf("got limit %d forcing %d GCs, want %d forcing some", result.MemoryLimit, result.LimitGCs, opts.MemoryLimit)
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		t.Errorf("memory limit after the run = %d, want it restored to %d", limit, int64(math.MaxInt64))
+	}
+}
+
+// hungryBenchmark keeps 16MB live while churning through garbage
+type hungryBenchmark struct{ live [][]byte }
+
+func (b *hungryBenchmark) Name() string  { return "hungry" }
+func (b *hungryBenchmark) Verify() error { return nil }
+
+func (b *hungryBenchmark) Setup() {
+	b.live = nil
+	for range 16 {
+		b.live = append(b.live, make([]byte, 1<<20))
+	}
+}
+
+var hungrySink []byte
+
+func (b *hungryBenchmark) Run() {
+	for range 64 {
+		hungrySink = make([]byte, 1<<20)
+	}
+}
+
+func TestNewClock(t *testing.T) {
+	// A fine clock that advances 5µs on every reading, as if reading it
+	// took that long
+	var reads time.Duration
+	fine := newClock("fine", func() time.Duration { reads++; return reads * 5 * time.Microsecond })
+	if fine.Overhead != 5*time.Microsecond || fine.Resolution != 5*time.Microsecond {
+		t.Errorf("fine clock has overhead %v and resolution %v, want 5µs and 5µs", fine.Overhead, fine.Resolution)
+	}
+	start := fine.Now()
+	if got := fine.Elapsed(start); got != 0 {
+		t.Errorf("Elapsed right after Now = %v, want the overhead left out", got)
+	}
+
+	// A coarse clock that only moves in 100µs steps every tenth reading, as
+	// browsers clamp performance.now()
+	reads = 0
+	coarse := newClock("coarse", func() time.Duration { reads++; return reads / 10 * 100 * time.Microsecond })
+	if coarse.Overhead != 0 || coarse.Resolution != 100*time.Microsecond {
+		t.Errorf("coarse clock has overhead %v and resolution %v, want 0 and 100µs", coarse.Overhead, coarse.Resolution)
+	}
+}
+
+// batchBenchmark does a little work per run and counts its inputs
+type batchBenchmark struct {
+	inputs int
+	runs   int
+	sum    int
+}
+
+func (b *batchBenchmark) Name() string     { return "batch" }
+func (b *batchBenchmark) Setup()           { b.SetupBatch(1) }
+func (b *batchBenchmark) SetupBatch(n int) { b.inputs += n }
+func (b *batchBenchmark) Verify() error    { return nil }
+
+func (b *batchBenchmark) Run() {
+	b.runs++
+	for i := range 1000 {
+		b.sum += i
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	interrupted.Store(true)
+	defer interrupted.Store(false)
+	result, err := Run(&sleepingBenchmark{}, Options{Iterations: 3, Quiet: true})
+	if !errors.Is(err, ErrInterrupted) || len(result.Durations) != 0 {
+		t.Errorf("got %d iterations and error %v, want none and ErrInterrupted", len(result.Durations), err)
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	for _, tt := range []struct {
+		list string
+		want []int
+	}{
+		{"2,3", []int{2, 3}},
+		{"0-3, 6", []int{0, 1, 2, 3, 6}},
+		{"5", []int{5}},
+		{"3-1", nil},
+		{"a", nil},
+		{"", nil},
+	} {
+		got, err := ParseCPUList(tt.list)
+		if !slices.Equal(got, tt.want) || (err == nil) != (tt.want != nil) {
+			t.Errorf("ParseCPUList(%q) = %v, %v, want %v", tt.list, got, err, tt.want)
+		}
+	}
+}
+
+func TestRunCalibration(t *testing.T) {
+	b := &batchBenchmark{}
+	result, err := Run(b, Options{Iterations: 3, MinSampleTime: time.Millisecond, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Batch < 2 {
+		t.Errorf("Batch = %d, want a calibrated batch for a tiny workload", result.Batch)
+	}
+	if b.runs != b.inputs {
+		t.Errorf("%d runs from %d prepared inputs, want every run to get fresh input", b.runs, b.inputs)
+	}
+	for _, d := range result.Durations {
+		if d*time.Duration(result.Batch) < time.Millisecond/4 {
+			t.Errorf("sample of %d runs at %v per run is well under the minimum sample time", result.Batch, d)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	results := Results{Suite: "sort", Language: "go", Runs: []ResultsRun{{
+		Size:    10,
+		Dataset: "random, 10 elements",
+		Benchmarks: []BenchmarkSummary{
+			{Name: "Quicksort", Iterations: []float64{1.5, 2}, CacheMisses: []uint64{7, 8}, BranchMisses: []uint64{3, 4}, Allocs: []uint64{1, 2}, AllocBytes: []uint64{80, 96},
+				IterationGCs: []uint32{0, 1}, GCPauses: []float64{0, 0.125}, OutlierIndexes: []int{1}},
+			{Name: "Heapsort", Iterations: []float64{0.25}, RunsPerSample: 4},
+		},
+	}}}
+	path := filepath.Join(t.TempDir(), "results.csv")
+	if err := results.WriteCSV(path); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `suite,language,run,dataset,size,benchmark,iteration,ms,runsPerSample,cacheMisses,branchMisses,allocs,allocBytes,gcs,gcPauseMs,outlier
+sort,go,,"random, 10 elements",10,Quicksort,1,1.5,1,7,3,1,80,0,0,false
+sort,go,,"random, 10 elements",10,Quicksort,2,2,1,8,4,2,96,1,0.125,true
+sort,go,,"random, 10 elements",10,Heapsort,1,0.25,4,,,,,,,false
+`
+	if string(out) != want {
+		t.Errorf("WriteCSV wrote\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestWriteBarChart(t *testing.T) {
+	rows := []ChartRow{
+		{Name: "Quicksort", Median: 4 * time.Millisecond},
+		{Name: "Radix sort", Median: 1100 * time.Microsecond},
+		{Name: "Bubble sort", TimedOut: true},
+		{Name: "Noop", Median: 1},
+	}
+	var out strings.Builder
+	WriteBarChart(&out, rows, "unicode")
+	want := "Quicksort   " + strings.Repeat("█", 40) + " 4.00ms\n" +
+		"Radix sort  " + strings.Repeat("█", 11) + " 1.10ms\n" +
+		"Bubble sort timed out\n" +
+		"Noop        ▏ 0.00000100ms\n"
+	if out.String() != want {
+		t.Errorf("unicode chart:\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	WriteBarChart(&out, rows[:2], "ascii")
+	want = "Quicksort  " + strings.Repeat("#", 40) + " 4.00ms\n" +
+		"Radix sort " + strings.Repeat("#", 11) + " 1.10ms\n"
+	if out.String() != want {
+		t.Errorf("ascii chart:\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	results := Results{Suite: "sort", Language: "go", Runs: []ResultsRun{{Size: 1000, Benchmarks: []BenchmarkSummary{
+		{Name: "Quicksort", Iterations: []float64{2, 3}, Median: 2.5},
+	}}}}
+	base := filepath.Join(t.TempDir(), "sort.json")
+	if err := results.WriteFormats(base, []string{"json", "csv", "md", "html"}); err != nil {
+		t.Fatal(err)
+	}
+	for format, want := range map[string]string{
+		"sort.json": `"name": "Quicksort"`,
+		"sort.csv":  "sort,go,,,1000,Quicksort,2,3,",
+		"sort.md":   "| Quicksort | 2.50ms |",
+		"sort.html": "<h1>Benchmark report</h1>",
+	} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(base), format))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s doesn't contain %q:\n%s", format, want, data)
+		}
+	}
+	if err := results.WriteFormats(base, []string{"pdf"}); err == nil {
+		t.Error("WriteFormats accepted an unknown format")
+	}
+}
+
+func TestWriteBenchstat(t *testing.T) {
+	results := Results{Suite: "sort", Language: "go", Machine: Machine{OS: "linux", Arch: "amd64", Runtime: "go1.25.1"}, Runs: []ResultsRun{{
+		Name: "sorted runs",
+		Size: 1000,
+		Benchmarks: []BenchmarkSummary{
+			{Name: "Quicksort (int32)", Iterations: []float64{1.5, 0.0123}},
+			{Name: "Bubble sort", TimedOut: true, Iterations: []float64{900}},
+			{Name: "Radix sort", Iterations: []float64{0.25}, RunsPerSample: 4, CacheMisses: []uint64{10}, BranchMisses: []uint64{2}, Allocs: []uint64{3}, AllocBytes: []uint64{8192}},
+		},
+	}}}
+	path := filepath.Join(t.TempDir(), "results.txt")
+	if err := results.WriteBenchstat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `goos: linux
+goarch: amd64
+pkg: jsconf/sort
+language: go
+runtime: go1.25.1
+BenchmarkQuicksort_(int32)/dataset=sorted_runs/size=1000	1	1500000 ns/op
+BenchmarkQuicksort_(int32)/dataset=sorted_runs/size=1000	1	12300 ns/op
+BenchmarkRadix_sort/dataset=sorted_runs/size=1000	4	250000 ns/op	8192 B/op	3 allocs/op	10.00 cache-misses/op	2.000 branch-misses/op
+`
+	if string(out) != want {
+		t.Errorf("WriteBenchstat wrote\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestPush(t *testing.T) {
+	pushBackoff = time.Millisecond
+	defer func() { pushBackoff = time.Second }()
+
+	t.Setenv("PUSH_TOKEN", "secret")
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+		}
+		var results Results
+		if err := json.NewDecoder(r.Body).Decode(&results); err != nil || results.Suite != "sort" {
+			t.Errorf("decoded %+v, %v, want the sort results", results, err)
+		}
+	}))
+	defer server.Close()
+
+	headers := HeaderFlag{}
+	if err := headers.Set("Authorization: Bearer $PUSH_TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Results{Suite: "sort"}).Push(server.URL, headers); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusUnauthorized)
+	}))
+	defer rejecting.Close()
+	err := (Results{}).Push(rejecting.URL, nil)
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized: bad token") {
+		t.Errorf("err = %v, want the 401 without retrying", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	goResults := Results{Suite: "sort", Language: "go", Runs: []ResultsRun{{Size: 10, Dataset: "random, 10 elements", Benchmarks: []BenchmarkSummary{
+		{Name: "Quicksort", Median: 1},
+		{Name: "Heap sort", Median: 2},
+	}}}}
+	jsResults := Results{Suite: "sort", Language: "js", Runs: []ResultsRun{{Size: 10, Dataset: "file data.json, 10 elements", Benchmarks: []BenchmarkSummary{
+		{Name: "Quicksort", Median: 3},
+	}}}}
+
+	merged, err := Merge([]string{"go", "js"}, []Results{goResults, jsResults})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Runs) != 1 {
+		t.Fatalf("got %d runs, want the two documents' runs matched into 1", len(merged.Runs))
+	}
+	quicksort := merged.Runs[0].Benchmarks["Quicksort"]
+	if quicksort["go"].Median != 1 || quicksort["js"].Median != 3 {
+		t.Errorf("Quicksort = %+v, want go and js side by side", quicksort)
+	}
+
+	labels, split := merged.Split()
+	if !slices.Equal(labels, []string{"go", "js"}) || len(split[0].Runs[0].Benchmarks) != 2 || len(split[1].Runs[0].Benchmarks) != 1 {
+		t.Errorf("Split = %v, %+v, want the original documents back", labels, split)
+	}
+
+	if _, err := Merge([]string{"go", "go"}, []Results{goResults, goResults}); err == nil {
+		t.Error("Merge accepted the same label twice")
+	}
+	if _, err := Merge([]string{"go", "ast"}, []Results{goResults, {Suite: "ast"}}); err == nil {
+		t.Error("Merge accepted results of different suites")
+	}
+}
+
+func TestAggregateRepetitions(t *testing.T) {
+	rep := func(iterations []float64, median float64) Results {
+		return Results{Suite: "sort", Language: "go", Seed: 1, Runs: []ResultsRun{{Size: 3, Dataset: "random, 3 elements", Benchmarks: []BenchmarkSummary{
+			{Name: "Quicksort", Iterations: iterations, Median: median, StdDev: 1, OutlierIndexes: []int{2}, Outliers: 1},
+			{Name: "Bubble sort", TimedOut: true, Iterations: []float64{}},
+		}}}}
+	}
+	aggregated, err := AggregateRepetitions([]Results{rep([]float64{1, 2, 3}, 2), rep([]float64{3, 4, 5}, 4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aggregated.Repetitions != 2 || aggregated.Seed != 1 || len(aggregated.Runs) != 1 {
+		t.Fatalf("got %+v, want one run over 2 repetitions with their shared seed", aggregated)
+	}
+	quicksort := aggregated.Runs[0].Benchmarks[0]
+	if len(quicksort.Iterations) != 6 || quicksort.Median != 3 || !slices.Equal(quicksort.OutlierIndexes, []int{2, 5}) {
+		t.Errorf("Quicksort = %+v, want the iterations and outliers of both repetitions pooled", quicksort)
+	}
+	reps := quicksort.Repetitions
+	if reps == nil || !slices.Equal(reps.Medians, []float64{2, 4}) ||
+		math.Abs(reps.InterRunStdDev-math.Sqrt2) > 1e-12 || reps.IntraRunStdDev != 1 {
+		t.Errorf("Repetitions = %+v, want medians 2 and 4, inter-run stddev √2 and intra-run stddev 1", reps)
+	}
+	if bubble := aggregated.Runs[0].Benchmarks[1]; !bubble.TimedOut || bubble.Repetitions != nil {
+		t.Errorf("Bubble sort = %+v, want it timed out", bubble)
+	}
+
+	if _, err := AggregateRepetitions([]Results{rep(nil, 0), {Suite: "ast", Language: "go"}}); err == nil {
+		t.Error("AggregateRepetitions accepted results of different suites")
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	goResults := Results{Suite: "sort", Language: "go", Execution: ExecutionParallel, Parallelism: 4, Runs: []ResultsRun{{Size: 1000, Benchmarks: []BenchmarkSummary{
+		{Name: "Quicksort", Median: 2, Allocs: []uint64{2, 2}, AllocBytes: []uint64{1024, 2048}},
+		{Name: "Bubble sort", TimedOut: true},
+	}}}}
+	jsResults := Results{Suite: "sort", Language: "js", Runs: []ResultsRun{{Size: 1000, Benchmarks: []BenchmarkSummary{
+		{Name: "Quicksort", Median: 4},
+		{Name: "Array.prototype.sort", Median: 1},
+	}}}}
+
+	r, err := NewReport([]string{"go", "js"}, []Results{goResults, jsResults}, "js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	r.WriteMarkdown(&out)
+
+	want := `Median time per benchmark, with the speedup over js in parentheses.
+
+go ran 4 benchmarks in parallel, so its timings include contention between them.
+
+### sort, 1000 elements
+
+| Benchmark | go | js |
+| --- | ---: | ---: |
+| Quicksort | 2.00ms (2.00×) | 4.00ms |
+| Bubble sort | timed out | – |
+| Array.prototype.sort | – | 1.00ms |
+| *Geometric mean* | 2.00× over 1 | – |
+
+Heap allocated per operation:
+
+| Benchmark | go | js |
+| --- | ---: | ---: |
+| Quicksort | 1.50KB in 2 allocs | – |
+| Bubble sort | – | – |
+| Array.prototype.sort | – | – |
+
+### Geometric mean speedups
+
+How many times faster each row is than each column, over the benchmarks both completed.
+
+| | go | js |
+| --- | ---: | ---: |
+| go | – | 2.00× over 1 |
+| js | 0.50× over 1 | – |
+`
+	if out.String() != want {
+		t.Errorf("WriteMarkdown wrote\n%s\nwant\n%s", out.String(), want)
+	}
+
+	if _, err := NewReport([]string{"go", "js"}, []Results{goResults, jsResults}, "rust"); err == nil {
+		t.Error("NewReport accepted an unknown baseline")
+	}
+}
+
+func TestLabels(t *testing.T) {
+	results := []Results{{Language: "go"}, {Language: "go"}, {Language: "js"}}
+	labels := Labels([]string{"a/native.json", "b/wasm.json", "js.json"}, results)
+	if want := []string{"go (native)", "go (wasm)", "js"}; !slices.Equal(labels, want) {
+		t.Errorf("Labels = %v, want %v", labels, want)
+	}
+}
+
+func TestCurves(t *testing.T) {
+	run := func(size int, median float64) ResultsRun {
+		return ResultsRun{Size: size, Benchmarks: []BenchmarkSummary{{Name: "Quicksort", Median: median, Mean: median}}}
+	}
+	results := Results{Suite: "sort", Language: "go", Runs: []ResultsRun{run(1000, 2), run(100, 1)}}
+	r, err := NewReport([]string{"go"}, []Results{results}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	curves := r.curves()
+	if len(curves) != 1 {
+		t.Fatalf("curves = %d, want 1", len(curves))
+	}
+	if got := curves[0].Sizes; !slices.Equal(got, []int{100, 1000}) {
+		t.Errorf("sizes = %v, want [100 1000]", got)
+	}
+	if got := curves[0].Median[0]; *got[0] != 1 || *got[1] != 2 {
+		t.Errorf("medians = %v, %v, want 1, 2", *got[0], *got[1])
+	}
+
+	var out strings.Builder
+	if err := r.WriteHTML(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"title":"sort: Quicksort"`) {
+		t.Error("WriteHTML didn't embed the scaling curve")
+	}
+}
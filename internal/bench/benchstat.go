@@ -0,0 +1,83 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// WriteBenchstat writes every sample to path in the text format of go test
+// -bench, so golang.org/x/perf/cmd/benchstat can compare two runs. Runs swept
+// over sizes or datasets become size= and dataset= sub-benchmark keys, and
+// benchmarks that timed out or failed verification are left out since their
+// samples are incomplete.
+func (r Results) WriteBenchstat(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintf(w, "goos: %s\ngoarch: %s\npkg: jsconf/%s\n", r.Machine.OS, r.Machine.Arch, r.Suite)
+	if r.Machine.CPU != "" {
+		fmt.Fprintf(w, "cpu: %s\n", r.Machine.CPU)
+	}
+	fmt.Fprintf(w, "language: %s\nruntime: %s\n", r.Language, r.Machine.Runtime)
+
+	for _, run := range r.Runs {
+		var keys string
+		if run.Name != "" {
+			keys += "/dataset=" + benchstatName(run.Name)
+		}
+		if run.Size > 0 {
+			keys += fmt.Sprintf("/size=%d", run.Size)
+		}
+		for _, b := range run.Benchmarks {
+			if b.TimedOut || b.Failed() != "" {
+				continue
+			}
+			runsPerSample := max(b.RunsPerSample, 1)
+			for i, ms := range b.Iterations {
+				fmt.Fprintf(w, "Benchmark%s%s\t%d\t%s ns/op", benchstatName(b.Name), keys, runsPerSample, benchstatValue(ms*1e6))
+				if i < len(b.AllocBytes) {
+					fmt.Fprintf(w, "\t%d B/op\t%d allocs/op", b.AllocBytes[i], b.Allocs[i])
+				}
+				if i < len(b.CacheMisses) {
+					fmt.Fprintf(w, "\t%s cache-misses/op\t%s branch-misses/op",
+						benchstatValue(float64(b.CacheMisses[i])), benchstatValue(float64(b.BranchMisses[i])))
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// benchstatName makes name a single benchmark name field, which can't
+// contain spaces and uses / to separate sub-benchmarks
+func benchstatName(name string) string {
+	return strings.NewReplacer(" ", "_", "\t", "_", "/", "_").Replace(name)
+}
+
+// benchstatValue formats x with as many decimals as testing.B prints, so
+// small values keep their significant digits
+func benchstatValue(x float64) string {
+	switch y := math.Abs(x); {
+	case y == 0 || y >= 999.95:
+		return fmt.Sprintf("%.0f", x)
+	case y >= 99.995:
+		return fmt.Sprintf("%.1f", x)
+	case y >= 9.9995:
+		return fmt.Sprintf("%.2f", x)
+	case y >= 0.99995:
+		return fmt.Sprintf("%.3f", x)
+	default:
+		return fmt.Sprintf("%.4f", x)
+	}
+}
@@ -0,0 +1,40 @@
+package bench
+
+import "time"
+
+// BatchBenchmark is a Benchmark that can prepare input for several runs up
+// front, so calibration can time a batch of runs as one sample without timing
+// Setup. Benchmarks that don't implement it time every run on its own.
+type BatchBenchmark interface {
+	Benchmark
+	// SetupBatch prepares fresh input for each of the next n calls to Run
+	SetupBatch(n int)
+}
+
+// maxBatch bounds the runs per sample, and so the inputs prepared at once
+const maxBatch = 100000
+
+// calibrate probes b with growing batches until one takes at least minTime,
+// and returns that batch size. Like testing.B, each probe aims 20% past
+// minTime based on the last one and grows the batch at most 100x.
+func calibrate(b BatchBenchmark, minTime time.Duration) int {
+	clock := SystemClock()
+	n := 1
+	for {
+		b.SetupBatch(n)
+		start := clock.Now()
+		for range n {
+			b.Run()
+		}
+		elapsed := clock.Elapsed(start)
+		if elapsed >= minTime || n >= maxBatch {
+			return n
+		}
+
+		next := maxBatch
+		if elapsed > 0 {
+			next = int(1.2 * float64(n) * float64(minTime) / float64(elapsed))
+		}
+		n = min(max(next, n+1), 100*n, maxBatch)
+	}
+}
@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ChartStyles are the styles accepted by WriteBarChart, with "none" for
+// callers to turn the chart off
+var ChartStyles = []string{"unicode", "ascii", "none"}
+
+// chartWidth is the length in characters of the slowest benchmark's bar
+const chartWidth = 40
+
+// eighths are the Unicode blocks one to seven eighths wide, so bars can end
+// partway through a character
+var eighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// ChartRow is one benchmark in a bar chart
+type ChartRow struct {
+	Name     string
+	Median   time.Duration
+	TimedOut bool
+	// Failed marks benchmarks that panicked or failed verification
+	Failed bool
+}
+
+// WriteBarChart writes a bar per row scaled to the slowest median, so
+// relative performance is visible at a glance in logs. The "ascii" style
+// draws bars with # for terminals without Unicode.
+func WriteBarChart(w io.Writer, rows []ChartRow, style string) {
+	var slowest time.Duration
+	for _, row := range rows {
+		if !row.TimedOut && !row.Failed {
+			slowest = max(slowest, row.Median)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	for _, row := range rows {
+		switch {
+		case row.TimedOut:
+			fmt.Fprintf(tw, "%s\ttimed out\n", row.Name)
+			continue
+		case row.Failed:
+			fmt.Fprintf(tw, "%s\tfailed\n", row.Name)
+			continue
+		}
+		var width float64
+		if slowest > 0 {
+			width = float64(row.Median) / float64(slowest) * chartWidth
+		}
+		fmt.Fprintf(tw, "%s\t%s %s\n", row.Name, bar(width, style), FormatMilliseconds(Milliseconds(row.Median)))
+	}
+	tw.Flush()
+}
+
+// bar draws a bar width characters long, never less than the narrowest
+// mark so that fast benchmarks still show up
+func bar(width float64, style string) string {
+	if style == "ascii" {
+		return strings.Repeat("#", max(int(width+0.5), 1))
+	}
+	full, rest := int(width), int((width-float64(int(width)))*8)
+	if full == 0 && rest == 0 {
+		rest = 1
+	}
+	return strings.Repeat("█", full) + eighths[rest]
+}
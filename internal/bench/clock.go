@@ -0,0 +1,91 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// clockCalibrationReads is how many back-to-back readings calibration
+// takes to measure a reading's overhead, and clockResolutionSteps how many
+// changes of the reading it waits for to find the resolution
+const (
+	clockCalibrationReads = 1000
+	clockResolutionSteps  = 10
+	// maxSpinReads bounds the readings taken waiting for a coarse clock to
+	// change, in case it doesn't
+	maxSpinReads = 10000000
+)
+
+// Clock is the timer iterations are measured with. Native builds read Go's
+// monotonic clock. Under js/wasm it reads performance.now() directly, which
+// browsers coarsen to between 5µs and 1ms, so the same harness code times
+// iterations as finely as each environment allows. Intervals are reported
+// net of the cost of taking a reading.
+type Clock struct {
+	// Source names what the clock reads, e.g. "monotonic" or
+	// "performance.now"
+	Source string
+	// Overhead is the median cost of one reading, subtracted from every
+	// interval
+	Overhead time.Duration
+	// Resolution is the smallest step seen between two readings
+	Resolution time.Duration
+
+	now func() time.Duration
+}
+
+var (
+	systemClock     *Clock
+	systemClockOnce sync.Once
+)
+
+// SystemClock returns the clock of the environment the harness runs in,
+// calibrating it on first use
+func SystemClock() *Clock {
+	systemClockOnce.Do(func() {
+		systemClock = newClock(clockSource, clockNow)
+		slog.Debug(fmt.Sprintf("Timing with %s, resolution %v, overhead %v per reading",
+			systemClock.Source, systemClock.Resolution, systemClock.Overhead),
+			"source", systemClock.Source, "resolution", systemClock.Resolution, "overhead", systemClock.Overhead)
+	})
+	return systemClock
+}
+
+// newClock calibrates a clock reading now
+func newClock(source string, now func() time.Duration) *Clock {
+	c := &Clock{Source: source, now: now}
+	deltas := make([]time.Duration, clockCalibrationReads)
+	for i := range deltas {
+		start := now()
+		deltas[i] = now() - start
+	}
+	slices.Sort(deltas)
+	c.Overhead = deltas[len(deltas)/2]
+
+	for range clockResolutionSteps {
+		start := now()
+		step := time.Duration(0)
+		for reads := 0; step == 0 && reads < maxSpinReads; reads++ {
+			step = now() - start
+		}
+		if step > 0 && (c.Resolution == 0 || step < c.Resolution) {
+			c.Resolution = step
+		}
+	}
+	return c
+}
+
+// Now returns the time since an arbitrary fixed point, for passing to
+// Elapsed
+func (c *Clock) Now() time.Duration {
+	return c.now()
+}
+
+// Elapsed returns the time since start, a reading of Now, less the overhead
+// of a reading
+func (c *Clock) Elapsed(start time.Duration) time.Duration {
+	return max(c.now()-start-c.Overhead, 0)
+}
@@ -0,0 +1,21 @@
+//go:build js && wasm
+
+package bench
+
+import (
+	"syscall/js"
+	"time"
+)
+
+// clockSource is what the clock reads under js/wasm
+const clockSource = "performance.now"
+
+// performance is the global Performance object, looked up once so each
+// reading is a single call across the JS bridge
+var performance = js.Global().Get("performance")
+
+// clockNow reads performance.now(), in fractional milliseconds since the
+// page or process started
+func clockNow() time.Duration {
+	return time.Duration(performance.Call("now").Float() * float64(time.Millisecond))
+}
@@ -0,0 +1,16 @@
+//go:build !(js && wasm)
+
+package bench
+
+import "time"
+
+// clockSource is what the clock reads outside js/wasm
+const clockSource = "monotonic"
+
+// clockEpoch is the fixed point clockNow measures from, whose monotonic
+// reading makes it immune to wall clock changes
+var clockEpoch = time.Now()
+
+func clockNow() time.Duration {
+	return time.Since(clockEpoch)
+}
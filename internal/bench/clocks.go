@@ -0,0 +1,38 @@
+package bench
+
+import "fmt"
+
+// ClockSample is a best-effort reading of the CPU clock and temperature,
+// taken before and after each benchmark so slow results can be matched
+// with the CPU throttling. Readings the OS doesn't expose are left out.
+type ClockSample struct {
+	// MeanMHz and MinMHz are the mean and lowest current frequency over the
+	// CPUs
+	MeanMHz float64 `json:"meanMHz,omitempty"`
+	MinMHz  float64 `json:"minMHz,omitempty"`
+	// MaxCelsius is the temperature of the hottest thermal zone
+	MaxCelsius float64 `json:"maxCelsius,omitempty"`
+}
+
+// SampleClocks reads the CPU frequencies and temperatures, or returns nil
+// where the OS exposes neither
+func SampleClocks() *ClockSample {
+	return sampleClocks()
+}
+
+func (s *ClockSample) String() string {
+	if s == nil {
+		return "unknown"
+	}
+	text := ""
+	if s.MeanMHz > 0 {
+		text = fmt.Sprintf("%.0fMHz (min %.0fMHz)", s.MeanMHz, s.MinMHz)
+	}
+	if s.MaxCelsius > 0 {
+		if text != "" {
+			text += ", "
+		}
+		text += fmt.Sprintf("%.0f°C", s.MaxCelsius)
+	}
+	return text
+}
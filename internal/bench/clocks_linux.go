@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sampleClocks reads the CPU frequencies from cpufreq and the temperatures
+// from the thermal zones under /sys
+func sampleClocks() *ClockSample {
+	return readClocks("/sys/devices/system/cpu", "/sys/class/thermal", "/proc/cpuinfo")
+}
+
+// readClocks reads each CPU's current frequency from cpufreq under cpuDir,
+// falling back to the "cpu MHz" lines of cpuinfo where cpufreq isn't
+// exposed, as in many VMs, and the hottest thermal zone under thermalDir
+func readClocks(cpuDir, thermalDir, cpuinfo string) *ClockSample {
+	var mhz []float64
+	paths, _ := filepath.Glob(filepath.Join(cpuDir, "cpu[0-9]*", "cpufreq", "scaling_cur_freq"))
+	for _, path := range paths {
+		if khz, ok := readNumber(path); ok && khz > 0 {
+			mhz = append(mhz, khz/1000)
+		}
+	}
+	if len(mhz) == 0 {
+		if data, err := os.ReadFile(cpuinfo); err == nil {
+			mhz = parseCPUMHz(data)
+		}
+	}
+
+	var sample ClockSample
+	found := false
+	if len(mhz) > 0 {
+		found = true
+		sample.MinMHz = mhz[0]
+		for _, f := range mhz {
+			sample.MeanMHz += f
+			sample.MinMHz = min(sample.MinMHz, f)
+		}
+		sample.MeanMHz /= float64(len(mhz))
+	}
+	zones, _ := filepath.Glob(filepath.Join(thermalDir, "thermal_zone*", "temp"))
+	for _, path := range zones {
+		// Zones report millidegrees, and some report nothing useful as 0 or
+		// below
+		if milli, ok := readNumber(path); ok && milli > 0 {
+			found = true
+			sample.MaxCelsius = max(sample.MaxCelsius, milli/1000)
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &sample
+}
+
+// parseCPUMHz returns the "cpu MHz" of each processor in /proc/cpuinfo,
+// which x86 kernels list
+func parseCPUMHz(cpuinfo []byte) []float64 {
+	var mhz []float64
+	scanner := bufio.NewScanner(bytes.NewReader(cpuinfo))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "cpu MHz" {
+			continue
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && f > 0 {
+			mhz = append(mhz, f)
+		}
+	}
+	return mhz
+}
+
+// readNumber reads a file holding a single number, as sysfs attributes do
+func readNumber(path string) (float64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	return f, err == nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package bench
+
+// sampleClocks has no readings on systems other than Linux, where they
+// need privileged tools such as powermetrics
+func sampleClocks() *ClockSample { return nil }
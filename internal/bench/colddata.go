@@ -0,0 +1,97 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// ColdDataModes are the modes accepted by ColdDataConfig.Mode
+var ColdDataModes = []string{"warm", "cold", "both"}
+
+// defaultCacheBustMB is used when coldData doesn't set cacheBustMB. It's
+// larger than the last-level cache of desktop and laptop CPUs.
+const defaultCacheBustMB = 64
+
+// ColdSuffix is added to the names of benchmarks timed with cold data, so
+// they sit next to their warm results instead of replacing them
+const ColdSuffix = " (cold)"
+
+// ColdDataConfig controls what the CPU caches hold when each iteration
+// starts. Iterations of a benchmark otherwise find their input, freshly
+// copied by Setup, already cached, and an algorithm that has seen the same
+// input before has trained the branch predictor on it.
+type ColdDataConfig struct {
+	// Mode is "warm" (default) to time iterations as they come, "cold" to
+	// evict the caches before each one, or "both" to run each benchmark
+	// both ways, naming the cold results with a " (cold)" suffix
+	Mode string `json:"mode"`
+	// Shuffle also reorders the input before each cold iteration, for
+	// benchmarks that implement ShuffleBenchmark
+	Shuffle bool `json:"shuffle"`
+	// CacheBustMB is the size of the buffer written to evict the caches,
+	// defaulting to 64
+	CacheBustMB float64 `json:"cacheBustMB"`
+}
+
+// WithDefaults fills in the unset fields of c
+func (c ColdDataConfig) WithDefaults() ColdDataConfig {
+	if c.Mode == "" {
+		c.Mode = "warm"
+	}
+	if c.CacheBustMB == 0 {
+		c.CacheBustMB = defaultCacheBustMB
+	}
+	return c
+}
+
+// Validate checks c after WithDefaults, naming the offending key
+func (c ColdDataConfig) Validate() error {
+	switch {
+	case !slices.Contains(ColdDataModes, c.Mode):
+		return fmt.Errorf("mode: expected one of %s, got %q", strings.Join(ColdDataModes, ", "), c.Mode)
+	case c.CacheBustMB < 0:
+		return fmt.Errorf("cacheBustMB: must not be negative, got %v", c.CacheBustMB)
+	}
+	return nil
+}
+
+// Passes returns whether each run of a benchmark under c is cold, in the
+// order to run them: one pass for "warm" or "cold", two for "both"
+func (c ColdDataConfig) Passes() []bool {
+	switch c.Mode {
+	case "cold":
+		return []bool{true}
+	case "both":
+		return []bool{false, true}
+	}
+	return []bool{false}
+}
+
+// ShuffleBenchmark is a Benchmark that can reorder the input Setup
+// prepared without changing what Verify expects, e.g. a sort's unsorted
+// input
+type ShuffleBenchmark interface {
+	Benchmark
+	Shuffle(rng *rand.Rand)
+}
+
+// cacheBuster is written by bustCache. It's kept between benchmarks so
+// that it's allocated once.
+var cacheBuster []byte
+
+// cacheLine is the stride bustCache writes at, the cache line size of
+// current x86 and most ARM CPUs
+const cacheLine = 64
+
+// bustCache evicts whatever the CPU caches hold by writing to every cache
+// line of a buffer of size bytes
+func bustCache(size int) {
+	if len(cacheBuster) < size {
+		cacheBuster = make([]byte, size)
+	}
+	for i := 0; i < size; i += cacheLine {
+		cacheBuster[i]++
+	}
+}
@@ -0,0 +1,99 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// bootstrapResamples is the number of resamples drawn to estimate the
+// median's confidence interval
+const bootstrapResamples = 1000
+
+// Defaults for ConfidenceConfig
+const (
+	defaultConfidenceLevel = 0.95
+	defaultMaxIterations   = 1000
+)
+
+// ConfidenceConfig sets the confidence level of the interval reported for
+// each median, and optionally keeps a benchmark iterating until the interval
+// is narrow enough
+type ConfidenceConfig struct {
+	// Level is the confidence level of the interval, defaulting to 0.95
+	Level float64 `json:"level"`
+	// TargetWidth, when set, keeps iterating past the configured iterations
+	// until the interval's width relative to the median is at most this,
+	// e.g. 0.02 for 2%
+	TargetWidth float64 `json:"targetWidth"`
+	// MaxIterations bounds the timed iterations run while aiming for
+	// TargetWidth, defaulting to 1000
+	MaxIterations int `json:"maxIterations"`
+}
+
+// WithDefaults fills in the unset fields of c
+func (c ConfidenceConfig) WithDefaults() ConfidenceConfig {
+	if c.Level == 0 {
+		c.Level = defaultConfidenceLevel
+	}
+	if c.MaxIterations == 0 {
+		c.MaxIterations = defaultMaxIterations
+	}
+	return c
+}
+
+// Validate checks c after WithDefaults, naming the offending key
+func (c ConfidenceConfig) Validate() error {
+	switch {
+	case c.Level <= 0 || c.Level >= 1:
+		return fmt.Errorf("level: must be between 0 and 1, got %v", c.Level)
+	case c.TargetWidth < 0:
+		return fmt.Errorf("targetWidth: must not be negative, got %v", c.TargetWidth)
+	case c.MaxIterations < 1:
+		return fmt.Errorf("maxIterations: must be at least 1, got %d", c.MaxIterations)
+	}
+	return nil
+}
+
+// ConfidenceInterval is a bootstrap confidence interval on a median
+type ConfidenceInterval struct {
+	Level float64
+	Low   time.Duration
+	High  time.Duration
+	// RelativeWidth is High-Low as a fraction of the median
+	RelativeWidth float64
+}
+
+func (ci ConfidenceInterval) String() string {
+	return fmt.Sprintf("%.0f%% CI %s-%s (width %.1f%% of median)",
+		ci.Level*100, FormatMilliseconds(Milliseconds(ci.Low)), FormatMilliseconds(Milliseconds(ci.High)), ci.RelativeWidth*100)
+}
+
+// BootstrapMedianCI estimates a confidence interval on the median of samples,
+// which must not be empty, with the percentile bootstrap. The resamples are
+// drawn from a fixed seed so the same samples always give the same interval.
+func BootstrapMedianCI(samples []time.Duration, level float64) ConfidenceInterval {
+	rng := rand.New(rand.NewPCG(1, 2))
+	resample := make([]time.Duration, len(samples))
+	medians := make([]time.Duration, bootstrapResamples)
+	for i := range medians {
+		for j := range resample {
+			resample[j] = samples[rng.IntN(len(samples))]
+		}
+		slices.Sort(resample)
+		medians[i] = resample[len(resample)/2]
+	}
+	slices.Sort(medians)
+
+	tail := (1 - level) / 2
+	ci := ConfidenceInterval{
+		Level: level,
+		Low:   medians[int(tail*float64(len(medians)-1))],
+		High:  medians[int((1-tail)*float64(len(medians)-1)+0.5)],
+	}
+	if median := Median(samples); median > 0 {
+		ci.RelativeWidth = float64(ci.High-ci.Low) / float64(median)
+	}
+	return ci
+}
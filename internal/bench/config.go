@@ -0,0 +1,46 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DecodeConfig parses a JSON config file into v. Keys v doesn't declare are
+// rejected so typos don't silently fall back to defaults, and errors name the
+// offending key or position in the file.
+func DecodeConfig(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(err, &typeErr):
+			return fmt.Errorf("%s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		case errors.As(err, &syntaxErr):
+			line, column := position(data, syntaxErr.Offset)
+			return fmt.Errorf("line %d, column %d: %v", line, column, syntaxErr)
+		}
+		// encoding/json has no error type for unknown fields
+		if key, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return fmt.Errorf("unknown key %s", key)
+		}
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after the top-level object")
+	}
+	return nil
+}
+
+// position converts the offset of a json.SyntaxError, which is just past the
+// offending byte, to that byte's 1-based line and column
+func position(data []byte, offset int64) (line, column int) {
+	before := data[:min(max(int(offset)-1, 0), len(data))]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -0,0 +1,23 @@
+package bench
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// ProfileNames are the bundled configurations every Go suite embeds as
+// profiles/<name>.json and selects with -profile: "quick" for a smoke run
+// of small sizes and few iterations, "full" for the whole matrix the talk's
+// numbers come from, and "ci" for repeatable runs on CI machines
+var ProfileNames = []string{"quick", "full", "ci"}
+
+// ReadProfile returns the bundled configuration called name from profiles,
+// a suite's embedded profiles directory
+func ReadProfile(profiles fs.FS, name string) ([]byte, error) {
+	if !slices.Contains(ProfileNames, name) {
+		return nil, fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(ProfileNames, ", "))
+	}
+	return fs.ReadFile(profiles, "profiles/"+name+".json")
+}
@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Defaults for CooldownConfig
+const (
+	defaultDriftWindow       = 5
+	defaultDriftPauseSeconds = 30
+	defaultMaxDriftPauses    = 3
+)
+
+// CooldownConfig lets the CPU cool down during long runs. Laptops throttle
+// their CPUs once they heat up, which otherwise slows every benchmark in
+// the later part of a run.
+type CooldownConfig struct {
+	// Seconds is how long to sleep before each benchmark
+	Seconds float64 `json:"seconds"`
+	// DriftThreshold, when set, pauses a benchmark for PauseSeconds once
+	// the median of its last Window timed iterations is this fraction
+	// slower than the median of its first Window, e.g. 0.1 for 10%, which
+	// is how throttling shows up
+	DriftThreshold float64 `json:"driftThreshold"`
+	// Window is the number of iterations whose medians are compared,
+	// defaulting to 5
+	Window int `json:"window"`
+	// PauseSeconds is how long each pause lasts, defaulting to 30
+	PauseSeconds float64 `json:"pauseSeconds"`
+	// MaxPauses bounds the pauses per benchmark, defaulting to 3, since
+	// some benchmarks slow down for reasons a pause doesn't fix
+	MaxPauses int `json:"maxPauses"`
+}
+
+// WithDefaults fills in the unset fields of c
+func (c CooldownConfig) WithDefaults() CooldownConfig {
+	if c.Window == 0 {
+		c.Window = defaultDriftWindow
+	}
+	if c.PauseSeconds == 0 {
+		c.PauseSeconds = defaultDriftPauseSeconds
+	}
+	if c.MaxPauses == 0 {
+		c.MaxPauses = defaultMaxDriftPauses
+	}
+	return c
+}
+
+// Validate checks c after WithDefaults, naming the offending key
+func (c CooldownConfig) Validate() error {
+	switch {
+	case c.Seconds < 0:
+		return fmt.Errorf("seconds: must not be negative, got %v", c.Seconds)
+	case c.DriftThreshold < 0:
+		return fmt.Errorf("driftThreshold: must not be negative, got %v", c.DriftThreshold)
+	case c.Window < 1:
+		return fmt.Errorf("window: must be at least 1, got %d", c.Window)
+	case c.PauseSeconds <= 0:
+		return fmt.Errorf("pauseSeconds: must be positive, got %v", c.PauseSeconds)
+	case c.MaxPauses < 1:
+		return fmt.Errorf("maxPauses: must be at least 1, got %d", c.MaxPauses)
+	}
+	return nil
+}
+
+// drifted reports whether the samples since index since, which must be
+// past the first c.Window, have drifted upward from the first c.Window
+func drifted(samples []time.Duration, since int, c CooldownConfig) bool {
+	if c.DriftThreshold == 0 || len(samples)-since < c.Window {
+		return false
+	}
+	baseline := Median(samples[:c.Window])
+	recent := Median(samples[len(samples)-c.Window:])
+	return float64(recent) > float64(baseline)*(1+c.DriftThreshold)
+}
+
+// cooldown sleeps for d, or until the run is interrupted, logging why
+func cooldown(d time.Duration, message string, args ...any) {
+	if d <= 0 {
+		return
+	}
+	slog.Info(message, args...)
+	select {
+	case <-time.After(d):
+	case <-Interrupts():
+	}
+}
@@ -0,0 +1,116 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix starts the environment variables that override config keys
+const EnvPrefix = "BENCH_"
+
+// OutEnv names the results file when no -results flag is given
+const OutEnv = EnvPrefix + "OUT"
+
+// ApplyEnv overrides the top-level keys of config, a pointer to a struct
+// decoded by DecodeConfig, with the environment variables named after them:
+// iterations by BENCH_ITERATIONS, budgetSeconds by BENCH_BUDGET_SECONDS and
+// so on. Lists such as algos are comma-separated, and unset or empty
+// variables are ignored. Each override is logged, since it changes the run
+// without any sign in the config file.
+func ApplyEnv(config any) error {
+	return applyEnv(config, os.LookupEnv)
+}
+
+func applyEnv(config any, lookup func(string) (string, bool)) error {
+	v := reflect.ValueOf(config).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := EnvName(key)
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			continue
+		}
+		var err error
+		if field.Type.Kind() == reflect.Slice {
+			items := strings.Split(value, ",")
+			list := reflect.MakeSlice(field.Type, len(items), len(items))
+			for j, item := range items {
+				if err = setScalar(list.Index(j), strings.TrimSpace(item)); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				v.Field(i).Set(list)
+			}
+		} else {
+			err = setScalar(v.Field(i), value)
+		}
+		if err == errUnsupported {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		slog.Info(fmt.Sprintf("Overriding %s with %s=%s", key, name, value), "key", key, "env", name, "value", value)
+	}
+	return nil
+}
+
+// EnvName returns the environment variable overriding a config key, e.g.
+// BENCH_PUSH_URL for pushURL
+func EnvName(key string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	for i, r := range key {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := rune(key[i-1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// errUnsupported marks config keys of types, such as nested objects, that
+// the environment can't override
+var errUnsupported = errors.New("unsupported type")
+
+func setScalar(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		return errUnsupported
+	}
+	return nil
+}
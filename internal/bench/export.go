@@ -0,0 +1,84 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// OutputFormats are the formats WriteFormats writes, as listed by -out
+var OutputFormats = []string{"json", "csv", "benchstat", "md", "html"}
+
+// outputExtensions are the extensions OutputPath gives each format
+var outputExtensions = map[string]string{
+	"json":      ".json",
+	"csv":       ".csv",
+	"benchstat": ".txt",
+	"md":        ".md",
+	"html":      ".html",
+}
+
+// CheckOutputFormats rejects formats that aren't in OutputFormats
+func CheckOutputFormats(formats []string) error {
+	for _, format := range formats {
+		if !slices.Contains(OutputFormats, format) {
+			return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(OutputFormats, ", "))
+		}
+	}
+	return nil
+}
+
+// OutputPath is where WriteFormats writes format: the results file base
+// with its extension replaced by the format's, e.g. sort.md for sort.json
+func OutputPath(base, format string) string {
+	return strings.TrimSuffix(base, filepath.Ext(base)) + outputExtensions[format]
+}
+
+// WriteFormats writes the document in each of formats to the paths given by
+// OutputPath, all from memory, so a run yields its JSON, CSV, benchstat
+// samples and the markdown and HTML of the report tool at once. The markdown
+// and HTML show the document alone, as report would given just its file.
+func (r Results) WriteFormats(base string, formats []string) error {
+	if err := CheckOutputFormats(formats); err != nil {
+		return err
+	}
+	for _, format := range formats {
+		path := OutputPath(base, format)
+		var err error
+		switch format {
+		case "json":
+			err = r.Write(path)
+		case "csv":
+			err = r.WriteCSV(path)
+		case "benchstat":
+			err = r.WriteBenchstat(path)
+		case "md", "html":
+			err = r.writeReport(path, format)
+		}
+		if err != nil {
+			return fmt.Errorf("writing %s results: %w", format, err)
+		}
+	}
+	return nil
+}
+
+// writeReport writes the report of the document alone to path as markdown
+// or HTML
+func (r Results) writeReport(path, format string) error {
+	report, err := NewReport(Labels([]string{path}, []Results{r}), []Results{r}, "")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if format == "html" {
+		if err := report.WriteHTML(&buf); err != nil {
+			return err
+		}
+	} else {
+		report.WriteMarkdown(&buf)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,116 @@
+package bench
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// SeedMask keeps seeds within 53 bits so they survive a round trip through
+// JSON in JS, where numbers are doubles
+const SeedMask = 1<<53 - 1
+
+// NewSeed picks a seed from the clock
+func NewSeed() int64 {
+	return time.Now().UnixNano() & SeedMask
+}
+
+// ErrNotInterleavable is returned by RunInterleaved for a benchmark whose
+// options can't apply to it alone while others run in between: CPU and
+// heap profiles, traces and memory limits, which are all process-wide
+var ErrNotInterleavable = errors.New("profiles, traces and memory limits need benchmarks run one at a time")
+
+// Interleavable reports whether opts can be passed to RunInterleaved
+func (o Options) Interleavable() bool {
+	return o.CPUProfileDir == "" && o.MemProfileDir == "" && o.TraceDir == "" && o.MemoryLimit == 0
+}
+
+// RunInterleaved times benchmarks together, one iteration of each in turn,
+// so whatever one leaves behind, such as warm caches or a grown heap,
+// affects all of the others alike instead of only the next one in order.
+// The turns go round the benchmarks in an order shuffled with seed,
+// warmup iterations included, each benchmark running under Run with its
+// own opts. A benchmark's budget counts the other benchmarks' turns too.
+// The results and errors Run returned are at the benchmarks' indexes.
+func RunInterleaved(benchmarks []Benchmark, opts []Options, seed int64) ([]Result, []error) {
+	results := make([]Result, len(benchmarks))
+	errs := make([]error, len(benchmarks))
+	if len(benchmarks) == 0 {
+		return results, errs
+	}
+	order := rand.New(rand.NewSource(seed)).Perm(len(benchmarks))
+	turns := newRoundRobin(order)
+
+	var wg sync.WaitGroup
+	for _, i := range order {
+		if !opts[i].Interleavable() {
+			results[i] = Result{Name: benchmarks[i].Name(), Batch: 1}
+			errs[i] = ErrNotInterleavable
+			turns.done[i] = true
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-turns.turns[i]
+			o := opts[i]
+			o.turn = func() { turns.pass(i) }
+			results[i], errs[i] = Run(benchmarks[i], o)
+			turns.done[i] = true
+			turns.next(i)
+		}()
+	}
+	// The first turn goes to whichever benchmark follows the last
+	turns.next(order[len(order)-1])
+	wg.Wait()
+	return results, errs
+}
+
+// roundRobin passes the turn between the goroutines of RunInterleaved, so
+// only one of them runs at a time. Only the goroutine whose turn it is
+// touches done, and the channels order its writes before the next turn.
+type roundRobin struct {
+	order []int
+	// done is set, by index, for benchmarks that have returned
+	done  []bool
+	turns []chan struct{}
+}
+
+func newRoundRobin(order []int) *roundRobin {
+	r := &roundRobin{order: order, done: make([]bool, len(order)), turns: make([]chan struct{}, len(order))}
+	for i := range r.turns {
+		r.turns[i] = make(chan struct{}, 1)
+	}
+	return r
+}
+
+// after returns the first benchmark after i in order that hasn't returned,
+// which is i itself when it's the only one left
+func (r *roundRobin) after(i int) (int, bool) {
+	pos := 0
+	for p, j := range r.order {
+		if j == i {
+			pos = p
+		}
+	}
+	for k := 1; k <= len(r.order); k++ {
+		if j := r.order[(pos+k)%len(r.order)]; !r.done[j] {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// next hands the turn on from i
+func (r *roundRobin) next(i int) {
+	if j, ok := r.after(i); ok {
+		r.turns[j] <- struct{}{}
+	}
+}
+
+// pass hands the turn on from i and waits for it to come back
+func (r *roundRobin) pass(i int) {
+	r.next(i)
+	<-r.turns[i]
+}
@@ -0,0 +1,54 @@
+package bench
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// ErrInterrupted is returned by Run when the run was interrupted by
+// SIGINT or SIGTERM after HandleInterrupts, and wrapped by RunSuite's error
+// once it has written the results of the benchmarks that completed
+var ErrInterrupted = errors.New("interrupted")
+
+var (
+	// interrupted is checked between iterations and by ReportProgress,
+	// where a channel receive would be dearer
+	interrupted     atomic.Bool
+	interrupts      = make(chan struct{})
+	handleInterrupt sync.Once
+)
+
+// HandleInterrupts traps SIGINT and SIGTERM so an interrupted suite stops
+// at the next iteration, or the next ReportProgress, and can still write
+// the benchmarks that completed. A second signal exits at once.
+func HandleInterrupts() {
+	handleInterrupt.Do(func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			slog.Warn("Interrupted, stopping after the benchmarks that completed; interrupt again to quit at once",
+				"signal", sig.String())
+			interrupted.Store(true)
+			close(interrupts)
+			<-signals
+			os.Exit(1)
+		}()
+	})
+}
+
+// Interrupted reports whether the run has been interrupted
+func Interrupted() bool {
+	return interrupted.Load()
+}
+
+// Interrupts returns a channel that is closed once the run is interrupted,
+// for passing the interrupt on to child processes
+func Interrupts() <-chan struct{} {
+	return interrupts
+}
@@ -0,0 +1,68 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// LogFormats are the formats accepted by SetupLogging
+var LogFormats = []string{"text", "json"}
+
+// SetupLogging makes slog's default logger print a suite's log. The text
+// format prints each message on its own line on stdout, as the suites
+// always have, and json prints one object per record, with its attributes,
+// on stderr, leaving stdout to the charts and tables. Quiet leaves only
+// warnings and errors, and verbose adds debug records.
+func SetupLogging(format string, quiet, verbose bool) error {
+	level := slog.LevelInfo
+	switch {
+	case quiet && verbose:
+		return fmt.Errorf("-q and -v can't be used together")
+	case quiet:
+		level = slog.LevelWarn
+	case verbose:
+		level = slog.LevelDebug
+	}
+
+	switch format {
+	case "", "text":
+		slog.SetDefault(slog.New(newMessageHandler(os.Stdout, level)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	default:
+		return fmt.Errorf("log format: expected one of %s, got %q", strings.Join(LogFormats, ", "), format)
+	}
+	return nil
+}
+
+// messageHandler writes just the message of each record, one per line.
+// Attributes are left to the json format, since the messages already
+// include them in their human readable form.
+type messageHandler struct {
+	w     io.Writer
+	level slog.Level
+	mu    *sync.Mutex
+}
+
+func newMessageHandler(w io.Writer, level slog.Level) *messageHandler {
+	return &messageHandler{w: w, level: level, mu: &sync.Mutex{}}
+}
+
+func (h *messageHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *messageHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, r.Message+"\n")
+	return err
+}
+
+func (h *messageHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *messageHandler) WithGroup(string) slog.Handler      { return h }
@@ -0,0 +1,33 @@
+package bench
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// describeMachine fills in the details of m that macOS reports through
+// sysctl and pmset
+func describeMachine(m *Machine) {
+	m.CPU = sysctl("machdep.cpu.brand_string")
+	m.PhysicalCores, _ = strconv.Atoi(sysctl("hw.physicalcpu"))
+	m.MemoryBytes, _ = strconv.ParseUint(sysctl("hw.memsize"), 10, 64)
+	m.Kernel = sysctl("kern.osrelease")
+
+	// The first line of pmset's output names the power source, e.g. "Now
+	// drawing from 'AC Power'"
+	if out, err := exec.Command("pmset", "-g", "batt").Output(); err == nil {
+		line, _, _ := strings.Cut(string(out), "\n")
+		battery := strings.Contains(line, "'Battery Power'")
+		m.OnBattery = &battery
+	}
+}
+
+// sysctl returns the value of a sysctl, or "" if it can't be read
+func sysctl(name string) string {
+	out, err := exec.Command("sysctl", "-n", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// describeMachine fills in the details of m that Linux reports under /proc
+// and /sys
+func describeMachine(m *Machine) {
+	if cpuinfo, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		m.CPU, m.PhysicalCores = parseCPUInfo(cpuinfo)
+	}
+	if meminfo, err := os.ReadFile("/proc/meminfo"); err == nil {
+		m.MemoryBytes = parseMemTotal(meminfo)
+	}
+	if release, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		m.Kernel = strings.TrimSpace(string(release))
+	}
+	m.OnBattery = onBattery("/sys/class/power_supply")
+}
+
+// parseCPUInfo returns the processor model and the number of distinct
+// physical cores in /proc/cpuinfo. Cores are 0 where it doesn't list core
+// ids, as on most ARM systems.
+func parseCPUInfo(cpuinfo []byte) (model string, cores int) {
+	type core struct{ physical, id string }
+	seen := map[core]bool{}
+	var current core
+	scanner := bufio.NewScanner(bytes.NewReader(cpuinfo))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "model name":
+			if model == "" {
+				model = value
+			}
+		case "physical id":
+			current.physical = value
+		case "core id":
+			current.id = value
+			seen[current] = true
+		}
+	}
+	return model, len(seen)
+}
+
+// parseMemTotal returns MemTotal from /proc/meminfo in bytes
+func parseMemTotal(meminfo []byte) uint64 {
+	scanner := bufio.NewScanner(bytes.NewReader(meminfo))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err == nil {
+				return kb * 1024
+			}
+		}
+	}
+	return 0
+}
+
+// onBattery reports whether the machine is running on battery from the
+// power supplies under dir: on battery when there are mains supplies and
+// none is online. Machines without a battery, like most servers, are never
+// on battery, and the answer is nil when dir can't be read.
+func onBattery(dir string) *bool {
+	supplies, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	battery, mains, online := false, false, false
+	for _, supply := range supplies {
+		kind, _ := os.ReadFile(filepath.Join(dir, supply.Name(), "type"))
+		switch strings.TrimSpace(string(kind)) {
+		case "Battery":
+			battery = true
+		case "Mains":
+			mains = true
+			state, _ := os.ReadFile(filepath.Join(dir, supply.Name(), "online"))
+			online = online || strings.TrimSpace(string(state)) == "1"
+		}
+	}
+	result := battery && mains && !online
+	return &result
+}
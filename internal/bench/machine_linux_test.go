@@ -0,0 +1,76 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescribeMachineLinux(t *testing.T) {
+	cpuinfo := []byte(`processor	: 0
+model name	: Example CPU
+physical id	: 0
+core id		: 0
+
+processor	: 1
+model name	: Example CPU
+physical id	: 0
+core id		: 0
+
+processor	: 2
+model name	: Example CPU
+physical id	: 0
+core id		: 1
+`)
+	if model, cores := parseCPUInfo(cpuinfo); model != "Example CPU" || cores != 2 {
+		t.Errorf("parseCPUInfo = %q, %d, want %q, 2", model, cores, "Example CPU")
+	}
+	if got := parseMemTotal([]byte("MemTotal:       16384 kB\nMemFree:        1024 kB\n")); got != 16384*1024 {
+		t.Errorf("parseMemTotal = %d, want %d", got, 16384*1024)
+	}
+
+	dir := t.TempDir()
+	supply := func(name, kind, online string) {
+		os.MkdirAll(filepath.Join(dir, name), 0o755)
+		os.WriteFile(filepath.Join(dir, name, "type"), []byte(kind+"\n"), 0o644)
+		os.WriteFile(filepath.Join(dir, name, "online"), []byte(online+"\n"), 0o644)
+	}
+	supply("BAT0", "Battery", "")
+	supply("AC", "Mains", "0")
+	if got := onBattery(dir); got == nil || !*got {
+		t.Errorf("onBattery with mains offline = %v, want true", got)
+	}
+	supply("AC", "Mains", "1")
+	if got := onBattery(dir); got == nil || *got {
+		t.Errorf("onBattery with mains online = %v, want false", got)
+	}
+	if got := onBattery(filepath.Join(dir, "missing")); got != nil {
+		t.Errorf("onBattery without power supplies = %v, want nil", *got)
+	}
+}
+
+func TestReadClocks(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, value string) {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0o755)
+		os.WriteFile(filepath.Join(dir, path), []byte(value+"\n"), 0o644)
+	}
+	write("cpu/cpu0/cpufreq/scaling_cur_freq", "3000000")
+	write("cpu/cpu1/cpufreq/scaling_cur_freq", "1000000")
+	write("thermal/thermal_zone0/temp", "45000")
+	write("thermal/thermal_zone1/temp", "71500")
+	write("thermal/thermal_zone2/temp", "-273000")
+	write("cpuinfo", "processor\t: 0\ncpu MHz\t\t: 2100.000\n")
+
+	got := readClocks(filepath.Join(dir, "cpu"), filepath.Join(dir, "thermal"), filepath.Join(dir, "cpuinfo"))
+	if want := (ClockSample{MeanMHz: 2000, MinMHz: 1000, MaxCelsius: 71.5}); got == nil || *got != want {
+		t.Errorf("readClocks = %v, want %v", got, &want)
+	}
+	got = readClocks(filepath.Join(dir, "missing"), filepath.Join(dir, "missing"), filepath.Join(dir, "cpuinfo"))
+	if want := (ClockSample{MeanMHz: 2100, MinMHz: 2100}); got == nil || *got != want {
+		t.Errorf("readClocks without cpufreq = %v, want %v from cpuinfo", got, &want)
+	}
+	if got := readClocks(filepath.Join(dir, "missing"), filepath.Join(dir, "missing"), filepath.Join(dir, "missing")); got != nil {
+		t.Errorf("readClocks without readings = %v, want nil", got)
+	}
+}
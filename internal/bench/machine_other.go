@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package bench
+
+// describeMachine leaves the OS-specific details of m out on systems other
+// than Linux and macOS
+func describeMachine(m *Machine) {}
@@ -0,0 +1,59 @@
+package bench
+
+import (
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+)
+
+// MemoryLimitBytes converts a memory limit in megabytes, as configured, to
+// bytes, with 0 for no limit
+func MemoryLimitBytes(mb float64) int64 {
+	return int64(mb * 1024 * 1024)
+}
+
+// setMemoryLimit sets the runtime's soft memory limit for one benchmark, so
+// a memory-hungry one makes the GC work harder rather than running the host
+// out of memory, and returns a function restoring the previous limit
+func setMemoryLimit(limit int64) (restore func()) {
+	previous := debug.SetMemoryLimit(limit)
+	return func() { debug.SetMemoryLimit(previous) }
+}
+
+// gcPercent returns the GOGC setting, -1 when the GC is off
+func gcPercent() int {
+	sample := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return int(int64(sample[0].Value.Uint64()))
+}
+
+// memoryLimitSamples are the runtime metrics memoryLimitBinding reads,
+// allocated once so reading them between iterations doesn't allocate
+var memoryLimitSamples = []metrics.Sample{
+	{Name: "/gc/heap/goal:bytes"},
+	{Name: "/gc/heap/live:bytes"},
+	{Name: "/gc/gogc:percent"},
+}
+
+// memoryLimitBinding reports whether the memory limit is holding the heap
+// goal below the one GOGC sets from the live heap, which makes the GC run
+// more often than it otherwise would
+func memoryLimitBinding() bool {
+	metrics.Read(memoryLimitSamples)
+	for _, s := range memoryLimitSamples {
+		if s.Value.Kind() != metrics.KindUint64 {
+			return false
+		}
+	}
+	goal := memoryLimitSamples[0].Value.Uint64()
+	live := memoryLimitSamples[1].Value.Uint64()
+	// GOGC=off is reported as -1, when only the limit triggers collections
+	gogc := int64(memoryLimitSamples[2].Value.Uint64())
+	if gogc < 0 {
+		return true
+	}
+	return float64(goal) < math.Floor(float64(live)*(1+float64(gogc)/100))
+}
@@ -0,0 +1,255 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MergedSchemaVersion is bumped whenever Merged changes incompatibly
+const MergedSchemaVersion = 1
+
+// Merged combines the results documents of several implementations of one
+// suite, such as the Go suite natively and as WASM and the JS harness, so
+// their benchmarks can be read side by side
+type Merged struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Suite         string `json:"suite"`
+	// CreatedAt is when the documents were merged, in RFC 3339 format
+	CreatedAt       string           `json:"createdAt"`
+	Implementations []Implementation `json:"implementations"`
+	Runs            []MergedRun      `json:"runs"`
+}
+
+// Implementation is everything in one merged results document but its runs
+type Implementation struct {
+	// Label names the implementation in MergedRun's benchmarks, e.g. "js"
+	Label       string  `json:"label"`
+	Language    string  `json:"language"`
+	CreatedAt   string  `json:"createdAt"`
+	Machine     Machine `json:"machine"`
+	Source      *Source `json:"source,omitempty"`
+	Execution   string  `json:"execution,omitempty"`
+	Parallelism int     `json:"parallelism,omitempty"`
+	Partial     bool    `json:"partial,omitempty"`
+	Seed        int64   `json:"seed,omitempty"`
+	Repetitions int     `json:"repetitions,omitempty"`
+	Config      any     `json:"config"`
+}
+
+// MergedRun is a run of the suite over one dataset at one size, matched
+// across implementations by its name and size, since each language
+// describes its dataset in its own words
+type MergedRun struct {
+	Name string `json:"name,omitempty"`
+	Size int    `json:"size"`
+	// Datasets are each implementation's description of the dataset, by
+	// label
+	Datasets map[string]string `json:"datasets"`
+	// Benchmarks holds each benchmark's summary by name and then by the
+	// label of each implementation that ran it
+	Benchmarks map[string]map[string]BenchmarkSummary `json:"benchmarks"`
+}
+
+// Labels names each results document by its language, adding the file name
+// from paths where several documents share a language
+func Labels(paths []string, results []Results) []string {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Language]++
+	}
+	labels := make([]string, len(results))
+	for i, r := range results {
+		labels[i] = r.Language
+		if counts[r.Language] > 1 {
+			labels[i] = fmt.Sprintf("%s (%s)", r.Language, strings.TrimSuffix(filepath.Base(paths[i]), ".json"))
+		}
+	}
+	return labels
+}
+
+// Merge combines results documents of the same suite, labelled by labels
+func Merge(labels []string, results []Results) (Merged, error) {
+	merged := Merged{
+		SchemaVersion:   MergedSchemaVersion,
+		CreatedAt:       time.Now().UTC().Format(time.RFC3339),
+		Implementations: []Implementation{},
+		Runs:            []MergedRun{},
+	}
+	runs := map[string]int{}
+	for i, r := range results {
+		label := labels[i]
+		if i == 0 {
+			merged.Suite = r.Suite
+		} else if r.Suite != merged.Suite {
+			return Merged{}, fmt.Errorf("%s: can't merge %s results with %s results", label, r.Suite, merged.Suite)
+		}
+		if slices.ContainsFunc(merged.Implementations, func(impl Implementation) bool { return impl.Label == label }) {
+			return Merged{}, fmt.Errorf("label %q is used twice", label)
+		}
+		merged.Implementations = append(merged.Implementations, Implementation{
+			Label:       label,
+			Language:    r.Language,
+			CreatedAt:   r.CreatedAt,
+			Machine:     r.Machine,
+			Source:      r.Source,
+			Execution:   r.Execution,
+			Parallelism: r.Parallelism,
+			Partial:     r.Partial,
+			Seed:        r.Seed,
+			Repetitions: r.Repetitions,
+			Config:      r.Config,
+		})
+
+		for _, run := range r.Runs {
+			key := fmt.Sprintf("%s/%d", run.Name, run.Size)
+			j, ok := runs[key]
+			if !ok {
+				j = len(merged.Runs)
+				runs[key] = j
+				merged.Runs = append(merged.Runs, MergedRun{
+					Name:       run.Name,
+					Size:       run.Size,
+					Datasets:   map[string]string{},
+					Benchmarks: map[string]map[string]BenchmarkSummary{},
+				})
+			}
+			m := merged.Runs[j]
+			m.Datasets[label] = run.Dataset
+			for _, b := range run.Benchmarks {
+				if m.Benchmarks[b.Name] == nil {
+					m.Benchmarks[b.Name] = map[string]BenchmarkSummary{}
+				}
+				m.Benchmarks[b.Name][label] = b
+			}
+		}
+	}
+	return merged, nil
+}
+
+// Split turns m back into one results document per implementation, with
+// the benchmarks of each run in name order, returning their labels
+func (m Merged) Split() ([]string, []Results) {
+	labels := make([]string, len(m.Implementations))
+	results := make([]Results, len(m.Implementations))
+	for i, impl := range m.Implementations {
+		labels[i] = impl.Label
+		results[i] = Results{
+			SchemaVersion: ResultsSchemaVersion,
+			Suite:         m.Suite,
+			Language:      impl.Language,
+			CreatedAt:     impl.CreatedAt,
+			Machine:       impl.Machine,
+			Source:        impl.Source,
+			Execution:     impl.Execution,
+			Parallelism:   impl.Parallelism,
+			Partial:       impl.Partial,
+			Seed:          impl.Seed,
+			Repetitions:   impl.Repetitions,
+			Config:        impl.Config,
+			Runs:          []ResultsRun{},
+		}
+		for _, run := range m.Runs {
+			dataset, ok := run.Datasets[impl.Label]
+			if !ok {
+				continue
+			}
+			r := ResultsRun{Name: run.Name, Size: run.Size, Dataset: dataset}
+			for _, name := range slices.Sorted(maps.Keys(run.Benchmarks)) {
+				if b, ok := run.Benchmarks[name][impl.Label]; ok {
+					r.Benchmarks = append(r.Benchmarks, b)
+				}
+			}
+			results[i].Runs = append(results[i].Runs, r)
+		}
+	}
+	return labels, results
+}
+
+// Write writes the document to path as indented JSON
+func (m Merged) Write(path string) error {
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0o644)
+}
+
+// IsMerged reports whether the JSON document data is a Merged document
+// rather than a Results one
+func IsMerged(data []byte) bool {
+	var doc struct {
+		Implementations json.RawMessage `json:"implementations"`
+	}
+	return json.Unmarshal(data, &doc) == nil && doc.Implementations != nil
+}
+
+// ReadMerged reads a merged document from path, rejecting documents of
+// another schema version
+func ReadMerged(path string) (Merged, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Merged{}, err
+	}
+	var m Merged
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Merged{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if m.SchemaVersion != MergedSchemaVersion {
+		return Merged{}, fmt.Errorf("%s: unsupported merged schema version %d, expected %d", path, m.SchemaVersion, MergedSchemaVersion)
+	}
+	return m, nil
+}
+
+// ReadDocuments reads the results documents at paths, expanding merged
+// documents into their implementations, which keep their labels. The other
+// documents take the label given at the same index of given, if any, and
+// are otherwise labelled by Labels.
+func ReadDocuments(paths, given []string) (labels []string, results []Results, err error) {
+	var plain []int
+	var plainPaths []string
+	var plainResults []Results
+	for i, path := range paths {
+		label := ""
+		if i < len(given) {
+			label = given[i]
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if IsMerged(data) {
+			if label != "" {
+				return nil, nil, fmt.Errorf("%s: a merged document can't be relabelled", path)
+			}
+			m, err := ReadMerged(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			l, r := m.Split()
+			labels = append(labels, l...)
+			results = append(results, r...)
+			continue
+		}
+		r, err := ReadResults(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if label == "" {
+			plain = append(plain, len(results))
+			plainPaths = append(plainPaths, path)
+			plainResults = append(plainResults, r)
+		}
+		labels = append(labels, label)
+		results = append(results, r)
+	}
+	for i, label := range Labels(plainPaths, plainResults) {
+		labels[plain[i]] = label
+	}
+	return labels, results, nil
+}
@@ -0,0 +1,155 @@
+package bench
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OutlierMethods and OutlierPolicies are the accepted values of
+// OutlierConfig's Method and Policy
+var (
+	OutlierMethods  = []string{"mad", "iqr"}
+	OutlierPolicies = []string{"report", "trim", "winsorize"}
+)
+
+// Default thresholds: a modified z-score of 3.5 is Iglewicz and Hoaglin's
+// cutoff for MAD, and 1.5 IQRs past the quartiles is Tukey's fence
+const (
+	defaultMADThreshold = 3.5
+	defaultIQRThreshold = 1.5
+)
+
+// madScale makes the median absolute deviation comparable to a standard
+// deviation for normally distributed samples
+const madScale = 1.4826
+
+// OutlierConfig selects how samples far from the rest, such as an iteration
+// hit by a GC cycle or descheduled by the OS, are detected and handled
+type OutlierConfig struct {
+	// Method is "mad" (default), which flags samples more than Threshold
+	// scaled median absolute deviations from the median, or "iqr", which
+	// flags samples more than Threshold interquartile ranges outside the
+	// quartiles
+	Method string `json:"method"`
+	// Policy is "report" (default), which only counts outliers, "trim",
+	// which leaves them out of the statistics, or "winsorize", which clamps
+	// them to the nearest sample that isn't an outlier
+	Policy string `json:"policy"`
+	// Threshold defaults to 3.5 for "mad" and 1.5 for "iqr"
+	Threshold float64 `json:"threshold"`
+}
+
+// WithDefaults fills in the unset fields of c
+func (c OutlierConfig) WithDefaults() OutlierConfig {
+	if c.Method == "" {
+		c.Method = "mad"
+	}
+	if c.Policy == "" {
+		c.Policy = "report"
+	}
+	if c.Threshold == 0 {
+		c.Threshold = defaultMADThreshold
+		if c.Method == "iqr" {
+			c.Threshold = defaultIQRThreshold
+		}
+	}
+	return c
+}
+
+// Validate checks c after WithDefaults, naming the offending key
+func (c OutlierConfig) Validate() error {
+	switch {
+	case !slices.Contains(OutlierMethods, c.Method):
+		return fmt.Errorf("method: expected one of %s, got %q", strings.Join(OutlierMethods, ", "), c.Method)
+	case !slices.Contains(OutlierPolicies, c.Policy):
+		return fmt.Errorf("policy: expected one of %s, got %q", strings.Join(OutlierPolicies, ", "), c.Policy)
+	case c.Threshold <= 0:
+		return fmt.Errorf("threshold: must be positive, got %v", c.Threshold)
+	}
+	return nil
+}
+
+// outlierBounds returns the range of sorted samples that aren't outliers.
+// Samples with no spread, where the MAD or IQR is zero, have no outliers.
+func outlierBounds(sorted []time.Duration, c OutlierConfig) (low, high time.Duration) {
+	var center, spread float64
+	switch c.Method {
+	case "iqr":
+		q1 := float64(Percentile(sorted, 0.25))
+		q3 := float64(Percentile(sorted, 0.75))
+		spread = q3 - q1
+		low = time.Duration(q1 - c.Threshold*spread)
+		high = time.Duration(q3 + c.Threshold*spread)
+	default:
+		center = float64(sorted[len(sorted)/2])
+		deviations := make([]time.Duration, len(sorted))
+		for i, d := range sorted {
+			deviations[i] = time.Duration(max(float64(d)-center, center-float64(d)))
+		}
+		slices.Sort(deviations)
+		spread = madScale * float64(deviations[len(deviations)/2])
+		low = time.Duration(center - c.Threshold*spread)
+		high = time.Duration(center + c.Threshold*spread)
+	}
+	if spread == 0 {
+		return sorted[0], sorted[len(sorted)-1]
+	}
+	return low, high
+}
+
+// ApplyOutlierPolicy detects outliers in durations, which must not be empty,
+// and returns the samples to compute statistics from under c's policy along
+// with the number of outliers found
+func ApplyOutlierPolicy(durations []time.Duration, c OutlierConfig) (retained []time.Duration, outliers int) {
+	c = c.WithDefaults()
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	low, high := outlierBounds(sorted, c)
+
+	// The smallest and largest samples within the bounds, for winsorizing
+	first := slices.IndexFunc(sorted, func(d time.Duration) bool { return d >= low })
+	last := len(sorted) - 1
+	for last > first && sorted[last] > high {
+		last--
+	}
+
+	for _, d := range durations {
+		outlier := d < low || d > high
+		if outlier {
+			outliers++
+		}
+		switch {
+		case !outlier || c.Policy == "report":
+			retained = append(retained, d)
+		case c.Policy == "winsorize":
+			retained = append(retained, min(max(d, sorted[first]), sorted[last]))
+		}
+	}
+	return retained, outliers
+}
+
+// OutlierIndexes returns the indexes in durations of the samples
+// ApplyOutlierPolicy counts as outliers
+func OutlierIndexes(durations []time.Duration, c OutlierConfig) []int {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	low, high := outlierBounds(sorted, c.WithDefaults())
+	var indexes []int
+	for i, d := range durations {
+		if d < low || d > high {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// describeOutliers is appended to the summary line when there are outliers
+func describeOutliers(outliers, samples int, c OutlierConfig) string {
+	if outliers == 0 {
+		return ""
+	}
+	action := map[string]string{"report": "flagged", "trim": "trimmed", "winsorize": "winsorized"}[c.Policy]
+	return fmt.Sprintf(" [%d of %d samples %s as outliers by %s]", outliers, samples, action, c.Method)
+}
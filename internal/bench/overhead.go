@@ -0,0 +1,96 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EmptyName is the name of the benchmark returned by NewEmptyBenchmark
+const EmptyName = "empty"
+
+// overheadSamples is how many samples calibration takes of the empty
+// benchmark run once, and of it batched overheadBatch times
+const (
+	overheadSamples = 101
+	overheadBatch   = 1000
+)
+
+// overheadWarning is how many times the harness's overhead a median has to
+// be for Run not to warn that the overhead dominates it
+const overheadWarning = 10
+
+// overheadCeiling is the median at and over which Run doesn't measure the
+// overhead to warn about it, since no harness is slow enough to dominate it
+const overheadCeiling = 100 * time.Microsecond
+
+// emptyBenchmark does nothing, so timing it measures the harness alone
+type emptyBenchmark struct{}
+
+func (emptyBenchmark) Name() string     { return EmptyName }
+func (emptyBenchmark) Setup()           {}
+func (emptyBenchmark) SetupBatch(n int) {}
+func (emptyBenchmark) Run()             {}
+func (emptyBenchmark) Verify() error    { return nil }
+
+// NewEmptyBenchmark returns a benchmark whose Run does nothing. Its median
+// is the harness's own cost per sample, the floor under every other
+// benchmark timed the same way.
+func NewEmptyBenchmark() Benchmark {
+	return emptyBenchmark{}
+}
+
+// Overhead is the harness's own cost in a sample, besides the clock
+// readings that Clock already leaves out: calling Run and looping over the
+// batch
+type Overhead struct {
+	// PerSample is the median time of a sample of one run of the empty
+	// benchmark
+	PerSample time.Duration
+	// PerRun is the time each further run in a batch adds
+	PerRun time.Duration
+}
+
+// Sample returns the overhead of a sample of batch runs
+func (o Overhead) Sample(batch int) time.Duration {
+	return o.PerSample + time.Duration(max(batch-1, 0))*o.PerRun
+}
+
+var (
+	harnessOverhead     Overhead
+	harnessOverheadOnce sync.Once
+)
+
+// HarnessOverhead returns the harness's overhead, timing the empty
+// benchmark the way Run times iterations on first use
+func HarnessOverhead() Overhead {
+	harnessOverheadOnce.Do(func() {
+		harnessOverhead = measureOverhead(emptyBenchmark{})
+		slog.Debug(fmt.Sprintf("Harness overhead is %v per sample and %v per batched run",
+			harnessOverhead.PerSample, harnessOverhead.PerRun),
+			"perSample", harnessOverhead.PerSample, "perRun", harnessOverhead.PerRun)
+	})
+	return harnessOverhead
+}
+
+// measureOverhead times b, which should do nothing, in samples of one run
+// and of overheadBatch runs. Each sample follows a read of the memory stats
+// as in Run, which leaves the caches colder than back-to-back samples would.
+func measureOverhead(b Benchmark) Overhead {
+	median := func(batch int) time.Duration {
+		samples := make([]time.Duration, overheadSamples)
+		var stats runtime.MemStats
+		for i := range samples {
+			runtime.ReadMemStats(&stats)
+			samples[i], _ = timeIteration(b, batch, EmptyName, true, 0)
+		}
+		slices.Sort(samples)
+		return samples[len(samples)/2]
+	}
+	perSample := median(1)
+	perRun := max(median(overheadBatch)-perSample, 0) / (overheadBatch - 1)
+	return Overhead{PerSample: perSample, PerRun: perRun}
+}
@@ -0,0 +1,33 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// PerfCounts are the hardware counter values for one timed iteration
+type PerfCounts struct {
+	CacheMisses  uint64
+	BranchMisses uint64
+}
+
+// perfUnavailable is set once the counters fail to open, so the failure is
+// only reported for the first benchmark
+var perfUnavailable bool
+
+// openBenchmarkCounters opens the counters for one benchmark, or returns nil
+// when they are disabled or unavailable. When the counters can't be opened,
+// for example without permission to use perf_event_open, benchmarks run
+// without them.
+func openBenchmarkCounters(enabled bool) *perfCounters {
+	if !enabled || perfUnavailable {
+		return nil
+	}
+	counters, err := openPerfCounters()
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Perf counters unavailable, continuing without them: %v", err), "err", err)
+		perfUnavailable = true
+		return nil
+	}
+	return counters
+}
@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Constants from linux/perf_event.h
+const (
+	perfTypeHardware      = 0
+	perfCountCacheMisses  = 3
+	perfCountBranchMisses = 5
+
+	perfFlagDisabled      = 1 << 0
+	perfFlagExcludeKernel = 1 << 5
+	perfFlagExcludeHV     = 1 << 6
+
+	perfFlagFDCloexec = 1 << 3
+
+	perfIocEnable  = 0x2400
+	perfIocDisable = 0x2401
+	perfIocReset   = 0x2403
+)
+
+// perfEventAttr is the first version of struct perf_event_attr
+// (PERF_ATTR_SIZE_VER0), which is all the counters here need
+type perfEventAttr struct {
+	typ          uint32
+	size         uint32
+	config       uint64
+	samplePeriod uint64
+	sampleType   uint64
+	readFormat   uint64
+	flags        uint64
+	wakeupEvents uint32
+	bpType       uint32
+	config1      uint64
+}
+
+// perfCounters counts hardware cache misses and branch mispredictions for
+// the calling OS thread. Go may move a goroutine between threads, so the
+// goroutine is locked to its thread while counting, and work done on other
+// threads, such as by parallel quicksort's workers, isn't counted.
+type perfCounters struct {
+	cacheMisses  int
+	branchMisses int
+}
+
+func openPerfCounters() (*perfCounters, error) {
+	runtime.LockOSThread()
+	cacheMisses, err := openPerfEvent(perfCountCacheMisses)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return nil, fmt.Errorf("perf_event_open cache misses: %w", err)
+	}
+	branchMisses, err := openPerfEvent(perfCountBranchMisses)
+	if err != nil {
+		syscall.Close(cacheMisses)
+		runtime.UnlockOSThread()
+		return nil, fmt.Errorf("perf_event_open branch misses: %w", err)
+	}
+	return &perfCounters{cacheMisses: cacheMisses, branchMisses: branchMisses}, nil
+}
+
+func openPerfEvent(config uint64) (int, error) {
+	attr := perfEventAttr{
+		typ:    perfTypeHardware,
+		config: config,
+		flags:  perfFlagDisabled | perfFlagExcludeKernel | perfFlagExcludeHV,
+	}
+	attr.size = uint32(unsafe.Sizeof(attr))
+
+	// pid 0 and cpu -1 count this thread on any CPU
+	fd, _, errno := syscall.Syscall6(syscall.SYS_PERF_EVENT_OPEN,
+		uintptr(unsafe.Pointer(&attr)), 0, ^uintptr(0), ^uintptr(0), perfFlagFDCloexec, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// start resets and enables both counters
+func (p *perfCounters) start() {
+	for _, fd := range []int{p.cacheMisses, p.branchMisses} {
+		ioctl(fd, perfIocReset)
+		ioctl(fd, perfIocEnable)
+	}
+}
+
+// stop disables both counters and returns their counts since start
+func (p *perfCounters) stop() PerfCounts {
+	ioctl(p.cacheMisses, perfIocDisable)
+	ioctl(p.branchMisses, perfIocDisable)
+	return PerfCounts{
+		CacheMisses:  readPerfCount(p.cacheMisses),
+		BranchMisses: readPerfCount(p.branchMisses),
+	}
+}
+
+func (p *perfCounters) close() {
+	syscall.Close(p.cacheMisses)
+	syscall.Close(p.branchMisses)
+	runtime.UnlockOSThread()
+}
+
+func ioctl(fd int, request uintptr) {
+	syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, 0)
+}
+
+func readPerfCount(fd int) uint64 {
+	var buf [8]byte
+	if _, err := syscall.Read(fd, buf[:]); err != nil {
+		return 0
+	}
+	return binary.NativeEndian.Uint64(buf[:])
+}
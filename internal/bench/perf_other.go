@@ -0,0 +1,20 @@
+//go:build !linux
+
+package bench
+
+import "errors"
+
+// perfCounters is only implemented on Linux, via perf_event_open
+type perfCounters struct{}
+
+func openPerfCounters() (*perfCounters, error) {
+	return nil, errors.New("perf counters are only supported on Linux")
+}
+
+func (p *perfCounters) start() {}
+
+func (p *perfCounters) stop() PerfCounts {
+	return PerfCounts{}
+}
+
+func (p *perfCounters) close() {}
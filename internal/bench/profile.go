@@ -0,0 +1,101 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"unicode"
+)
+
+// cpuProfile is a pprof CPU profile being written to a file
+type cpuProfile struct {
+	file *os.File
+}
+
+// startCPUProfile starts profiling into dir, creating it if needed, in a
+// file named after the benchmark
+func startCPUProfile(dir, name string) (*cpuProfile, error) {
+	file, err := createProfile(dir, name, "cpu")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("starting CPU profile of %s: %w", name, err)
+	}
+	return &cpuProfile{file: file}, nil
+}
+
+func (p *cpuProfile) stop() error {
+	pprof.StopCPUProfile()
+	return p.file.Close()
+}
+
+// startTrace starts an execution trace into dir, creating it if needed, in
+// a file named after the benchmark
+func startTrace(dir, name string) (*os.File, error) {
+	file, err := createProfile(dir, name, "trace")
+	if err != nil {
+		return nil, err
+	}
+	if err := trace.Start(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("starting trace of %s: %w", name, err)
+	}
+	return file, nil
+}
+
+// writeHeapProfile writes the allocations sampled since the program started
+// to dir, creating it if needed. Heap profiles are cumulative, so the
+// allocations of one benchmark are the difference from a profile written
+// before it started, as shown by go tool pprof -base.
+func writeHeapProfile(dir, name, kind string) error {
+	file, err := createProfile(dir, name, kind)
+	if err != nil {
+		return err
+	}
+	// The profile is only brought up to date by a collection
+	runtime.GC()
+	if err := pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+		file.Close()
+		return fmt.Errorf("writing heap profile of %s: %w", name, err)
+	}
+	return file.Close()
+}
+
+func createProfile(dir, name, kind string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, ProfileFileName(name, kind))
+	slog.Debug(fmt.Sprintf("Writing %s", path), "benchmark", name, "kind", kind, "path", path)
+	return os.Create(path)
+}
+
+// ProfileFileName is the file a benchmark's profile of kind is written to,
+// e.g. "quick-sort.cpu.pprof" for the CPU profile of "Quick sort". Execution
+// traces, which go tool trace reads rather than pprof, are "quick-sort.trace".
+func ProfileFileName(name, kind string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if kind == "trace" {
+		return b.String() + ".trace"
+	}
+	return b.String() + "." + kind + ".pprof"
+}
@@ -0,0 +1,102 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// progressInterval throttles progress lines so long runs report roughly
+// once a second
+const progressInterval = time.Second
+
+// ErrTimedOut is panicked from ReportProgress once the iteration deadline has
+// passed and recovered by Run
+var ErrTimedOut = errors.New("iteration timed out")
+
+// iterationMonitor reports elapsed time and an ETA for the iteration
+// currently being timed and enforces its timeout. It is only checked when the
+// benchmark reports progress, so the cost inside the timed region is a clock
+// read per report.
+type iterationMonitor struct {
+	label    string
+	report   bool
+	start    time.Time
+	last     time.Time
+	deadline time.Time
+}
+
+// activeMonitor points to monitor while an iteration is running, and is
+// nil otherwise or in quiet mode without a timeout
+var (
+	activeMonitor *iterationMonitor
+	monitor       iterationMonitor
+)
+
+func startMonitor(label string, quiet bool, timeout time.Duration) {
+	if quiet && timeout == 0 {
+		return
+	}
+	// Reuse one monitor so starting it doesn't show up as an allocation in
+	// the iteration's memory stats
+	now := time.Now()
+	monitor = iterationMonitor{label: label, report: !quiet, start: now, last: now}
+	if timeout > 0 {
+		monitor.deadline = now.Add(timeout)
+	}
+	activeMonitor = &monitor
+}
+
+func stopMonitor() {
+	activeMonitor = nil
+}
+
+// timeIteration times batch calls to b.Run under a monitor labelled label and
+// reports whether it was stopped by the timeout
+func timeIteration(b Benchmark, batch int, label string, quiet bool, timeout time.Duration) (duration time.Duration, timedOut bool) {
+	startMonitor(label, quiet, timeout)
+	defer stopMonitor()
+	defer func() {
+		if r := recover(); r != nil {
+			if r != ErrTimedOut && r != ErrInterrupted {
+				panic(r)
+			}
+			timedOut = r == ErrTimedOut
+		}
+	}()
+
+	clock := SystemClock()
+	start := clock.Now()
+	for range batch {
+		b.Run()
+	}
+	return clock.Elapsed(start), false
+}
+
+// ReportProgress is called by long-running benchmarks with the fraction of
+// the iteration done so far. It panics with ErrTimedOut once the deadline
+// has passed, or with ErrInterrupted once the run is interrupted, which are
+// the only ways to stop a benchmark partway through.
+func ReportProgress(done float64) {
+	m := activeMonitor
+	if m == nil {
+		return
+	}
+	if interrupted.Load() {
+		panic(ErrInterrupted)
+	}
+	now := time.Now()
+	if !m.deadline.IsZero() && now.After(m.deadline) {
+		panic(ErrTimedOut)
+	}
+	if !m.report || now.Sub(m.last) < progressInterval || done <= 0 {
+		return
+	}
+	m.last = now
+
+	elapsed := now.Sub(m.start)
+	eta := time.Duration(float64(elapsed) * (1 - done) / done)
+	slog.Info(fmt.Sprintf("%s: %.0f%% (%.1fs elapsed, ETA %.1fs)", m.label, done*100, elapsed.Seconds(), eta.Seconds()),
+		"iteration", m.label, "done", done, "elapsed", elapsed, "eta", eta)
+}
@@ -0,0 +1,93 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// pushAttempts is how many times Push tries to upload before giving up
+const pushAttempts = 4
+
+// pushBackoff is the wait before the first retry, doubling after each
+var pushBackoff = time.Second
+
+// Push POSTs the document as JSON to url with headers, for collecting
+// results from several machines. It retries network errors, 429s and 5xxs
+// with exponential backoff. $VAR and ${VAR} in header values are expanded
+// from the environment, so tokens needn't be written into config files.
+func (r Results) Push(url string, headers map[string]string) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	backoff := pushBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := pushOnce(url, headers, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == pushAttempts {
+			return fmt.Errorf("pushing results to %s: %w", url, err)
+		}
+		slog.Warn(fmt.Sprintf("Pushing results failed (%v), retrying in %v", err, backoff), "err", err, "attempt", attempt)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// pushOnce makes one upload attempt and reports whether a failure is worth
+// retrying
+func pushOnce(url string, headers map[string]string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("%s", resp.Status)
+	if text := strings.TrimSpace(string(message)); text != "" {
+		err = fmt.Errorf("%s: %s", resp.Status, text)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// HeaderFlag collects repeated "Name: value" flags into headers for Push
+type HeaderFlag map[string]string
+
+func (h HeaderFlag) String() string {
+	var headers []string
+	for name, value := range h {
+		headers = append(headers, name+": "+value)
+	}
+	return strings.Join(headers, ", ")
+}
+
+func (h HeaderFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected \"Name: value\", got %q", s)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
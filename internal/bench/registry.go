@@ -0,0 +1,78 @@
+package bench
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Factory creates a benchmark ready to run
+type Factory func() Benchmark
+
+// Registration is a benchmark added by Register
+type Registration struct {
+	Name string
+	// Tags group benchmarks for filtering, e.g. by phase or input
+	Tags []string
+	New  Factory
+}
+
+// registry holds the registered benchmarks in registration order
+var registry []Registration
+
+// Register adds a benchmark to those run by RunSuite and Main, usually from
+// an init function in the file that defines it. It panics if name is
+// already registered, since results are matched by name.
+func Register(name string, tags []string, factory Factory) {
+	if slices.ContainsFunc(registry, func(r Registration) bool { return r.Name == name }) {
+		panic(fmt.Sprintf("bench: %s registered twice", name))
+	}
+	registry = append(registry, Registration{Name: name, Tags: tags, New: factory})
+}
+
+// Registered returns every registered benchmark in registration order
+func Registered() []Registration {
+	return slices.Clone(registry)
+}
+
+// Select returns the registered benchmarks whose names match the regular
+// expression run, or all of them when it's empty, and whose tags pass
+// MatchTags
+func Select(run string, tags, skipTags []string) ([]Registration, error) {
+	var pattern *regexp.Regexp
+	if run != "" {
+		var err error
+		if pattern, err = regexp.Compile(run); err != nil {
+			return nil, fmt.Errorf("run: %w", err)
+		}
+	}
+	var selected []Registration
+	for _, r := range registry {
+		if pattern != nil && !pattern.MatchString(r.Name) {
+			continue
+		}
+		if MatchTags(r.Tags, tags, skipTags) {
+			selected = append(selected, r)
+		}
+	}
+	return selected, nil
+}
+
+// MatchTags reports whether a benchmark tagged with have has every one of
+// tags and none of skipTags
+func MatchTags(have, tags, skipTags []string) bool {
+	if slices.ContainsFunc(tags, func(tag string) bool { return !slices.Contains(have, tag) }) {
+		return false
+	}
+	return !slices.ContainsFunc(skipTags, func(tag string) bool { return slices.Contains(have, tag) })
+}
+
+// ParseTags splits a comma-separated -tags or -skip-tags value, returning
+// nil for an empty one
+func ParseTags(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
@@ -0,0 +1,183 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// RepetitionSummary is how a benchmark's time varied over repetitions of
+// its whole suite, which tells variation between runs, such as from the
+// machine's state or the process's memory layout, apart from the noise
+// between the iterations of one run that StdDev measures
+type RepetitionSummary struct {
+	// Medians are the benchmark's median in each repetition
+	Medians []float64 `json:"medians"`
+	// InterRunStdDev is the sample standard deviation of Medians
+	InterRunStdDev float64 `json:"interRunStdDev"`
+	// IntraRunStdDev is the standard deviation of the iterations within
+	// each repetition, pooled over all of them
+	IntraRunStdDev float64 `json:"intraRunStdDev"`
+}
+
+// AggregateRepetitions combines the results of running one suite several
+// times into one document. Runs are matched by name and size, and
+// benchmarks by name, in the order of the first repetition. Each
+// benchmark's iterations are pooled across the repetitions, with their
+// statistics computed over all of them and outliers counted as the report
+// policy does, and Repetitions records how they varied between runs and
+// within them. Benchmarks that timed out, panicked or failed verification
+// in any repetition keep that repetition's summary alone.
+func AggregateRepetitions(reps []Results) (Results, error) {
+	if len(reps) == 0 {
+		return Results{}, fmt.Errorf("no repetitions to aggregate")
+	}
+	first := reps[0]
+	for i, r := range reps[1:] {
+		if r.Suite != first.Suite || r.Language != first.Language {
+			return Results{}, fmt.Errorf("repetition %d: can't aggregate %s %s results with %s %s results",
+				i+2, r.Language, r.Suite, first.Language, first.Suite)
+		}
+	}
+
+	aggregated := first
+	aggregated.CreatedAt = reps[len(reps)-1].CreatedAt
+	aggregated.Repetitions = len(reps)
+	aggregated.Runs = []ResultsRun{}
+	for _, r := range reps {
+		aggregated.Partial = aggregated.Partial || r.Partial
+		if r.Execution == ExecutionParallel {
+			aggregated.Execution = ExecutionParallel
+			aggregated.Parallelism = max(aggregated.Parallelism, r.Parallelism)
+		}
+		if r.Seed != first.Seed {
+			aggregated.Seed = 0
+		}
+	}
+
+	for _, run := range first.Runs {
+		combined := ResultsRun{Name: run.Name, Size: run.Size, Dataset: run.Dataset, Benchmarks: []BenchmarkSummary{}}
+		var matched []ResultsRun
+		for _, r := range reps {
+			i := slices.IndexFunc(r.Runs, func(other ResultsRun) bool { return other.Name == run.Name && other.Size == run.Size })
+			if i < 0 {
+				continue
+			}
+			matched = append(matched, r.Runs[i])
+			if r.Runs[i].Dataset != run.Dataset {
+				combined.Dataset = "varies between repetitions"
+			}
+		}
+		for _, b := range run.Benchmarks {
+			var summaries []BenchmarkSummary
+			for _, r := range matched {
+				if i := slices.IndexFunc(r.Benchmarks, func(other BenchmarkSummary) bool { return other.Name == b.Name }); i >= 0 {
+					summaries = append(summaries, r.Benchmarks[i])
+				}
+			}
+			combined.Benchmarks = append(combined.Benchmarks, aggregateBenchmark(summaries))
+		}
+		aggregated.Runs = append(aggregated.Runs, combined)
+	}
+	return aggregated, nil
+}
+
+// aggregateBenchmark pools the summaries of one benchmark from each
+// repetition that ran it
+func aggregateBenchmark(summaries []BenchmarkSummary) BenchmarkSummary {
+	for _, b := range summaries {
+		if b.TimedOut || b.Failed() != "" {
+			return b
+		}
+	}
+
+	pooled := BenchmarkSummary{
+		Name:         summaries[0].Name,
+		Adaptive:     summaries[0].Adaptive,
+		Stable:       summaries[0].Stable,
+		ClocksBefore: summaries[0].ClocksBefore,
+		ClocksAfter:  summaries[len(summaries)-1].ClocksAfter,
+		Iterations:   []float64{},
+		Repetitions: &RepetitionSummary{
+			Medians: []float64{},
+		},
+	}
+	level := defaultConfidenceLevel
+	var durations []time.Duration
+	var squares float64
+	var degrees int
+	for _, b := range summaries {
+		for _, i := range b.OutlierIndexes {
+			pooled.OutlierIndexes = append(pooled.OutlierIndexes, len(pooled.Iterations)+i)
+		}
+		pooled.Iterations = append(pooled.Iterations, b.Iterations...)
+		pooled.Unsteady = pooled.Unsteady || b.Unsteady
+		pooled.DriftPauses += b.DriftPauses
+		pooled.CacheMisses = append(pooled.CacheMisses, b.CacheMisses...)
+		pooled.BranchMisses = append(pooled.BranchMisses, b.BranchMisses...)
+		pooled.Allocs = append(pooled.Allocs, b.Allocs...)
+		pooled.AllocBytes = append(pooled.AllocBytes, b.AllocBytes...)
+		pooled.GCs += b.GCs
+		pooled.IterationGCs = append(pooled.IterationGCs, b.IterationGCs...)
+		pooled.GCPauses = append(pooled.GCPauses, b.GCPauses...)
+		pooled.MemoryLimitBytes = b.MemoryLimitBytes
+		pooled.LimitGCs += b.LimitGCs
+		pooled.RunsPerSample = max(pooled.RunsPerSample, b.RunsPerSample)
+		if b.MedianCI != nil {
+			level = b.MedianCI.Level
+		}
+		for _, ms := range b.Iterations {
+			durations = append(durations, time.Duration(ms*float64(time.Millisecond)))
+		}
+		pooled.Repetitions.Medians = append(pooled.Repetitions.Medians, b.Median)
+		if n := len(b.Iterations) - 1; n > 0 {
+			squares += float64(n) * b.StdDev * b.StdDev
+			degrees += n
+		}
+	}
+	pooled.Outliers = len(pooled.OutlierIndexes)
+	if len(durations) == 0 {
+		return pooled
+	}
+
+	stats := Summarize(durations)
+	pooled.Median = stats.Median
+	pooled.Mean = stats.Mean
+	pooled.StdDev = stats.StdDev
+	pooled.Min = stats.Min
+	pooled.Max = stats.Max
+	pooled.P90 = stats.P90
+	pooled.P95 = stats.P95
+	pooled.P99 = stats.P99
+	ci := BootstrapMedianCI(durations, level)
+	pooled.MedianCI = &ConfidenceSummary{
+		Level:         ci.Level,
+		Low:           Milliseconds(ci.Low),
+		High:          Milliseconds(ci.High),
+		RelativeWidth: ci.RelativeWidth,
+	}
+	pooled.Repetitions.InterRunStdDev = sampleStdDev(pooled.Repetitions.Medians)
+	if degrees > 0 {
+		pooled.Repetitions.IntraRunStdDev = math.Sqrt(squares / float64(degrees))
+	}
+	return pooled
+}
+
+// sampleStdDev is the sample standard deviation of values, zero for fewer
+// than two
+func sampleStdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(squares / float64(len(values)-1))
+}
@@ -0,0 +1,177 @@
+package bench
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// reportImplementation is one results document, which becomes a column of
+// a Report
+type reportImplementation struct {
+	label   string
+	results Results
+}
+
+// reportTable compares the implementations over one run of a suite. Runs
+// are matched across documents by suite, name and size, since each language
+// describes its dataset in its own words.
+type reportTable struct {
+	title      string
+	suite      string
+	run        string
+	size       int
+	benchmarks []string
+	// summaries holds each benchmark's summary per implementation, nil where
+	// an implementation didn't run it
+	summaries map[string][]*BenchmarkSummary
+}
+
+// Report is the comparison of several results documents, written as
+// markdown tables by WriteMarkdown or as an HTML page of charts by WriteHTML
+type Report struct {
+	implementations []reportImplementation
+	// baseline indexes the implementation speedups are relative to
+	baseline int
+	tables   []*reportTable
+}
+
+// NewReport lines up the benchmarks of every results document, labelled by
+// labels, in the order they first appear. baseline names the implementation
+// speedups are relative to, or is empty for the first one.
+func NewReport(labels []string, results []Results, baseline string) (*Report, error) {
+	implementations := make([]reportImplementation, len(results))
+	for i, r := range results {
+		implementations[i] = reportImplementation{label: labels[i], results: r}
+	}
+	r := &Report{implementations: implementations}
+	if baseline != "" {
+		r.baseline = slices.IndexFunc(implementations, func(impl reportImplementation) bool { return impl.label == baseline })
+		if r.baseline < 0 {
+			labels := make([]string, len(implementations))
+			for i, impl := range implementations {
+				labels[i] = impl.label
+			}
+			return nil, fmt.Errorf("unknown baseline %q, expected one of %s", baseline, strings.Join(labels, ", "))
+		}
+	}
+
+	tables := map[string]*reportTable{}
+	for i, impl := range implementations {
+		for _, run := range impl.results.Runs {
+			key := fmt.Sprintf("%s/%s/%d", impl.results.Suite, run.Name, run.Size)
+			t := tables[key]
+			if t == nil {
+				t = &reportTable{
+					title:     run.Title(impl.results.Suite),
+					suite:     impl.results.Suite,
+					run:       run.Name,
+					size:      run.Size,
+					summaries: map[string][]*BenchmarkSummary{},
+				}
+				tables[key] = t
+				r.tables = append(r.tables, t)
+			}
+			for j := range run.Benchmarks {
+				b := &run.Benchmarks[j]
+				if t.summaries[b.Name] == nil {
+					t.benchmarks = append(t.benchmarks, b.Name)
+					t.summaries[b.Name] = make([]*BenchmarkSummary, len(implementations))
+				}
+				t.summaries[b.Name][i] = b
+			}
+		}
+	}
+	return r, nil
+}
+
+// speedup is how many times faster b is than the baseline, or 0 when either
+// has no median
+func speedup(baseline, b *BenchmarkSummary) float64 {
+	if baseline == nil || b == nil || baseline.TimedOut || b.TimedOut || b.Median == 0 ||
+		baseline.Failed() != "" || b.Failed() != "" {
+		return 0
+	}
+	return baseline.Median / b.Median
+}
+
+// geomeanSpeedup is the geometric mean of how many times faster
+// implementation i is than implementation j, over the benchmarks in tables
+// both have medians for, and how many there were
+func (r *Report) geomeanSpeedup(tables []*reportTable, i, j int) (float64, int) {
+	var speedups []float64
+	for _, t := range tables {
+		for _, name := range t.benchmarks {
+			if s := speedup(t.summaries[name][j], t.summaries[name][i]); s > 0 {
+				speedups = append(speedups, s)
+			}
+		}
+	}
+	return GeometricMean(speedups), len(speedups)
+}
+
+// formatGeomean formats a geometric mean speedup over n benchmarks
+func formatGeomean(geomean float64, n int) string {
+	if n == 0 {
+		return "–"
+	}
+	return fmt.Sprintf("%.2f× over %d", geomean, n)
+}
+
+// allocated is the mean heap bytes and allocations of one run of b, and
+// whether they were recorded, which only Go suites do
+func allocated(b *BenchmarkSummary) (bytes, allocs float64, ok bool) {
+	if b == nil || len(b.AllocBytes) == 0 {
+		return 0, 0, false
+	}
+	for i := range b.AllocBytes {
+		bytes += float64(b.AllocBytes[i])
+		allocs += float64(b.Allocs[i])
+	}
+	n := float64(len(b.AllocBytes))
+	return bytes / n, allocs / n, true
+}
+
+// hasAllocations reports whether any implementation recorded allocations in
+// any of the tables
+func hasAllocations(tables []*reportTable) bool {
+	for _, t := range tables {
+		for _, summaries := range t.summaries {
+			for _, b := range summaries {
+				if _, _, ok := allocated(b); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasRepetitions reports whether any implementation pooled repetitions of
+// its suite in any of the tables
+func hasRepetitions(tables []*reportTable) bool {
+	for _, t := range tables {
+		for _, summaries := range t.summaries {
+			for _, b := range summaries {
+				if b != nil && b.Repetitions != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "1.50KB"
+func formatBytes(bytes float64) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%.0fB", bytes)
+	}
+	for _, unit := range []string{"KB", "MB"} {
+		bytes /= 1024
+		if bytes < 1024 {
+			return fmt.Sprintf("%.2f%s", bytes, unit)
+		}
+	}
+	return fmt.Sprintf("%.2fGB", bytes/1024)
+}
@@ -0,0 +1,229 @@
+package bench
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+)
+
+//go:embed report.html
+var htmlSource string
+
+var htmlTemplate = template.Must(template.New("report").Parse(htmlSource))
+
+// htmlData is what report.html renders. Medians and means are in
+// milliseconds, indexed by implementation, and null where an implementation
+// didn't run the benchmark or it timed out, panicked or failed
+// verification.
+type htmlData struct {
+	Implementations []htmlImplementation `json:"implementations"`
+	Baseline        int                  `json:"baseline"`
+	Tables          []htmlTable          `json:"tables"`
+	Curves          []htmlCurve          `json:"curves"`
+	// Allocations is set when any implementation recorded heap allocations,
+	// which can then be charted instead of times
+	Allocations bool `json:"allocations"`
+	// Speedups has a row per implementation with its geometric mean speedup
+	// over each implementation, across every table, when there are several
+	Speedups []htmlSpeedups `json:"-"`
+}
+
+type htmlSpeedups struct {
+	Label string
+	Cells []string
+}
+
+type htmlImplementation struct {
+	Label     string `json:"label"`
+	Suite     string `json:"suite"`
+	Runtime   string `json:"runtime"`
+	Platform  string `json:"platform"`
+	CPU       string `json:"cpu"`
+	Commit    string `json:"commit"`
+	CreatedAt string `json:"createdAt"`
+	// Partial is set when the run was interrupted
+	Partial bool `json:"partial"`
+}
+
+// htmlTable is a bar chart of every benchmark in one run
+type htmlTable struct {
+	Title      string          `json:"title"`
+	Benchmarks []htmlBenchmark `json:"benchmarks"`
+	// Geomean is each implementation's geometric mean speedup over the
+	// baseline, formatted, and empty for the baseline itself
+	Geomean []string `json:"geomean"`
+}
+
+type htmlBenchmark struct {
+	Name   string     `json:"name"`
+	Median []*float64 `json:"median"`
+	Mean   []*float64 `json:"mean"`
+	Bytes  []*float64 `json:"bytes"`
+}
+
+// htmlCurve is how one benchmark scales across the sizes of a sweep, with
+// Median, Mean and Bytes indexed by implementation and then by size
+type htmlCurve struct {
+	Title  string       `json:"title"`
+	Sizes  []int        `json:"sizes"`
+	Median [][]*float64 `json:"median"`
+	Mean   [][]*float64 `json:"mean"`
+	Bytes  [][]*float64 `json:"bytes"`
+}
+
+// WriteHTML writes the report as a single HTML file with bar charts per run
+// and scaling curves for runs swept over several sizes
+func (r *Report) WriteHTML(w io.Writer) error {
+	data := htmlData{Baseline: r.baseline, Tables: []htmlTable{}, Curves: r.curves(), Allocations: hasAllocations(r.tables)}
+	for _, impl := range r.implementations {
+		machine := impl.results.Machine
+		data.Implementations = append(data.Implementations, htmlImplementation{
+			Label:     impl.label,
+			Suite:     impl.results.Suite,
+			Runtime:   machine.Runtime,
+			Platform:  platformDescription(impl.results),
+			CPU:       machine.CPU,
+			Commit:    commitDescription(impl.results.Source),
+			CreatedAt: impl.results.CreatedAt,
+			Partial:   impl.results.Partial,
+		})
+	}
+	for _, t := range r.tables {
+		run := []*reportTable{t}
+		table := htmlTable{Title: t.title}
+		for _, name := range t.benchmarks {
+			median, mean, bytes := r.values(t, name)
+			table.Benchmarks = append(table.Benchmarks, htmlBenchmark{Name: name, Median: median, Mean: mean, Bytes: bytes})
+		}
+		for i := range r.implementations {
+			geomean := ""
+			if i != r.baseline {
+				geomean = formatGeomean(r.geomeanSpeedup(run, i, r.baseline))
+			}
+			table.Geomean = append(table.Geomean, geomean)
+		}
+		data.Tables = append(data.Tables, table)
+	}
+	if len(r.implementations) > 1 {
+		for i, impl := range r.implementations {
+			row := htmlSpeedups{Label: impl.label}
+			for j := range r.implementations {
+				cell := "–"
+				if i != j {
+					cell = formatGeomean(r.geomeanSpeedup(r.tables, i, j))
+				}
+				row.Cells = append(row.Cells, cell)
+			}
+			data.Speedups = append(data.Speedups, row)
+		}
+	}
+	return htmlTemplate.Execute(w, data)
+}
+
+// values returns the median and mean time of benchmark name in t, and the
+// heap bytes allocated per run, for each implementation
+func (r *Report) values(t *reportTable, name string) (median, mean, bytes []*float64) {
+	median = make([]*float64, len(r.implementations))
+	mean = make([]*float64, len(r.implementations))
+	bytes = make([]*float64, len(r.implementations))
+	for i, b := range t.summaries[name] {
+		if b != nil && !b.TimedOut && b.Failed() == "" {
+			median[i], mean[i] = &b.Median, &b.Mean
+		}
+		if allocatedBytes, _, ok := allocated(b); ok {
+			bytes[i] = &allocatedBytes
+		}
+	}
+	return median, mean, bytes
+}
+
+// curves collects a scaling curve for each benchmark of every suite and run
+// name swept over more than one size
+func (r *Report) curves() []htmlCurve {
+	type sweepKey struct{ suite, run string }
+	var order []sweepKey
+	sweeps := map[sweepKey][]*reportTable{}
+	for _, t := range r.tables {
+		if t.size == 0 {
+			continue
+		}
+		key := sweepKey{t.suite, t.run}
+		if sweeps[key] == nil {
+			order = append(order, key)
+		}
+		sweeps[key] = append(sweeps[key], t)
+	}
+
+	curves := []htmlCurve{}
+	for _, key := range order {
+		tables := sweeps[key]
+		if len(tables) < 2 {
+			continue
+		}
+		slices.SortFunc(tables, func(a, b *reportTable) int { return a.size - b.size })
+
+		var benchmarks []string
+		for _, t := range tables {
+			for _, name := range t.benchmarks {
+				if !slices.Contains(benchmarks, name) {
+					benchmarks = append(benchmarks, name)
+				}
+			}
+		}
+		for _, name := range benchmarks {
+			title := key.suite
+			if key.run != "" {
+				title += " " + key.run
+			}
+			curve := htmlCurve{
+				Title:  fmt.Sprintf("%s: %s", title, name),
+				Median: make([][]*float64, len(r.implementations)),
+				Mean:   make([][]*float64, len(r.implementations)),
+				Bytes:  make([][]*float64, len(r.implementations)),
+			}
+			for _, t := range tables {
+				curve.Sizes = append(curve.Sizes, t.size)
+				median, mean, bytes := r.values(t, name)
+				for i := range r.implementations {
+					curve.Median[i] = append(curve.Median[i], median[i])
+					curve.Mean[i] = append(curve.Mean[i], mean[i])
+					curve.Bytes[i] = append(curve.Bytes[i], bytes[i])
+				}
+			}
+			curves = append(curves, curve)
+		}
+	}
+	return curves
+}
+
+// platformDescription describes the machine results were measured on, and
+// whether its benchmarks shared it by running in parallel
+func platformDescription(results Results) string {
+	machine := results.Machine
+	description := fmt.Sprintf("%s/%s, %d CPUs", machine.OS, machine.Arch, machine.CPUs)
+	if results.Execution == ExecutionParallel {
+		description += fmt.Sprintf(", %d benchmarks in parallel", results.Parallelism)
+	}
+	return description
+}
+
+// commitDescription abbreviates the commit results were measured at, with
+// its branch and whether the tree was dirty
+func commitDescription(source *Source) string {
+	if source == nil {
+		return ""
+	}
+	description := source.Commit
+	if len(description) > 12 {
+		description = description[:12]
+	}
+	if source.Dirty {
+		description += " (dirty)"
+	}
+	if source.Branch != "" {
+		description += " on " + source.Branch
+	}
+	return description
+}
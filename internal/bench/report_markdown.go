@@ -0,0 +1,163 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes one GitHub-flavored markdown table per run, with a
+// row per benchmark and a column per implementation, followed by a table of
+// heap allocations where any implementation recorded them and one of the
+// variation between and within runs where any pools repetitions. With several
+// implementations each table ends with their geometric mean speedup, and a
+// last table compares every pair of them over all runs.
+func (r *Report) WriteMarkdown(w io.Writer) {
+	if len(r.implementations) > 1 {
+		fmt.Fprintf(w, "Median time per benchmark, with the speedup over %s in parentheses.\n", r.implementations[r.baseline].label)
+	} else {
+		fmt.Fprintln(w, "Median time per benchmark.")
+	}
+	for _, impl := range r.implementations {
+		if impl.results.Execution == ExecutionParallel {
+			fmt.Fprintf(w, "\n%s ran %d benchmarks in parallel, so its timings include contention between them.\n", impl.label, impl.results.Parallelism)
+		}
+		if impl.results.Partial {
+			fmt.Fprintf(w, "\n%s was interrupted, so benchmarks that hadn't completed are missing.\n", impl.label)
+		}
+		if impl.results.Repetitions > 1 {
+			fmt.Fprintf(w, "\n%s pools %d repetitions of the suite.\n", impl.label, impl.results.Repetitions)
+		}
+	}
+
+	for _, t := range r.tables {
+		fmt.Fprintf(w, "\n### %s\n\n", escapeMarkdown(t.title))
+		r.writeMarkdownHeader(w)
+		for _, name := range t.benchmarks {
+			summaries := t.summaries[name]
+			fmt.Fprintf(w, "| %s |", escapeMarkdown(name))
+			for i, b := range summaries {
+				fmt.Fprintf(w, " %s |", r.markdownCell(summaries[r.baseline], b, i == r.baseline))
+			}
+			fmt.Fprintln(w)
+		}
+		if len(r.implementations) > 1 {
+			fmt.Fprint(w, "| *Geometric mean* |")
+			for i := range r.implementations {
+				cell := "–"
+				if i != r.baseline {
+					cell = formatGeomean(r.geomeanSpeedup([]*reportTable{t}, i, r.baseline))
+				}
+				fmt.Fprintf(w, " %s |", cell)
+			}
+			fmt.Fprintln(w)
+		}
+
+		if hasAllocations([]*reportTable{t}) {
+			fmt.Fprint(w, "\nHeap allocated per operation:\n\n")
+			r.writeMarkdownHeader(w)
+			for _, name := range t.benchmarks {
+				fmt.Fprintf(w, "| %s |", escapeMarkdown(name))
+				for _, b := range t.summaries[name] {
+					fmt.Fprintf(w, " %s |", allocationCell(b))
+				}
+				fmt.Fprintln(w)
+			}
+		}
+
+		if hasRepetitions([]*reportTable{t}) {
+			fmt.Fprint(w, "\nStandard deviation between repetitions of the suite, and within them, as a percentage of the median:\n\n")
+			r.writeMarkdownHeader(w)
+			for _, name := range t.benchmarks {
+				fmt.Fprintf(w, "| %s |", escapeMarkdown(name))
+				for _, b := range t.summaries[name] {
+					fmt.Fprintf(w, " %s |", variationCell(b))
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+
+	if len(r.implementations) > 1 {
+		r.writeMarkdownSpeedups(w)
+	}
+}
+
+// writeMarkdownSpeedups writes how many times faster each implementation,
+// by row, is than each other one, by column, across every run
+func (r *Report) writeMarkdownSpeedups(w io.Writer) {
+	fmt.Fprint(w, "\n### Geometric mean speedups\n\n")
+	fmt.Fprint(w, "How many times faster each row is than each column, over the benchmarks both completed.\n\n")
+	fmt.Fprint(w, "| |")
+	for _, impl := range r.implementations {
+		fmt.Fprintf(w, " %s |", escapeMarkdown(impl.label))
+	}
+	fmt.Fprint(w, "\n| --- |")
+	for range r.implementations {
+		fmt.Fprint(w, " ---: |")
+	}
+	fmt.Fprintln(w)
+	for i, impl := range r.implementations {
+		fmt.Fprintf(w, "| %s |", escapeMarkdown(impl.label))
+		for j := range r.implementations {
+			cell := "–"
+			if i != j {
+				cell = formatGeomean(r.geomeanSpeedup(r.tables, i, j))
+			}
+			fmt.Fprintf(w, " %s |", cell)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func (r *Report) writeMarkdownHeader(w io.Writer) {
+	fmt.Fprint(w, "| Benchmark |")
+	for _, impl := range r.implementations {
+		fmt.Fprintf(w, " %s |", escapeMarkdown(impl.label))
+	}
+	fmt.Fprint(w, "\n| --- |")
+	for range r.implementations {
+		fmt.Fprint(w, " ---: |")
+	}
+	fmt.Fprintln(w)
+}
+
+func (r *Report) markdownCell(baseline, b *BenchmarkSummary, isBaseline bool) string {
+	switch {
+	case b == nil:
+		return "–"
+	case b.TimedOut:
+		return "timed out"
+	case b.Failed() != "":
+		return "failed"
+	}
+	cell := FormatMilliseconds(b.Median)
+	if s := speedup(baseline, b); s > 0 && !isBaseline {
+		cell += fmt.Sprintf(" (%.2f×)", s)
+	}
+	return cell
+}
+
+func allocationCell(b *BenchmarkSummary) string {
+	bytes, allocs, ok := allocated(b)
+	switch {
+	case !ok:
+		return "–"
+	case allocs == 0:
+		return "none"
+	}
+	return fmt.Sprintf("%s in %.0f allocs", formatBytes(bytes), allocs)
+}
+
+func variationCell(b *BenchmarkSummary) string {
+	if b == nil || b.Repetitions == nil || b.Median == 0 {
+		return "–"
+	}
+	return fmt.Sprintf("±%.1f%% between, ±%.1f%% within",
+		b.Repetitions.InterRunStdDev/b.Median*100, b.Repetitions.IntraRunStdDev/b.Median*100)
+}
+
+// escapeMarkdown keeps pipes in names from splitting table cells
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
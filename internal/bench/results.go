@@ -0,0 +1,398 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ResultsSchemaVersion is the version of the results document. It changes
+// whenever a field is removed or changes meaning, so consumers can reject
+// documents they don't understand. results.schema.json at the repository
+// root describes the current version.
+const ResultsSchemaVersion = 1
+
+// Results is the results document written by every suite, so report
+// generators and CI comparisons can read any of them. All durations are in
+// milliseconds.
+type Results struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Suite         string `json:"suite"`
+	Language      string `json:"language"`
+	// CreatedAt is when the document was written, in RFC 3339 format
+	CreatedAt string  `json:"createdAt"`
+	Machine   Machine `json:"machine"`
+	// Source is the commit that produced the results, when they were
+	// measured in a git checkout
+	Source *Source `json:"source,omitempty"`
+	// Execution is ExecutionSerial when benchmarks ran one at a time, as
+	// measurements should, ExecutionInterleaved when they also ran one at a
+	// time but took turns iteration by iteration, or ExecutionParallel when
+	// up to Parallelism of them shared the machine, which skews their
+	// timings
+	Execution   string `json:"execution"`
+	Parallelism int    `json:"parallelism,omitempty"`
+	// Partial is set when the run was interrupted, so the benchmarks that
+	// hadn't completed are missing
+	Partial bool `json:"partial,omitempty"`
+	// Seed is what the suite drew its generated data and other random
+	// choices from, which reproduces them when passed back to it
+	Seed int64 `json:"seed,omitempty"`
+	// Repetitions is set when the document aggregates this many runs of
+	// the whole suite, as written by AggregateRepetitions
+	Repetitions int `json:"repetitions,omitempty"`
+	// Config is a snapshot of the suite's configuration after defaults
+	Config any          `json:"config"`
+	Runs   []ResultsRun `json:"runs"`
+}
+
+// Machine describes where the benchmarks ran. Fields the OS doesn't report
+// are left out.
+type Machine struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// CPUs is the number of logical CPUs, and PhysicalCores the number of
+	// physical cores they share
+	CPUs          int `json:"cpus"`
+	PhysicalCores int `json:"physicalCores,omitempty"`
+	// GOMAXPROCS is the Go scheduler's limit on parallelism
+	GOMAXPROCS int `json:"gomaxprocs,omitempty"`
+	// GOGC is the Go garbage collector's target percentage, -1 when off
+	GOGC int `json:"gogc,omitempty"`
+	// CPU is the processor model
+	CPU string `json:"cpu,omitempty"`
+	// MemoryBytes is the total physical memory
+	MemoryBytes uint64 `json:"memoryBytes,omitempty"`
+	// Kernel is the OS kernel release, e.g. "6.8.0-45-generic"
+	Kernel string `json:"kernel,omitempty"`
+	// OnBattery is set when the machine was running on battery power, which
+	// usually throttles the CPU
+	OnBattery *bool `json:"onBattery,omitempty"`
+	// Timer is the clock the iterations were timed with
+	Timer *TimerSummary `json:"timer,omitempty"`
+	// Runtime is the language runtime and its version, e.g. "go1.25.1"
+	Runtime  string `json:"runtime"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// TimerSummary describes the Clock iterations were timed with, so results
+// from environments with coarse timers, such as browsers, can be told apart
+type TimerSummary struct {
+	// Source is what the clock reads, e.g. "monotonic" or "performance.now"
+	Source string `json:"source"`
+	// ResolutionMs is the smallest step seen between two readings, and
+	// OverheadMs the cost of a reading, which is left out of every
+	// iteration
+	ResolutionMs float64 `json:"resolutionMs"`
+	OverheadMs   float64 `json:"overheadMs"`
+}
+
+// ResultsRun is one pass of a suite, over one dataset at one size
+type ResultsRun struct {
+	Name       string             `json:"name,omitempty"`
+	Size       int                `json:"size"`
+	Dataset    string             `json:"dataset"`
+	Benchmarks []BenchmarkSummary `json:"benchmarks"`
+}
+
+// BenchmarkSummary is the samples and statistics of one benchmark
+type BenchmarkSummary struct {
+	Name string `json:"name"`
+	// Adaptive marks algorithms that exploit existing order in their input
+	Adaptive bool `json:"adaptive,omitempty"`
+	// Stable is whether the algorithm kept equal keys in input order, when
+	// the suite checked it
+	Stable   *bool `json:"stable,omitempty"`
+	TimedOut bool  `json:"timedOut,omitempty"`
+	// VerifyError is why the output failed verification, in which case the
+	// statistics are also left out
+	VerifyError string `json:"verifyError,omitempty"`
+	// Failure records where and why the benchmark panicked or failed
+	// verification, with the stack of a panic, in which case the statistics
+	// are also left out
+	Failure *Failure `json:"failure,omitempty"`
+	// Iterations are the completed iterations in run order, and the
+	// statistics are left out when the benchmark timed out
+	Iterations []float64 `json:"iterations"`
+	// Cold is set when the iterations ran with cold data, evicted from the
+	// CPU caches before each one
+	Cold bool `json:"cold,omitempty"`
+	// Warmup are the warmup iterations before them, which the statistics
+	// leave out, and Unsteady is set when they were cut off by the steady
+	// state detector's maxWarmup rather than by settling
+	Warmup   []float64 `json:"warmup,omitempty"`
+	Unsteady bool      `json:"unsteady,omitempty"`
+	// DriftPauses is how many times the benchmark was paused to cool down
+	// after its iteration times drifted upward, as thermal throttling
+	// makes them
+	DriftPauses int `json:"driftPauses,omitempty"`
+	// ClocksBefore and ClocksAfter are the CPU frequencies and temperature
+	// read before the first iteration and after the last, where the OS
+	// exposes them, to tell throttled results apart
+	ClocksBefore *ClockSample `json:"clocksBefore,omitempty"`
+	ClocksAfter  *ClockSample `json:"clocksAfter,omitempty"`
+	// CacheMisses and BranchMisses are per iteration, when perf counters
+	// are enabled. Like the allocations below, they are kept for
+	// benchmarks that timed out or failed verification.
+	CacheMisses  []uint64 `json:"cacheMisses,omitempty"`
+	BranchMisses []uint64 `json:"branchMisses,omitempty"`
+	Median       float64  `json:"median,omitempty"`
+	Mean         float64  `json:"mean,omitempty"`
+	StdDev       float64  `json:"stddev,omitempty"`
+	Min          float64  `json:"min,omitempty"`
+	Max          float64  `json:"max,omitempty"`
+	P90          float64  `json:"p90,omitempty"`
+	P95          float64  `json:"p95,omitempty"`
+	P99          float64  `json:"p99,omitempty"`
+
+	// Outliers is how many of Iterations were detected as outliers, and
+	// OutlierIndexes which ones. The statistics are computed after the
+	// outliers policy is applied.
+	Outliers       int   `json:"outliers,omitempty"`
+	OutlierIndexes []int `json:"outlierIndexes,omitempty"`
+	// MedianCI is the bootstrap confidence interval on Median
+	MedianCI *ConfidenceSummary `json:"medianCI,omitempty"`
+	// RunsPerSample is set when calibration batched several runs into each
+	// sample, in which case Iterations are per run
+	RunsPerSample int `json:"runsPerSample,omitempty"`
+	// OverheadMs is the harness's own overhead per run, which
+	// OverheadSubtracted says was taken off Iterations
+	OverheadMs         float64 `json:"overheadMs,omitempty"`
+	OverheadSubtracted bool    `json:"overheadSubtracted,omitempty"`
+	// Repetitions is how the benchmark varied between and within runs when
+	// the document aggregates several runs of the suite
+	Repetitions *RepetitionSummary `json:"repetitions,omitempty"`
+
+	// Allocs and AllocBytes are the heap allocations per iteration, and GCs
+	// the collections during all of them, which only Go suites record.
+	// IterationGCs and GCPauses are the collections and their total pause
+	// in milliseconds during each iteration, over its whole batch when
+	// RunsPerSample is set.
+	Allocs       []uint64  `json:"allocs,omitempty"`
+	AllocBytes   []uint64  `json:"allocBytes,omitempty"`
+	GCs          uint32    `json:"gcs,omitempty"`
+	IterationGCs []uint32  `json:"iterationGCs,omitempty"`
+	GCPauses     []float64 `json:"gcPauses,omitempty"`
+	// MemoryLimitBytes is the soft memory limit the benchmark ran under,
+	// and LimitGCs how many of GCs the limit forced to run early
+	MemoryLimitBytes int64  `json:"memoryLimitBytes,omitempty"`
+	LimitGCs         uint32 `json:"limitGCs,omitempty"`
+}
+
+// Title names the run for reports, by its name and size where set and
+// otherwise by its dataset
+func (r ResultsRun) Title(suite string) string {
+	title := suite
+	if r.Name != "" {
+		title += " " + r.Name
+	}
+	if r.Size > 0 {
+		title += fmt.Sprintf(", %d elements", r.Size)
+	} else if r.Dataset != "" {
+		title += ": " + r.Dataset
+	}
+	return title
+}
+
+// ConfidenceSummary is a ConfidenceInterval in milliseconds
+type ConfidenceSummary struct {
+	Level         float64 `json:"level"`
+	Low           float64 `json:"low"`
+	High          float64 `json:"high"`
+	RelativeWidth float64 `json:"relativeWidth"`
+}
+
+// Execution modes of a results document
+const (
+	ExecutionSerial      = "serial"
+	ExecutionParallel    = "parallel"
+	ExecutionInterleaved = "interleaved"
+)
+
+// NewResults starts a results document for a Go suite
+func NewResults(suite string, config any) Results {
+	return Results{
+		SchemaVersion: ResultsSchemaVersion,
+		Suite:         suite,
+		Language:      "go",
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		Machine:       currentMachine(),
+		Source:        currentSource(),
+		Execution:     ExecutionSerial,
+		Config:        config,
+		Runs:          []ResultsRun{},
+	}
+}
+
+func currentMachine() Machine {
+	machine := Machine{
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUs:       runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		GOGC:       gcPercent(),
+		Runtime:    runtime.Version(),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		machine.Hostname = hostname
+	}
+	clock := SystemClock()
+	machine.Timer = &TimerSummary{
+		Source:       clock.Source,
+		ResolutionMs: Milliseconds(clock.Resolution),
+		OverheadMs:   Milliseconds(clock.Overhead),
+	}
+	describeMachine(&machine)
+	return machine
+}
+
+// Summarize converts a benchmark's Result into its summary in the results
+// document
+func (r Result) Summarize() BenchmarkSummary {
+	summary := BenchmarkSummary{Name: r.Name, TimedOut: r.TimedOut, VerifyError: r.VerifyError, Failure: r.Failure, Iterations: []float64{}}
+	for _, d := range r.Durations {
+		summary.Iterations = append(summary.Iterations, Milliseconds(d))
+	}
+	for _, d := range r.Warmup {
+		summary.Warmup = append(summary.Warmup, Milliseconds(d))
+	}
+	summary.Cold = r.Cold
+	summary.Unsteady = r.Unsteady
+	summary.DriftPauses = r.DriftPauses
+	summary.ClocksBefore = r.ClocksBefore
+	summary.ClocksAfter = r.ClocksAfter
+	for _, perf := range r.Perf {
+		summary.CacheMisses = append(summary.CacheMisses, perf.CacheMisses)
+		summary.BranchMisses = append(summary.BranchMisses, perf.BranchMisses)
+	}
+	for _, mem := range r.Mem {
+		summary.Allocs = append(summary.Allocs, mem.Allocs)
+		summary.AllocBytes = append(summary.AllocBytes, mem.Bytes)
+		summary.GCs += mem.GCs
+		summary.IterationGCs = append(summary.IterationGCs, mem.GCs)
+		summary.GCPauses = append(summary.GCPauses, Milliseconds(mem.GCPause))
+	}
+	summary.MemoryLimitBytes = r.MemoryLimit
+	summary.LimitGCs = r.LimitGCs
+	if r.TimedOut || r.Failure != nil {
+		return summary
+	}
+
+	summary.Median = r.Stats.Median
+	summary.Mean = r.Stats.Mean
+	summary.StdDev = r.Stats.StdDev
+	summary.Min = r.Stats.Min
+	summary.Max = r.Stats.Max
+	summary.P90 = r.Stats.P90
+	summary.P95 = r.Stats.P95
+	summary.P99 = r.Stats.P99
+	summary.Outliers = r.Outliers
+	summary.OutlierIndexes = r.OutlierIndexes
+	summary.MedianCI = &ConfidenceSummary{
+		Level:         r.CI.Level,
+		Low:           Milliseconds(r.CI.Low),
+		High:          Milliseconds(r.CI.High),
+		RelativeWidth: r.CI.RelativeWidth,
+	}
+	if r.Batch > 1 {
+		summary.RunsPerSample = r.Batch
+	}
+	summary.OverheadMs = Milliseconds(r.Overhead)
+	summary.OverheadSubtracted = r.OverheadSubtracted
+	return summary
+}
+
+// Failed returns why the benchmark failed: its verifyError, or a
+// description of its failure when it panicked, or "" when it didn't fail
+func (b BenchmarkSummary) Failed() string {
+	switch {
+	case b.VerifyError != "":
+		return b.VerifyError
+	case b.Failure != nil:
+		return b.Failure.String()
+	}
+	return ""
+}
+
+// Write writes the document to path as indented JSON
+func (r Results) Write(path string) error {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0o644)
+}
+
+// ReadResults reads a results document from path, rejecting documents of
+// another schema version
+func ReadResults(path string) (Results, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Results{}, err
+	}
+	var r Results
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Results{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.SchemaVersion != ResultsSchemaVersion {
+		return Results{}, fmt.Errorf("%s: schemaVersion %d is not supported, expected %d", path, r.SchemaVersion, ResultsSchemaVersion)
+	}
+	return r, nil
+}
+
+// csvHeader names the columns written by WriteCSV
+var csvHeader = []string{
+	"suite", "language", "run", "dataset", "size", "benchmark", "iteration", "ms", "runsPerSample", "cacheMisses", "branchMisses",
+	"allocs", "allocBytes", "gcs", "gcPauseMs", "outlier",
+}
+
+// WriteCSV writes one row per iteration of every benchmark in every run to
+// path, for spreadsheets and dataframes. Perf counter and allocation columns
+// are empty when they weren't recorded, and warmup iterations are left out.
+func (r Results) WriteCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write(csvHeader)
+	for _, run := range r.Runs {
+		for _, b := range run.Benchmarks {
+			runsPerSample := max(b.RunsPerSample, 1)
+			for i, ms := range b.Iterations {
+				var cacheMisses, branchMisses string
+				if i < len(b.CacheMisses) {
+					cacheMisses = strconv.FormatUint(b.CacheMisses[i], 10)
+					branchMisses = strconv.FormatUint(b.BranchMisses[i], 10)
+				}
+				var allocs, allocBytes, gcs, gcPause string
+				if i < len(b.Allocs) {
+					allocs = strconv.FormatUint(b.Allocs[i], 10)
+					allocBytes = strconv.FormatUint(b.AllocBytes[i], 10)
+				}
+				if i < len(b.IterationGCs) {
+					gcs = strconv.FormatUint(uint64(b.IterationGCs[i]), 10)
+					gcPause = strconv.FormatFloat(b.GCPauses[i], 'f', -1, 64)
+				}
+				w.Write([]string{
+					r.Suite, r.Language, run.Name, run.Dataset, strconv.Itoa(run.Size), b.Name,
+					strconv.Itoa(i + 1), strconv.FormatFloat(ms, 'f', -1, 64), strconv.Itoa(runsPerSample),
+					cacheMisses, branchMisses, allocs, allocBytes, gcs, gcPause,
+					strconv.FormatBool(slices.Contains(b.OutlierIndexes, i)),
+				})
+			}
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
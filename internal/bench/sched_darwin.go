@@ -0,0 +1,26 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// applyScheduling applies c to the process. macOS only exposes niceness
+// without cgo: CPU affinity is a hint it doesn't offer on Apple silicon, and
+// real-time scheduling goes through Mach thread policies.
+func applyScheduling(c SchedulingConfig) error {
+	var errs []error
+	if c.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, c.Nice); err != nil {
+			errs = append(errs, fmt.Errorf("setting nice %d: %w", c.Nice, err))
+		}
+	}
+	if c.Realtime {
+		errs = append(errs, errors.New("real-time scheduling is only supported on Linux"))
+	}
+	if len(c.CPUAffinity) > 0 {
+		errs = append(errs, errors.New("CPU affinity is only supported on Linux"))
+	}
+	return errors.Join(errs...)
+}
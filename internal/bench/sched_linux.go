@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// schedFIFO is SCHED_FIFO from linux/sched.h
+const schedFIFO = 1
+
+// applyScheduling applies c to every thread of the process. Linux schedules
+// threads rather than processes, so niceness, policy and affinity are set
+// per thread through its ID in /proc/self/task. Threads and processes
+// started afterwards inherit them from the thread that starts them.
+func applyScheduling(c SchedulingConfig) error {
+	var mask [(maxCPU + 1) / 64]uint64
+	for _, cpu := range c.CPUAffinity {
+		mask[cpu/64] |= 1 << (cpu % 64)
+	}
+
+	// The Go runtime may start threads while the existing ones are being
+	// updated, from a thread not yet updated, so pass over them until no new
+	// ones turn up
+	var niceErr, realtimeErr, affinityErr error
+	done := map[int]bool{}
+	for {
+		tids, err := threadIDs()
+		if err != nil {
+			return err
+		}
+		updated := false
+		for _, tid := range tids {
+			if done[tid] {
+				continue
+			}
+			done[tid], updated = true, true
+			if c.Nice != 0 && niceErr == nil {
+				niceErr = syscall.Setpriority(syscall.PRIO_PROCESS, tid, c.Nice)
+			}
+			if c.Realtime && realtimeErr == nil {
+				param := struct{ priority int32 }{1}
+				if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER,
+					uintptr(tid), schedFIFO, uintptr(unsafe.Pointer(&param))); errno != 0 {
+					realtimeErr = errno
+				}
+			}
+			if len(c.CPUAffinity) > 0 && affinityErr == nil {
+				if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY,
+					uintptr(tid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask))); errno != 0 {
+					affinityErr = errno
+				}
+			}
+		}
+		if !updated {
+			break
+		}
+	}
+
+	var errs []error
+	if niceErr != nil {
+		errs = append(errs, fmt.Errorf("setting nice %d: %w", c.Nice, niceErr))
+	}
+	if realtimeErr != nil {
+		errs = append(errs, fmt.Errorf("setting the SCHED_FIFO policy: %w", realtimeErr))
+	}
+	if affinityErr != nil {
+		errs = append(errs, fmt.Errorf("setting CPU affinity %v: %w", c.CPUAffinity, affinityErr))
+	}
+	return errors.Join(errs...)
+}
+
+// threadIDs lists the IDs of the process's threads
+func threadIDs() ([]int, error) {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, err
+	}
+	var tids []int
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package bench
+
+import "errors"
+
+// applyScheduling is only implemented on Linux and macOS
+func applyScheduling(c SchedulingConfig) error {
+	return errors.New("scheduling settings are only supported on Linux and macOS")
+}
@@ -0,0 +1,136 @@
+package bench
+
+import (
+	"math"
+	"slices"
+)
+
+// MannWhitneyU returns the two-sided p-value of the Mann-Whitney U test
+// that samples a and b come from the same distribution, such as the
+// iterations of one benchmark in two runs. Unlike a t-test it assumes
+// nothing about the shape of the distributions, which for timings are
+// usually skewed by a long tail of slow iterations. Up to
+// exactMannWhitneyLimit samples in all the p-value is exact, counting the
+// splits of the pooled ranks that are at least as extreme; above it, it uses
+// the normal approximation with corrections for ties and continuity, which
+// is close by then. It is 1 when either sample is empty.
+func MannWhitneyU(a, b []float64) float64 {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	type sample struct {
+		value float64
+		inA   bool
+	}
+	var samples []sample
+	for _, v := range a {
+		samples = append(samples, sample{v, true})
+	}
+	for _, v := range b {
+		samples = append(samples, sample{v, false})
+	}
+	slices.SortFunc(samples, func(x, y sample) int {
+		switch {
+		case x.value < y.value:
+			return -1
+		case x.value > y.value:
+			return 1
+		}
+		return 0
+	})
+
+	// Tied values share the mean of the ranks they span. Ranks are kept
+	// doubled so those means stay whole numbers.
+	ranks := make([]int, len(samples))
+	var rankSumA, ties float64
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].value == samples[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k, s := range samples[i:j] {
+			ranks[i+k] = i + j + 1
+			if s.inA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	if len(samples) <= exactMannWhitneyLimit {
+		return exactMannWhitneyP(ranks, len(a), int(2*rankSumA))
+	}
+
+	n := n1 + n2
+	u := rankSumA - n1*(n1+1)/2
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+	z := max(math.Abs(u-mean)-0.5, 0) / math.Sqrt(variance)
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// exactMannWhitneyLimit is the most samples in all for which MannWhitneyU
+// computes the exact p-value, which takes time and memory proportional to
+// the samples cubed
+const exactMannWhitneyLimit = 20
+
+// exactMannWhitneyP returns the two-sided p-value of drawing a rank sum at
+// least as far from its mean as sumA, the doubled rank sum of the first
+// sample's n1 values, when every split of the doubled ranks into n1 and the
+// rest is equally likely
+func exactMannWhitneyP(ranks []int, n1, sumA int) float64 {
+	total := 0
+	for _, r := range ranks {
+		total += r
+	}
+	// ways[k][s] counts the subsets of the ranks seen so far with k members
+	// summing to s
+	ways := make([][]float64, n1+1)
+	for k := range ways {
+		ways[k] = make([]float64, total+1)
+	}
+	ways[0][0] = 1
+	for _, r := range ranks {
+		for k := n1; k > 0; k-- {
+			for s := total; s >= r; s-- {
+				ways[k][s] += ways[k-1][s-r]
+			}
+		}
+	}
+
+	// The mean of the doubled rank sum is n1 (n + 1), twice the mean of
+	// the rank sum
+	mean := n1 * (len(ranks) + 1)
+	distance := math.Abs(float64(sumA - mean))
+	var extreme, splits float64
+	for s, count := range ways[n1] {
+		splits += count
+		if math.Abs(float64(s-mean)) >= distance {
+			extreme += count
+		}
+	}
+	return extreme / splits
+}
+
+// MannWhitneyUMinP returns the smallest p-value MannWhitneyU can give for
+// samples of n1 and n2 values, that of two samples with no overlap. When it
+// isn't below the significance level, no difference between samples that
+// small can be told apart from chance.
+func MannWhitneyUMinP(n1, n2 int) float64 {
+	a := make([]float64, n1)
+	b := make([]float64, n2)
+	for i := range a {
+		a[i] = float64(i)
+	}
+	for i := range b {
+		b[i] = float64(n1 + i)
+	}
+	return MannWhitneyU(a, b)
+}
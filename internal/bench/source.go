@@ -0,0 +1,59 @@
+package bench
+
+import (
+	"os/exec"
+	"runtime/debug"
+	"strings"
+)
+
+// Source identifies the code that produced a results document
+type Source struct {
+	Commit string `json:"commit"`
+	// Dirty is set when the working tree had uncommitted changes
+	Dirty  bool   `json:"dirty,omitempty"`
+	Branch string `json:"branch,omitempty"`
+}
+
+// currentSource describes the commit the binary was built from, using the
+// VCS settings go build stamps into it and falling back to asking git, as
+// under go run. It's nil outside a git repository, and in wasm where git
+// can't be run.
+func currentSource() *Source {
+	var source Source
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				source.Commit = setting.Value
+			case "vcs.modified":
+				source.Dirty = setting.Value == "true"
+			}
+		}
+	}
+
+	head, err := git("rev-parse", "HEAD")
+	if source.Commit == "" {
+		if err != nil {
+			return nil
+		}
+		source.Commit = head
+		status, err := git("status", "--porcelain")
+		source.Dirty = err == nil && status != ""
+	}
+
+	// Build info doesn't record the branch, which is only trusted when the
+	// checkout is still at the commit the binary was built from
+	if head == source.Commit {
+		if branch, err := git("rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+			source.Branch = branch
+		}
+	}
+	return &source
+}
+
+// git runs a git command in the working directory and returns its trimmed
+// output
+func git(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
@@ -0,0 +1,103 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Stats summarises the timed iterations of one benchmark, in milliseconds
+type Stats struct {
+	Median float64
+	Mean   float64
+	// StdDev is the sample standard deviation, zero for a single sample
+	StdDev float64
+	Min    float64
+	Max    float64
+	P90    float64
+	P95    float64
+	P99    float64
+}
+
+// Summarize computes Stats for durations, which must not be empty
+func Summarize(durations []time.Duration) Stats {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	mean := Milliseconds(total) / float64(len(durations))
+
+	var squares float64
+	for _, d := range durations {
+		squares += (Milliseconds(d) - mean) * (Milliseconds(d) - mean)
+	}
+	var stdDev float64
+	if len(durations) > 1 {
+		stdDev = math.Sqrt(squares / float64(len(durations)-1))
+	}
+
+	return Stats{
+		Median: Milliseconds(Median(durations)),
+		Mean:   mean,
+		StdDev: stdDev,
+		Min:    Milliseconds(sorted[0]),
+		Max:    Milliseconds(sorted[len(sorted)-1]),
+		P90:    Milliseconds(Percentile(sorted, 0.90)),
+		P95:    Milliseconds(Percentile(sorted, 0.95)),
+		P99:    Milliseconds(Percentile(sorted, 0.99)),
+	}
+}
+
+// Median returns the upper median of durations, which must not be empty
+func Median(durations []time.Duration) time.Duration {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// Percentile uses the nearest-rank method on sorted durations, like the AST
+// benchmark's p95
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+}
+
+func Milliseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1000000
+}
+
+// FormatMilliseconds formats ms with two decimals, or enough to show three
+// significant digits below 0.1ms, which calibrated batches can measure
+func FormatMilliseconds(ms float64) string {
+	decimals := 2
+	if ms > 0 && ms < 0.1 {
+		decimals = 2 + int(math.Ceil(-math.Log10(ms)))
+	}
+	return strconv.FormatFloat(ms, 'f', decimals, 64) + "ms"
+}
+
+// String formats s for the summary line printed after each benchmark
+func (s Stats) String() string {
+	f := FormatMilliseconds
+	return fmt.Sprintf("%s (mean %s, stddev %s, min %s, max %s, p90 %s, p95 %s, p99 %s)",
+		f(s.Median), f(s.Mean), f(s.StdDev), f(s.Min), f(s.Max), f(s.P90), f(s.P95), f(s.P99))
+}
+
+// GeometricMean returns the geometric mean of ratios, which must all be
+// positive, or 0 when there are none. Unlike the arithmetic mean, it weighs
+// a 2× speedup and a 2× slowdown equally, so it summarizes speedups across
+// benchmarks of very different sizes.
+func GeometricMean(ratios []float64) float64 {
+	if len(ratios) == 0 {
+		return 0
+	}
+	var logs float64
+	for _, r := range ratios {
+		logs += math.Log(r)
+	}
+	return math.Exp(logs / float64(len(ratios)))
+}
@@ -0,0 +1,85 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Defaults for SteadyStateConfig
+const (
+	defaultSteadyWindow    = 5
+	defaultSteadyThreshold = 0.05
+	defaultMaxWarmup       = 100
+)
+
+// SteadyStateConfig replaces a guessed warmup count with warming up until
+// iteration times settle, as JIT compilation, cache warming and heap growth
+// make the first iterations of a benchmark drift
+type SteadyStateConfig struct {
+	// Enabled keeps warming up until the last Window warmup iterations are
+	// steady. The configured warmup becomes the minimum.
+	Enabled bool `json:"enabled"`
+	// Window is the number of consecutive iterations checked, defaulting
+	// to 5
+	Window int `json:"window"`
+	// Threshold is the coefficient of variation, the standard deviation
+	// over the mean, below which the window is steady, defaulting to 0.05
+	Threshold float64 `json:"threshold"`
+	// MaxWarmup bounds the warmup iterations, defaulting to 100, after
+	// which timing starts anyway and the benchmark is marked unsteady
+	MaxWarmup int `json:"maxWarmup"`
+}
+
+// WithDefaults fills in the unset fields of c
+func (c SteadyStateConfig) WithDefaults() SteadyStateConfig {
+	if c.Window == 0 {
+		c.Window = defaultSteadyWindow
+	}
+	if c.Threshold == 0 {
+		c.Threshold = defaultSteadyThreshold
+	}
+	if c.MaxWarmup == 0 {
+		c.MaxWarmup = defaultMaxWarmup
+	}
+	return c
+}
+
+// Validate checks c after WithDefaults, naming the offending key
+func (c SteadyStateConfig) Validate() error {
+	switch {
+	case c.Window < 2:
+		return fmt.Errorf("window: must be at least 2, got %d", c.Window)
+	case c.Threshold <= 0:
+		return fmt.Errorf("threshold: must be positive, got %v", c.Threshold)
+	case c.MaxWarmup < c.Window:
+		return fmt.Errorf("maxWarmup: must be at least window (%d), got %d", c.Window, c.MaxWarmup)
+	}
+	return nil
+}
+
+// steady reports whether the last c.Window of samples vary by at most
+// c.Threshold of their mean
+func steady(samples []time.Duration, c SteadyStateConfig) bool {
+	if len(samples) < c.Window {
+		return false
+	}
+	return variation(samples[len(samples)-c.Window:]) <= c.Threshold
+}
+
+// variation is the coefficient of variation of samples
+func variation(samples []time.Duration) float64 {
+	var total float64
+	for _, d := range samples {
+		total += float64(d)
+	}
+	mean := total / float64(len(samples))
+	if mean == 0 {
+		return 0
+	}
+	var squares float64
+	for _, d := range samples {
+		squares += (float64(d) - mean) * (float64(d) - mean)
+	}
+	return math.Sqrt(squares/float64(len(samples)-1)) / mean
+}
@@ -0,0 +1,437 @@
+package bench
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Suite describes the benchmarks run by RunSuite for the results document
+type Suite struct {
+	// Name is the suite recorded in results, e.g. "ast"
+	Name string
+	// Dataset describes the input of the registered benchmarks
+	Dataset string
+	// Profiles holds the suite's bundled configurations, read by ReadProfile
+	Profiles fs.FS
+}
+
+// SuiteConfig is the part of a suite's config file used by RunSuite
+type SuiteConfig struct {
+	Iterations int `json:"iterations"`
+	Warmup     int `json:"warmup"`
+	// BudgetSeconds runs each benchmark for this long instead of a fixed
+	// number of iterations
+	BudgetSeconds float64 `json:"budgetSeconds"`
+	// Outliers selects how outlying samples are handled, as in the sort
+	// benchmark's config.json
+	Outliers OutlierConfig `json:"outliers"`
+	// Confidence sets the median's confidence interval level and optional
+	// target width, also as in the sort benchmark
+	Confidence ConfidenceConfig `json:"confidence"`
+	// SteadyState warms up until iteration times settle instead of for a
+	// fixed count, also as in the sort benchmark
+	SteadyState SteadyStateConfig `json:"steadyState"`
+	// Scheduling sets the process's priority and CPU affinity, also as in
+	// the sort benchmark
+	Scheduling SchedulingConfig `json:"scheduling"`
+	// Cooldown sleeps before each benchmark and pauses those whose
+	// iteration times drift upward, also as in the sort benchmark
+	Cooldown CooldownConfig `json:"cooldown"`
+	// ColdData evicts the CPU caches before each iteration, also as in the
+	// sort benchmark
+	ColdData ColdDataConfig `json:"coldData"`
+	// SubtractOverhead takes the harness's own overhead per sample, timed
+	// with an empty benchmark, off every sample
+	SubtractOverhead bool `json:"subtractOverhead"`
+	// Interleave runs the benchmarks together, one iteration of each in
+	// turn in an order shuffled with Seed, as RunInterleaved does, so
+	// ordering effects between them average out. Seed is picked from the
+	// clock when unset.
+	Interleave bool  `json:"interleave"`
+	Seed       int64 `json:"seed"`
+	// MemoryLimitMB is the soft memory limit each benchmark runs under, and
+	// MemoryLimitsMB replaces it for benchmarks by name, so memory-hungry
+	// ones such as huge ASTs can't run the machine out of memory
+	MemoryLimitMB  float64            `json:"memoryLimitMB"`
+	MemoryLimitsMB map[string]float64 `json:"memoryLimitsMB"`
+	// Chart is how the bar chart of medians is drawn: "unicode" (default),
+	// "ascii" or "none"
+	Chart string `json:"chart"`
+}
+
+// Validate checks the config and fills in defaults
+func (c *SuiteConfig) Validate() error {
+	if c.Iterations < 1 {
+		return fmt.Errorf("iterations: must be at least 1, got %d", c.Iterations)
+	}
+	if c.Warmup < 0 {
+		return fmt.Errorf("warmup: must not be negative, got %d", c.Warmup)
+	}
+	if c.BudgetSeconds < 0 {
+		return fmt.Errorf("budgetSeconds: must not be negative, got %v", c.BudgetSeconds)
+	}
+	c.Outliers = c.Outliers.WithDefaults()
+	if err := c.Outliers.Validate(); err != nil {
+		return fmt.Errorf("outliers.%w", err)
+	}
+	c.Confidence = c.Confidence.WithDefaults()
+	if err := c.Confidence.Validate(); err != nil {
+		return fmt.Errorf("confidence.%w", err)
+	}
+	c.SteadyState = c.SteadyState.WithDefaults()
+	if err := c.SteadyState.Validate(); err != nil {
+		return fmt.Errorf("steadyState.%w", err)
+	}
+	if err := c.Scheduling.Validate(); err != nil {
+		return fmt.Errorf("scheduling.%w", err)
+	}
+	c.Cooldown = c.Cooldown.WithDefaults()
+	if err := c.Cooldown.Validate(); err != nil {
+		return fmt.Errorf("cooldown.%w", err)
+	}
+	c.ColdData = c.ColdData.WithDefaults()
+	if err := c.ColdData.Validate(); err != nil {
+		return fmt.Errorf("coldData.%w", err)
+	}
+	if c.MemoryLimitMB < 0 {
+		return fmt.Errorf("memoryLimitMB: must not be negative, got %v", c.MemoryLimitMB)
+	}
+	for name, mb := range c.MemoryLimitsMB {
+		if mb < 0 {
+			return fmt.Errorf("memoryLimitsMB.%s: must not be negative, got %v", name, mb)
+		}
+	}
+	if c.Interleave && (c.MemoryLimitMB > 0 || len(c.MemoryLimitsMB) > 0) {
+		return errors.New("interleave: memory limits need benchmarks run one at a time")
+	}
+	if c.Chart == "" {
+		c.Chart = "unicode"
+	}
+	if !slices.Contains(ChartStyles, c.Chart) {
+		return fmt.Errorf("chart: expected one of %s, got %q", strings.Join(ChartStyles, ", "), c.Chart)
+	}
+	return nil
+}
+
+// Flags are the command line flags of a suite run by RunSuite
+type Flags struct {
+	Config   string
+	Profile  string
+	List     bool
+	Run      string
+	Tags     string
+	SkipTags string
+	Empty    bool
+
+	Results   string
+	Out       string
+	Benchstat string
+	CSV       string
+
+	PushURL     string
+	PushHeaders HeaderFlag
+
+	CPUProfileDir string
+	MemProfileDir string
+	TraceDir      string
+
+	Nice        int
+	Realtime    bool
+	CPUAffinity string
+	Cooldown    float64
+	ColdData    string
+	Interleave  bool
+	Seed        int64
+
+	SubtractOverhead bool
+
+	Quiet     bool
+	Verbose   bool
+	LogFormat string
+}
+
+// Define defines the flags on fs
+func (f *Flags) Define(fs *flag.FlagSet) {
+	fs.StringVar(&f.Config, "config", "../config.json", "read iterations, warmup and the other harness settings from this file")
+	fs.StringVar(&f.Profile, "profile", "", "read the settings from this bundled configuration instead of -config: "+strings.Join(ProfileNames, ", "))
+	fs.BoolVar(&f.List, "list", false, "list the registered benchmarks and their tags instead of running them")
+	fs.StringVar(&f.Run, "run", "", "only run benchmarks whose names match this regular expression")
+	fs.StringVar(&f.Tags, "tags", "", "only run benchmarks with all of these comma-separated tags")
+	fs.StringVar(&f.SkipTags, "skip-tags", "", "skip benchmarks with any of these comma-separated tags")
+	fs.BoolVar(&f.Empty, "empty", false, "also run the harness's empty benchmark first, whose median is the harness's own overhead per sample")
+	fs.StringVar(&f.Results, "results", os.Getenv(OutEnv), "write results as JSON to this file (default $"+OutEnv+")")
+	fs.StringVar(&f.Out, "out", "", "also write the results in these comma-separated formats next to -results, e.g. json,csv,md,html: "+strings.Join(OutputFormats, ", "))
+	fs.StringVar(&f.Benchstat, "out-benchstat", "", "write every sample in the go test -bench format read by benchstat to this file")
+	fs.StringVar(&f.CSV, "out-csv", "", "write one row per benchmark as CSV to this file")
+	f.PushHeaders = HeaderFlag{}
+	fs.StringVar(&f.PushURL, "push-url", "", "POST the results document to this URL")
+	fs.Var(f.PushHeaders, "push-header", "header sent with -push-url as \"Name: value\", e.g. \"Authorization: Bearer $TOKEN\" (repeatable)")
+	fs.StringVar(&f.CPUProfileDir, "cpuprofile-dir", "", "write a pprof CPU profile of each benchmark's timed iterations to this directory")
+	fs.StringVar(&f.MemProfileDir, "memprofile-dir", "", "write heap profiles from before and after each benchmark's timed iterations to this directory, for go tool pprof -base")
+	fs.StringVar(&f.TraceDir, "trace-dir", "", "write an execution trace of each benchmark's timed iterations to this directory, for go tool trace")
+	fs.IntVar(&f.Nice, "nice", 0, "run at this niceness, from -20 (highest priority) to 19")
+	fs.BoolVar(&f.Realtime, "realtime", false, "run under the SCHED_FIFO real-time policy where permitted (Linux)")
+	fs.StringVar(&f.CPUAffinity, "cpu-affinity", "", "pin the benchmarks to these CPUs, e.g. 2,3 or 0-3 (Linux)")
+	fs.Float64Var(&f.Cooldown, "cooldown", 0, "sleep this many seconds before each benchmark to let the CPU cool down")
+	fs.StringVar(&f.ColdData, "cold-data", "", "evict the CPU caches before each iteration: warm (no), cold, or both to run each benchmark both ways")
+	fs.BoolVar(&f.Interleave, "interleave", false, "run the benchmarks together, one iteration of each in turn in an order drawn from -seed")
+	fs.Int64Var(&f.Seed, "seed", 0, "seed the -interleave order (default from the clock)")
+	fs.BoolVar(&f.SubtractOverhead, "subtract-overhead", false, "take the harness's own overhead, timed with the empty benchmark, off every sample")
+	DefineLogFlags(fs, &f.Quiet, &f.Verbose, &f.LogFormat)
+}
+
+// DefineLogFlags defines the -q, -v and -log-format flags read by
+// SetupLogging on fs
+func DefineLogFlags(fs *flag.FlagSet, quiet, verbose *bool, format *string) {
+	fs.BoolVar(quiet, "q", false, "only log warnings and errors")
+	fs.BoolVar(verbose, "v", false, "also log debug details, such as calibration and the profiles written")
+	fs.StringVar(format, "log-format", "text", "log format: text, or json for one object per record on stderr")
+}
+
+// Main is the entry point of a suite binary made of registered benchmarks:
+// it parses the command line flags and runs the suite, exiting on errors
+func Main(suite Suite) {
+	var flags Flags
+	flags.Define(flag.CommandLine)
+	flag.Parse()
+	if err := RunSuite(suite, flags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// RunSuite runs the registered benchmarks selected by flags with the
+// settings in flags.Config, overridden by the environment as described by
+// ApplyEnv, prints a chart of their medians and writes and pushes the
+// results document as flags ask. Benchmarks that panic or fail
+// verification are recorded and the rest still run, but an error is
+// returned once the results are written. When the run is interrupted, the benchmarks that
+// completed are written as a partial document and the error wraps
+// ErrInterrupted.
+func RunSuite(suite Suite, flags Flags) error {
+	if err := SetupLogging(flags.LogFormat, flags.Quiet, flags.Verbose); err != nil {
+		return err
+	}
+	selected, err := Select(flags.Run, ParseTags(flags.Tags), ParseTags(flags.SkipTags))
+	if err != nil {
+		return err
+	}
+	var formats []string
+	if flags.Out != "" {
+		formats = strings.Split(flags.Out, ",")
+		if err := CheckOutputFormats(formats); err != nil {
+			return fmt.Errorf("-out: %w", err)
+		}
+		if flags.Results == "" {
+			return errors.New("-out: needs -results to name the files")
+		}
+	}
+	if flags.List {
+		for _, r := range selected {
+			fmt.Printf("%s\t%s\n", r.Name, strings.Join(r.Tags, ","))
+		}
+		return nil
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no registered benchmarks match -run %q, -tags %q and -skip-tags %q", flags.Run, flags.Tags, flags.SkipTags)
+	}
+
+	source := flags.Config
+	var configFile []byte
+	if flags.Profile != "" {
+		source = "profile " + flags.Profile
+		if suite.Profiles == nil {
+			return fmt.Errorf("the %s suite has no bundled profiles", suite.Name)
+		}
+		configFile, err = ReadProfile(suite.Profiles, flags.Profile)
+	} else {
+		configFile, err = os.ReadFile(flags.Config)
+	}
+	if err != nil {
+		return err
+	}
+	var config SuiteConfig
+	if err := DecodeConfig(configFile, &config); err != nil {
+		return fmt.Errorf("parsing %s: %w", source, err)
+	}
+	if err := ApplyEnv(&config); err != nil {
+		return err
+	}
+	if flags.Nice != 0 {
+		config.Scheduling.Nice = flags.Nice
+	}
+	if flags.Realtime {
+		config.Scheduling.Realtime = true
+	}
+	if flags.CPUAffinity != "" {
+		if config.Scheduling.CPUAffinity, err = ParseCPUList(flags.CPUAffinity); err != nil {
+			return fmt.Errorf("-cpu-affinity: %w", err)
+		}
+	}
+	if flags.Cooldown != 0 {
+		config.Cooldown.Seconds = flags.Cooldown
+	}
+	if flags.ColdData != "" {
+		config.ColdData.Mode = flags.ColdData
+	}
+	if flags.SubtractOverhead {
+		config.SubtractOverhead = true
+	}
+	if flags.Interleave {
+		config.Interleave = true
+	}
+	if flags.Seed != 0 {
+		config.Seed = flags.Seed
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	if config.Interleave {
+		if flags.CPUProfileDir != "" || flags.MemProfileDir != "" || flags.TraceDir != "" {
+			return errors.New("-interleave: profiles and traces need benchmarks run one at a time")
+		}
+		if config.Seed == 0 {
+			config.Seed = NewSeed()
+		}
+		slog.Info(fmt.Sprintf("Seed: %d", config.Seed), "seed", config.Seed)
+	}
+	for name := range config.MemoryLimitsMB {
+		if !slices.ContainsFunc(Registered(), func(r Registration) bool { return r.Name == name }) {
+			return fmt.Errorf("%s: memoryLimitsMB.%s: no registered benchmark has this name", source, name)
+		}
+	}
+	if err := config.Scheduling.Apply(); err != nil {
+		slog.Warn(fmt.Sprintf("Couldn't apply scheduling settings, continuing without them: %v", err), "err", err)
+	}
+
+	opts := Options{
+		Warmup:     config.Warmup,
+		Iterations: config.Iterations,
+		Budget:     time.Duration(config.BudgetSeconds * float64(time.Second)),
+		Outliers:   config.Outliers,
+		Confidence: config.Confidence,
+
+		SteadyState:   config.SteadyState,
+		Cooldown:      config.Cooldown,
+		ColdData:      config.ColdData,
+		CPUProfileDir: flags.CPUProfileDir,
+		MemProfileDir: flags.MemProfileDir,
+		TraceDir:      flags.TraceDir,
+
+		SubtractOverhead: config.SubtractOverhead,
+	}
+	run := ResultsRun{Dataset: suite.Dataset, Benchmarks: []BenchmarkSummary{}}
+	var chart []ChartRow
+	failed := 0
+	HandleInterrupts()
+	passes := config.ColdData.Passes()
+	if flags.Empty {
+		empty := Registration{Name: EmptyName, Tags: []string{"harness"}, New: NewEmptyBenchmark}
+		selected = append([]Registration{empty}, selected...)
+	}
+	record := func(result Result, err error) error {
+		if err != nil {
+			if !errors.Is(err, ErrVerification) && !errors.Is(err, ErrPanicked) {
+				return err
+			}
+			slog.Warn(fmt.Sprintf("%v, skipping %s", err, result.Name), "benchmark", result.Name, "err", err)
+			failed++
+		}
+		run.Benchmarks = append(run.Benchmarks, result.Summarize())
+		chart = append(chart, ChartRow{Name: result.Name, Median: result.Median, TimedOut: result.TimedOut, Failed: result.Failure != nil})
+		return nil
+	}
+	if config.Interleave {
+		var benchmarks []Benchmark
+		var benchmarkOpts []Options
+		for _, r := range selected {
+			for _, cold := range passes {
+				opts.Cold = cold
+				benchmarks = append(benchmarks, r.New())
+				benchmarkOpts = append(benchmarkOpts, opts)
+			}
+		}
+		results, errs := RunInterleaved(benchmarks, benchmarkOpts, config.Seed)
+		for i, result := range results {
+			// Interrupted benchmarks are left out
+			if errors.Is(errs[i], ErrInterrupted) {
+				continue
+			}
+			if err := record(result, errs[i]); err != nil {
+				return err
+			}
+		}
+	} else {
+	suite:
+		for _, r := range selected {
+			opts.MemoryLimit = MemoryLimitBytes(config.MemoryLimitMB)
+			if mb := config.MemoryLimitsMB[r.Name]; mb > 0 {
+				opts.MemoryLimit = MemoryLimitBytes(mb)
+			}
+			for _, cold := range passes {
+				opts.Cold = cold
+				result, err := Run(r.New(), opts)
+				if errors.Is(err, ErrInterrupted) {
+					break suite
+				}
+				if err := record(result, err); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if config.Chart != "none" {
+		fmt.Println("\nMedians:")
+		WriteBarChart(os.Stdout, chart, config.Chart)
+	}
+
+	results := NewResults(suite.Name, config)
+	results.Partial = Interrupted()
+	if config.Interleave {
+		results.Execution = ExecutionInterleaved
+		results.Seed = config.Seed
+	}
+	results.Runs = append(results.Runs, run)
+	if flags.Results != "" {
+		if err := results.Write(flags.Results); err != nil {
+			return fmt.Errorf("writing results: %w", err)
+		}
+	}
+	if len(formats) > 0 {
+		// -results is already the JSON
+		formats = slices.DeleteFunc(formats, func(format string) bool { return format == "json" })
+		if err := results.WriteFormats(flags.Results, formats); err != nil {
+			return err
+		}
+	}
+	if flags.Benchstat != "" {
+		if err := results.WriteBenchstat(flags.Benchstat); err != nil {
+			return fmt.Errorf("writing benchstat results: %w", err)
+		}
+	}
+	if flags.CSV != "" {
+		if err := results.WriteCSV(flags.CSV); err != nil {
+			return fmt.Errorf("writing CSV results: %w", err)
+		}
+	}
+	if flags.PushURL != "" {
+		if err := results.Push(flags.PushURL, flags.PushHeaders); err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Pushed results to %s", flags.PushURL), "url", flags.PushURL)
+	}
+	if results.Partial {
+		return fmt.Errorf("%w after %d of %d benchmarks", ErrInterrupted, len(run.Benchmarks), len(selected)*len(passes))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d benchmarks failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+module jsconf/internal
+
+go 1.25.1
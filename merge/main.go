@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"jsconf/internal/bench"
+)
+
+func main() {
+	out := flag.String("o", "", "write the merged document to this file instead of stdout")
+	repetitions := flag.Bool("repetitions", false, "the files are repetitions of one suite run: pool them into one results document and print how each benchmark varied between and within the runs")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: merge [flags] [label=]results.json...\n       merge -repetitions [flags] results.json...\n\nCombines results files of one suite from any language, such as Go natively and as WASM and the JS harness, into one document keyed by benchmark name, for report. Files are labelled by their language unless a label is given, and merged files can be merged again.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	combine := run
+	if *repetitions {
+		combine = aggregate
+	}
+	if err := combine(flag.Args(), *out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out string) error {
+	given, paths := parseArgs(args)
+	labels, results, err := bench.ReadDocuments(paths, given)
+	if err != nil {
+		return err
+	}
+
+	merged, err := bench.Merge(labels, results)
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		return merged.Write(out)
+	}
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(data, '\n'))
+	return err
+}
+
+// aggregate pools the results files at paths, repetitions of one suite
+// run, into one document and prints a table of how each benchmark varied
+// between the repetitions and within them to stderr
+func aggregate(paths []string, out string) error {
+	reps := make([]bench.Results, len(paths))
+	for i, path := range paths {
+		var err error
+		if reps[i], err = bench.ReadResults(path); err != nil {
+			return err
+		}
+	}
+	aggregated, err := bench.AggregateRepetitions(reps)
+	if err != nil {
+		return err
+	}
+	writeVariation(os.Stderr, aggregated)
+	if out != "" {
+		return aggregated.Write(out)
+	}
+	data, err := json.MarshalIndent(aggregated, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(data, '\n'))
+	return err
+}
+
+// writeVariation writes each benchmark's median over every repetition
+// with its standard deviation between and within the repetitions, as a
+// percentage of the median
+func writeVariation(w io.Writer, r bench.Results) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, run := range r.Runs {
+		fmt.Fprintf(w, "\n%s, %d repetitions:\n", run.Title(r.Suite), r.Repetitions)
+		fmt.Fprintln(tw, "Benchmark\tMedian\tBetween runs\tWithin runs")
+		for _, b := range run.Benchmarks {
+			if b.Repetitions == nil || b.Median == 0 {
+				fmt.Fprintf(tw, "%s\t–\t–\t–\n", b.Name)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t±%.1f%%\t±%.1f%%\n", b.Name, bench.FormatMilliseconds(b.Median),
+				b.Repetitions.InterRunStdDev/b.Median*100, b.Repetitions.IntraRunStdDev/b.Median*100)
+		}
+		tw.Flush()
+	}
+}
+
+// parseArgs splits label=path arguments into their labels, empty where
+// none was given, and paths
+func parseArgs(args []string) (labels, paths []string) {
+	for _, arg := range args {
+		label, path, ok := strings.Cut(arg, "=")
+		if !ok {
+			label, path = "", arg
+		}
+		labels = append(labels, label)
+		paths = append(paths, path)
+	}
+	return labels, paths
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	labels, paths := parseArgs([]string{"go.json", "wasm=sort/go/wasi.json", "js.json"})
+	if !slices.Equal(labels, []string{"", "wasm", ""}) || !slices.Equal(paths, []string{"go.json", "sort/go/wasi.json", "js.json"}) {
+		t.Errorf("parseArgs = %q, %q", labels, paths)
+	}
+}
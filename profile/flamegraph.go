@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"slices"
+	"strings"
+)
+
+// frame is a function in the flame graph, with the total value of the
+// stacks passing through it
+type frame struct {
+	name     string
+	value    int64
+	children []*frame
+}
+
+// buildFrames nests folded stacks into a tree under a root frame, with
+// children in name order so the layout is stable between runs
+func buildFrames(stacks map[string]int64) *frame {
+	root := &frame{name: "all"}
+	for stack, value := range stacks {
+		if value <= 0 {
+			continue
+		}
+		root.value += value
+		node := root
+		for name := range strings.SplitSeq(stack, ";") {
+			i := slices.IndexFunc(node.children, func(f *frame) bool { return f.name == name })
+			if i < 0 {
+				i = len(node.children)
+				node.children = append(node.children, &frame{name: name})
+			}
+			node = node.children[i]
+			node.value += value
+		}
+	}
+	var sortFrames func(f *frame)
+	sortFrames = func(f *frame) {
+		slices.SortFunc(f.children, func(a, b *frame) int { return strings.Compare(a.name, b.name) })
+		for _, child := range f.children {
+			sortFrames(child)
+		}
+	}
+	sortFrames(root)
+	return root
+}
+
+// Flame graph layout, in pixels
+const (
+	frameHeight = 17
+	titleHeight = 40
+	padding     = 10
+	// charWidth approximates the width of a character at the 12px font
+	// size, to decide how much of a name fits in its frame
+	charWidth = 7
+	// minFrameWidth leaves out frames too narrow to see
+	minFrameWidth = 0.3
+)
+
+// writeFlameGraph draws root as an SVG flame graph width pixels wide, with
+// the root at the bottom and each frame as wide as its share of the total.
+// Hovering a frame shows its value formatted by format.
+func writeFlameGraph(w io.Writer, root *frame, title string, width int, format func(int64) string) error {
+	depth := maxDepth(root)
+	height := titleHeight + (depth+1)*frameHeight + padding
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Verdana, sans-serif" font-size="12">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+	fmt.Fprintf(b, `<text x="%d" y="26" text-anchor="middle" font-size="17">%s</text>`+"\n", width/2, html.EscapeString(title))
+
+	plotWidth := float64(width - 2*padding)
+	var draw func(f *frame, x float64, level int)
+	draw = func(f *frame, x float64, level int) {
+		frameWidth := float64(f.value) / float64(root.value) * plotWidth
+		if frameWidth < minFrameWidth {
+			return
+		}
+		y := height - padding - (level+1)*frameHeight
+		share := float64(f.value) / float64(root.value) * 100
+		fmt.Fprintf(b, `<g><title>%s (%s, %.2f%%)</title><rect x="%.1f" y="%d" width="%.1f" height="%d" rx="2" fill="%s"/>`,
+			html.EscapeString(f.name), format(f.value), share, x, y, frameWidth, frameHeight-1, frameColor(f.name))
+		if label := fitLabel(f.name, frameWidth); label != "" {
+			fmt.Fprintf(b, `<text x="%.1f" y="%d">%s</text>`, x+3, y+frameHeight-5, html.EscapeString(label))
+		}
+		fmt.Fprintln(b, "</g>")
+		for _, child := range f.children {
+			draw(child, x, level+1)
+			x += float64(child.value) / float64(root.value) * plotWidth
+		}
+	}
+	if root.value > 0 {
+		draw(root, padding, 0)
+	} else {
+		fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="middle">No samples</text>`+"\n", width/2, height-padding-5)
+	}
+	fmt.Fprintln(b, "</svg>")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func maxDepth(f *frame) int {
+	depth := 0
+	for _, child := range f.children {
+		depth = max(depth, maxDepth(child)+1)
+	}
+	return depth
+}
+
+// fitLabel shortens name to fit in a frame width pixels wide, or returns ""
+// when not even a few characters fit
+func fitLabel(name string, width float64) string {
+	fits := int((width - 6) / charWidth)
+	switch {
+	case fits < 3:
+		return ""
+	case len(name) <= fits:
+		return name
+	}
+	return name[:fits-2] + ".."
+}
+
+// frameColor picks a warm color for a function from its name, so the same
+// function has the same color in every graph
+func frameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	r := 205 + v%50
+	g := (v >> 8) % 230
+	b := (v >> 16) % 55
+	return fmt.Sprintf("rgb(%d,%d,%d)", r, g, b)
+}
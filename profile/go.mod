@@ -0,0 +1,3 @@
+module jsconf/profile
+
+go 1.25.1
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const usage = `Usage:
+  profile render [flags] profile.pprof...
+
+render draws a flame graph SVG of each pprof profile, such as those written
+with -cpuprofile-dir and -memprofile-dir, next to it or to -o. With -format
+folded it writes the profile's folded stacks instead, for other flame graph
+tools.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "render":
+		err = runRender(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// renderOptions are the flags of render
+type renderOptions struct {
+	output     string
+	format     string
+	sampleType string
+	base       string
+	title      string
+	width      int
+}
+
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	var opts renderOptions
+	flags.StringVar(&opts.output, "o", "", "write to this file instead of next to the profile, with a single profile")
+	flags.StringVar(&opts.format, "format", "svg", "output format: svg or folded")
+	flags.StringVar(&opts.sampleType, "sample", "", "sample type to draw, e.g. alloc_space or alloc_objects (default the profile's default)")
+	flags.StringVar(&opts.base, "base", "", "subtract this profile first, e.g. the allocs-base profile written before a benchmark")
+	flags.StringVar(&opts.title, "title", "", "title of the flame graph (default the profile's file name and sample type)")
+	flags.IntVar(&opts.width, "width", 1200, "width of the flame graph in pixels")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("render: expected one or more profiles")
+	}
+	if opts.output != "" && flags.NArg() > 1 {
+		return fmt.Errorf("render: -o can only be used with a single profile")
+	}
+	if !slices.Contains([]string{"svg", "folded"}, opts.format) {
+		return fmt.Errorf("render: -format: expected svg or folded, got %q", opts.format)
+	}
+	if opts.width < 100 {
+		return fmt.Errorf("render: -width: must be at least 100, got %d", opts.width)
+	}
+
+	for _, path := range flags.Args() {
+		output := opts.output
+		if output == "" {
+			output = strings.TrimSuffix(path, ".pprof") + "." + opts.format
+		}
+		if err := render(path, output, opts); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", output)
+	}
+	return nil
+}
+
+// render converts the profile at path into a flame graph or folded stacks
+// at output
+func render(path, output string, opts renderOptions) error {
+	p, err := readProfile(path)
+	if err != nil {
+		return err
+	}
+	index, err := p.sampleIndex(opts.sampleType)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	sampleType := p.sampleTypes[index]
+	stacks := p.folded(index)
+
+	if opts.base != "" {
+		base, err := readProfile(opts.base)
+		if err != nil {
+			return err
+		}
+		baseIndex, err := base.sampleIndex(sampleType.typ)
+		if err != nil {
+			return fmt.Errorf("%s: %w", opts.base, err)
+		}
+		for stack, value := range base.folded(baseIndex) {
+			stacks[stack] -= value
+		}
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if opts.format == "folded" {
+		err = writeFolded(file, stacks)
+	} else {
+		title := opts.title
+		if title == "" {
+			title = fmt.Sprintf("%s (%s)", filepath.Base(path), sampleType.typ)
+		}
+		err = writeFlameGraph(file, buildFrames(stacks), title, opts.width, valueFormatter(sampleType.unit))
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// writeFolded writes one "root;...;leaf value" line per stack, in stack
+// order, as read by flamegraph.pl and speedscope
+func writeFolded(w io.Writer, stacks map[string]int64) error {
+	keys := make([]string, 0, len(stacks))
+	for stack, value := range stacks {
+		if value > 0 {
+			keys = append(keys, stack)
+		}
+	}
+	slices.Sort(keys)
+	for _, stack := range keys {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, stacks[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valueFormatter formats sample values in unit for frame tooltips
+func valueFormatter(unit string) func(int64) string {
+	switch unit {
+	case "nanoseconds":
+		return func(v int64) string { return fmt.Sprintf("%.2fms", float64(v)/1e6) }
+	case "bytes":
+		return formatBytes
+	}
+	return func(v int64) string { return fmt.Sprintf("%d %s", v, unit) }
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "1.50KB"
+func formatBytes(bytes int64) string {
+	value := float64(bytes)
+	if value < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	for _, unit := range []string{"KB", "MB"} {
+		value /= 1024
+		if value < 1024 {
+			return fmt.Sprintf("%.2f%s", value, unit)
+		}
+	}
+	return fmt.Sprintf("%.2fGB", value/1024)
+}
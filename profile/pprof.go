@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// profile is the part of a pprof profile a flame graph needs: what each
+// sample value measures and the stack it was recorded at
+type profile struct {
+	sampleTypes []valueType
+	// defaultSampleType names the sample type pprof shows by default, or is
+	// empty for the last one
+	defaultSampleType string
+	samples           []sample
+}
+
+type valueType struct {
+	typ, unit string
+}
+
+// sample is one recorded stack, root first, with a value per sample type
+type sample struct {
+	stack  []string
+	values []int64
+}
+
+// readProfile reads a pprof profile, gzipped as runtime/pprof writes them or
+// not
+func readProfile(path string) (*profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	p, err := parseProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not a pprof profile: %w", path, err)
+	}
+	return p, nil
+}
+
+// sampleIndex returns the index of the sample type named name, or of the
+// default sample type when name is empty
+func (p *profile) sampleIndex(name string) (int, error) {
+	if len(p.sampleTypes) == 0 {
+		return 0, errors.New("profile has no sample types")
+	}
+	if name == "" {
+		name = p.defaultSampleType
+	}
+	if name == "" {
+		return len(p.sampleTypes) - 1, nil
+	}
+	names := make([]string, len(p.sampleTypes))
+	for i, t := range p.sampleTypes {
+		names[i] = t.typ
+	}
+	i := slices.Index(names, name)
+	if i < 0 {
+		return 0, fmt.Errorf("unknown sample type %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return i, nil
+}
+
+// folded sums the values at index of every sample by stack, keyed by the
+// stack's frames root first and joined by semicolons
+func (p *profile) folded(index int) map[string]int64 {
+	stacks := map[string]int64{}
+	for _, s := range p.samples {
+		if index < len(s.values) && s.values[index] != 0 {
+			stacks[strings.Join(s.stack, ";")] += s.values[index]
+		}
+	}
+	return stacks
+}
+
+// The messages and fields of profile.proto that are decoded
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+
+	functionID   = 1
+	functionName = 2
+)
+
+// parseProfile decodes the protobuf encoding of a profile. Names are string
+// table indexes until the whole message has been read, since the table can
+// come after the messages that refer to it.
+func parseProfile(data []byte) (*profile, error) {
+	type rawSample struct {
+		locations []uint64
+		values    []int64
+	}
+	var (
+		sampleTypes       [][2]int64
+		samples           []rawSample
+		locations         = map[uint64][]uint64{}
+		functions         = map[uint64]int64{}
+		strs              []string
+		defaultSampleType int64
+	)
+
+	err := decodeMessage(data, func(field int, d *decoder) error {
+		switch field {
+		case profileSampleType:
+			var t [2]int64
+			err := decodeMessage(d.bytes(), func(field int, d *decoder) error {
+				switch field {
+				case valueTypeType:
+					t[0] = int64(d.varint())
+				case valueTypeUnit:
+					t[1] = int64(d.varint())
+				default:
+					d.skip()
+				}
+				return nil
+			})
+			sampleTypes = append(sampleTypes, t)
+			return err
+		case profileSample:
+			var s rawSample
+			err := decodeMessage(d.bytes(), func(field int, d *decoder) error {
+				switch field {
+				case sampleLocationID:
+					d.repeated(func(v uint64) { s.locations = append(s.locations, v) })
+				case sampleValue:
+					d.repeated(func(v uint64) { s.values = append(s.values, int64(v)) })
+				default:
+					d.skip()
+				}
+				return nil
+			})
+			samples = append(samples, s)
+			return err
+		case profileLocation:
+			var id uint64
+			var lines []uint64
+			err := decodeMessage(d.bytes(), func(field int, d *decoder) error {
+				switch field {
+				case locationID:
+					id = d.varint()
+				case locationLine:
+					return decodeMessage(d.bytes(), func(field int, d *decoder) error {
+						if field == lineFunctionID {
+							lines = append(lines, d.varint())
+						} else {
+							d.skip()
+						}
+						return nil
+					})
+				default:
+					d.skip()
+				}
+				return nil
+			})
+			locations[id] = lines
+			return err
+		case profileFunction:
+			var id uint64
+			var name int64
+			err := decodeMessage(d.bytes(), func(field int, d *decoder) error {
+				switch field {
+				case functionID:
+					id = d.varint()
+				case functionName:
+					name = int64(d.varint())
+				default:
+					d.skip()
+				}
+				return nil
+			})
+			functions[id] = name
+			return err
+		case profileStringTable:
+			strs = append(strs, string(d.bytes()))
+		case profileDefaultSampleType:
+			defaultSampleType = int64(d.varint())
+		default:
+			d.skip()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	str := func(i int64) string {
+		if i < 0 || i >= int64(len(strs)) {
+			return ""
+		}
+		return strs[i]
+	}
+	p := &profile{defaultSampleType: str(defaultSampleType)}
+	for _, t := range sampleTypes {
+		p.sampleTypes = append(p.sampleTypes, valueType{typ: str(t[0]), unit: str(t[1])})
+	}
+	for _, s := range samples {
+		// Locations are leaf first, and so are the functions inlined at
+		// each, so the stack is built backwards
+		var stack []string
+		for _, location := range s.locations {
+			for _, function := range locations[location] {
+				stack = append(stack, str(functions[function]))
+			}
+		}
+		slices.Reverse(stack)
+		p.samples = append(p.samples, sample{stack: stack, values: s.values})
+	}
+	return p, nil
+}
+
+// decoder reads protobuf fields. Errors are sticky, so values can be read
+// without checking each one.
+type decoder struct {
+	data     []byte
+	wireType int
+	err      error
+}
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// decodeMessage calls field for each field of the message in data, which
+// must consume the field's value
+func decodeMessage(data []byte, field func(int, *decoder) error) error {
+	d := &decoder{data: data}
+	for len(d.data) > 0 && d.err == nil {
+		key := d.readVarint()
+		if d.err != nil {
+			break
+		}
+		d.wireType = int(key & 7)
+		if err := field(int(key>>3), d); err != nil {
+			return err
+		}
+	}
+	return d.err
+}
+
+func (d *decoder) readVarint() uint64 {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errors.New("truncated or malformed protobuf")
+	}
+	d.data = nil
+}
+
+// varint reads a varint field's value
+func (d *decoder) varint() uint64 {
+	if d.wireType != wireVarint {
+		d.fail()
+		return 0
+	}
+	return d.readVarint()
+}
+
+// bytes reads a length-delimited field's value
+func (d *decoder) bytes() []byte {
+	if d.wireType != wireBytes {
+		d.fail()
+		return nil
+	}
+	n := d.readVarint()
+	if n > uint64(len(d.data)) {
+		d.fail()
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+// repeated reads a repeated varint field, which may be packed
+func (d *decoder) repeated(add func(uint64)) {
+	if d.wireType == wireVarint {
+		add(d.varint())
+		return
+	}
+	packed := &decoder{data: d.bytes(), wireType: wireVarint}
+	for len(packed.data) > 0 && packed.err == nil {
+		add(packed.varint())
+	}
+	if packed.err != nil {
+		d.fail()
+	}
+}
+
+// skip discards a field's value
+func (d *decoder) skip() {
+	switch d.wireType {
+	case wireVarint:
+		d.readVarint()
+	case wireBytes:
+		d.bytes()
+	case wireFixed64:
+		d.advance(8)
+	case wireFixed32:
+		d.advance(4)
+	default:
+		d.fail()
+	}
+}
+
+func (d *decoder) advance(n int) {
+	if n > len(d.data) {
+		d.fail()
+		return
+	}
+	d.data = d.data[n:]
+}
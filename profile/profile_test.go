@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+var sink [][]byte
+
+//go:noinline
+func allocate() {
+	for range 100 {
+		sink = append(sink, make([]byte, 4096))
+	}
+}
+
+func TestReadProfile(t *testing.T) {
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+	allocate()
+	runtime.GC()
+
+	path := filepath.Join(t.TempDir(), "allocs.pprof")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	p, err := readProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := p.sampleIndex("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.sampleTypes[index]; got != (valueType{"alloc_space", "bytes"}) {
+		t.Errorf("default sample type = %v, want alloc_space in bytes", got)
+	}
+	var allocated int64
+	for stack, value := range p.folded(index) {
+		if strings.HasSuffix(stack, ".allocate") {
+			allocated += value
+		}
+	}
+	if allocated < 100*4096 {
+		t.Errorf("allocate allocated %d bytes, want at least %d", allocated, 100*4096)
+	}
+
+	if _, err := p.sampleIndex("cpu"); err == nil {
+		t.Error("sampleIndex accepted an unknown sample type")
+	}
+}
+
+func TestWriteFlameGraph(t *testing.T) {
+	root := buildFrames(map[string]int64{"main;sort;swap": 3, "main;load": 1, "main;sort": 2, "gc": 0})
+	if root.value != 6 || len(root.children) != 1 {
+		t.Fatalf("root = %d with %d children, want 6 with 1", root.value, len(root.children))
+	}
+	main := root.children[0]
+	if len(main.children) != 2 || main.children[0].name != "load" || main.children[1].value != 5 {
+		t.Errorf("main's children aren't load (1) and sort (5)")
+	}
+
+	var out strings.Builder
+	if err := writeFlameGraph(&out, root, "Sorting", 1000, valueFormatter("nanoseconds")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<title>sort (0.00ms, 83.33%)</title>", ">Sorting</text>", ">main</text>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("flame graph is missing %s", want)
+		}
+	}
+}
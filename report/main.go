@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"jsconf/internal/bench"
+)
+
+func main() {
+	baseline := flag.String("baseline", "", "implementation speedups are relative to, e.g. go, defaulting to the first results file")
+	out := flag.String("o", "", "write the report to this file instead of stdout")
+	format := flag.String("format", "markdown", "report format, markdown or html")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: report [flags] results.json...\n\nConverts results files, or merged files from the merge tool, into markdown tables or an HTML page of charts comparing them.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(flag.Args(), *format, *baseline, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(paths []string, format, baseline, out string) error {
+	if format != "markdown" && format != "html" {
+		return fmt.Errorf("unknown format %q, expected \"markdown\" or \"html\"", format)
+	}
+	if len(paths) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	labels, results, err := bench.ReadDocuments(paths, nil)
+	if err != nil {
+		return err
+	}
+
+	r, err := bench.NewReport(labels, results, baseline)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if format == "html" {
+		if err := r.WriteHTML(&buf); err != nil {
+			return err
+		}
+	} else {
+		r.WriteMarkdown(&buf)
+	}
+
+	if out == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(out, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+
+	"jsconf/internal/bench"
+)
+
+// intBenchmark is one algorithm in the []int part of the suite
+type intBenchmark struct {
+	key    string
+	name   string
+	sortFn func([]int)
+}
+
+// AlgorithmConfig overrides the suite settings for a single algorithm
+type AlgorithmConfig struct {
+	// Iterations replaces the global iteration count
+	Iterations int `json:"iterations"`
+	// Warmup replaces the global warmup count, including with zero
+	Warmup *int `json:"warmup"`
+	// MaxSize is the largest dataset the algorithm is run against
+	MaxSize int `json:"maxSize"`
+	// MemoryLimitMB replaces the global memory limit
+	MemoryLimitMB float64 `json:"memoryLimitMB"`
+}
+
+// algorithmKeys names every algorithm in the suite. Variants of an
+// algorithm for other element types share its key.
+var algorithmKeys = []string{
+	"bubble",
+	"radix",
+	"radix-reuse",
+	"shift-radix",
+	"msd-radix",
+	"counting",
+	"quick",
+	"branchless-quick",
+	"pdq",
+	"generic-quick",
+	"mono-quick",
+	"parallel-quick",
+	"merge",
+	"merge-alloc",
+	"merge-reuse",
+	"heap",
+	"timsort",
+	"bucket",
+	"external",
+	"shell",
+	"insertion",
+	"selection",
+	"multikey",
+	"topk-heap",
+	"topk-select",
+	"is-sorted",
+	"builtin",
+	"sort-ints",
+	"sort-slice",
+	"slices-sortfunc",
+}
+
+// adaptiveKeys are the algorithms that exploit existing order in their
+// input, running in close to linear time on sorted data
+var adaptiveKeys = []string{"pdq", "timsort", "insertion", "builtin", "sort-ints", "sort-slice", "slices-sortfunc"}
+
+// quadraticKeys are the algorithms limited to quadraticMaxSize unless they
+// set their own maxSize
+var quadraticKeys = []string{"bubble", "insertion", "selection"}
+
+// parallelKeys are the algorithms that sort on several goroutines at once
+var parallelKeys = []string{"parallel-quick"}
+
+// stdlibKeys are the standard library's sorts, timed as baselines
+var stdlibKeys = []string{"builtin", "sort-ints", "sort-slice", "slices-sortfunc"}
+
+// nativeOnlyKeys are the algorithms that spill to temporary files, which the
+// WASM builds only have when the host provides a temp dir, as Node does under
+// js/wasm. They aren't tagged "wasm", and are skipped when tempDirUsable
+// finds no temp dir.
+var nativeOnlyKeys = []string{"external"}
+
+// tempDirUsable reports whether a temp dir can be created, which is only in
+// doubt in the WASM builds: a browser has no filesystem, and wasip1 only has
+// the directories its host preopens
+var tempDirUsable = sync.OnceValue(func() bool {
+	if runtime.GOOS != "js" && runtime.GOOS != "wasip1" {
+		return true
+	}
+	dir, err := os.MkdirTemp("", "sort-")
+	if err != nil {
+		return false
+	}
+	os.RemoveAll(dir)
+	return true
+})
+
+// algorithmTagNames are the tags algorithmTags can return, in the order
+// -tags and -skip-tags document them
+var algorithmTagNames = []string{"sort", "adaptive", "quadratic", "parallel", "stdlib", "wasm"}
+
+// algorithmTags returns the tags of the algorithm key, matched by the tags
+// and skipTags filters: every algorithm is tagged "sort", those that run
+// under js/wasm and wasip1 "wasm", and the others as listed above
+func algorithmTags(key string) []string {
+	tags := []string{"sort"}
+	for _, group := range []struct {
+		tag  string
+		keys []string
+	}{
+		{"adaptive", adaptiveKeys},
+		{"quadratic", quadraticKeys},
+		{"parallel", parallelKeys},
+		{"stdlib", stdlibKeys},
+	} {
+		if slices.Contains(group.keys, key) {
+			tags = append(tags, group.tag)
+		}
+	}
+	if !slices.Contains(nativeOnlyKeys, key) {
+		tags = append(tags, "wasm")
+	}
+	return tags
+}
+
+// algorithmSelected reports whether key passes the algos and exclude
+// filters and the tags and skipTags filters
+func algorithmSelected(config Config, key string) bool {
+	if len(config.Algos) > 0 && !slices.Contains(config.Algos, key) {
+		return false
+	}
+	if slices.Contains(config.Exclude, key) {
+		return false
+	}
+	return bench.MatchTags(algorithmTags(key), config.Tags, config.SkipTags)
+}
+
+// algorithmOptions returns the harness options to run the algorithm with
+// against n elements, with its number of warmup and measured iterations and
+// whether it's traced, or false if it should be skipped
+func algorithmOptions(config Config, key, name string, n int) (opts bench.Options, ok bool) {
+	if !algorithmSelected(config, key) {
+		return opts, false
+	}
+
+	override := config.Algorithms[key]
+
+	maxSize := override.MaxSize
+	if maxSize == 0 && slices.Contains(quadraticKeys, key) {
+		maxSize = config.QuadraticMaxSize
+	}
+	if maxSize > 0 && n > maxSize {
+		slog.Info(fmt.Sprintf("Skipping %s: %d elements exceeds maxSize of %d", name, n, maxSize),
+			"benchmark", name, "size", n, "maxSize", maxSize)
+		return opts, false
+	}
+	if slices.Contains(nativeOnlyKeys, key) && !tempDirUsable() {
+		slog.Info(fmt.Sprintf("Skipping %s: no usable temp dir", name), "benchmark", name)
+		return opts, false
+	}
+
+	opts = harnessOptions
+	opts.Warmup = config.Warmup
+	if override.Warmup != nil {
+		opts.Warmup = *override.Warmup
+	}
+	opts.Iterations = config.Iterations
+	if override.Iterations > 0 {
+		opts.Iterations = override.Iterations
+	}
+	opts.MemoryLimit = bench.MemoryLimitBytes(config.MemoryLimitMB)
+	if override.MemoryLimitMB > 0 {
+		opts.MemoryLimit = bench.MemoryLimitBytes(override.MemoryLimitMB)
+	}
+	if len(config.Trace) > 0 && !slices.Contains(config.Trace, key) {
+		opts.TraceDir = ""
+	}
+	opts.ColdData = config.ColdData
+	opts.ShuffleSeed = derivedSeed(config.Seed, "cold data "+name)
+	return opts, true
+}
+
+// runAlgorithm is runBenchmark with the per-algorithm settings for key
+// applied, once per cold data pass
+func runAlgorithm[T cmp.Ordered](config Config, key, name string, data []T, expected []T, sortFn func([]T)) {
+	if opts, ok := algorithmOptions(config, key, name, len(data)); ok {
+		for _, opts := range coldDataPasses(opts) {
+			if planning {
+				plan = append(plan, plannedBenchmark{key: key, name: plannedName(name, opts), size: len(data), opts: opts})
+				continue
+			}
+			runBenchmark(name, data, expected, opts, sortFn)
+			markAdaptive(key)
+			markStable(key)
+		}
+	}
+}
+
+// runAlgorithmWithCheck is runBenchmarkWithCheck with the per-algorithm
+// settings for key applied, once per cold data pass
+func runAlgorithmWithCheck[T any](config Config, key, name string, data []T, sortFn func([]T), check func([]T)) {
+	if opts, ok := algorithmOptions(config, key, name, len(data)); ok {
+		for _, opts := range coldDataPasses(opts) {
+			if planning {
+				plan = append(plan, plannedBenchmark{key: key, name: plannedName(name, opts), size: len(data), opts: opts})
+				continue
+			}
+			runBenchmarkWithCheck(name, data, opts, sortFn, check)
+			markAdaptive(key)
+			markStable(key)
+		}
+	}
+}
+
+// plannedName is the name the harness gives benchmark name run with opts
+func plannedName(name string, opts bench.Options) string {
+	if opts.Cold {
+		return name + bench.ColdSuffix
+	}
+	return name
+}
+
+// coldDataPasses returns opts once per pass of its ColdData mode, with Cold
+// set for the cold pass
+func coldDataPasses(opts bench.Options) []bench.Options {
+	var passes []bench.Options
+	for _, cold := range opts.ColdData.Passes() {
+		opts.Cold = cold
+		passes = append(passes, opts)
+	}
+	return passes
+}
+
+// markAdaptive flags the result just added by an adaptive algorithm
+func markAdaptive(key string) {
+	if slices.Contains(adaptiveKeys, key) {
+		suiteResults[len(suiteResults)-1].adaptive = true
+	}
+}
+
+// markStable records on the result just added whether the stability check
+// found key stable, when the check ran and covers key
+func markStable(key string) {
+	if stable, ok := stability[key]; ok {
+		suiteResults[len(suiteResults)-1].stable = &stable
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+// Allocation strategy variants of merge sort and radix sort. mergeSort
+// allocates one buffer per call and radixSort one per digit pass; these add
+// the extremes either side, so the allocator's share of the time can be read
+// off the difference rather than conflated with the algorithm.
+
+// scratchBuffer is an int buffer kept between sorts and grown as needed, so
+// a sort run repeatedly only allocates on its first call
+type scratchBuffer struct {
+	buf []int
+}
+
+// get returns a buffer of length n, reusing the previous one if it's large
+// enough
+func (s *scratchBuffer) get(n int) []int {
+	if cap(s.buf) < n {
+		s.buf = make([]int, n)
+	}
+	return s.buf[:n]
+}
+
+// mergeSortAllocating is the textbook merge sort that allocates a fresh
+// buffer for every merge
+func mergeSortAllocating(data []int) {
+	if len(data) < 2 {
+		return
+	}
+	mid := len(data) / 2
+	mergeSortAllocating(data[:mid])
+	mergeSortAllocating(data[mid:])
+
+	aux := make([]int, len(data))
+	merge(data, aux, 0, mid, len(data))
+}
+
+// mergeSortReusing is mergeSort with its buffer taken from scratch
+func mergeSortReusing(data []int, scratch *scratchBuffer) {
+	mergeSortRange(data, scratch.get(len(data)), 0, len(data))
+}
+
+// radixSortReusing is radixSort with every digit pass sharing one output
+// buffer taken from scratch
+func radixSortReusing(data []int, scratch *scratchBuffer) {
+	if len(data) == 0 {
+		return
+	}
+
+	min, max := data[0], data[0]
+	for _, v := range data {
+		if v > max {
+			max = v
+		}
+		if v < min {
+			min = v
+		}
+	}
+
+	output := scratch.get(len(data))
+	if min < 0 {
+		for exp := 1; max/exp > 0 || min/exp < 0; exp *= 10 {
+			countingSortBySignedDigitInto(data, output, exp)
+		}
+		return
+	}
+	for exp := 1; max/exp > 0; exp *= 10 {
+		countingSortByDigitInto(data, output, exp)
+	}
+}
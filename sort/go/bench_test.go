@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// These wrap the suite's sorts in testing.B so the usual go test flags work,
+// e.g. go test -bench BubbleSort -benchtime 1x -cpuprofile cpu.out. Each
+// sorts a fresh copy of ../data.json, as the suite does.
+
+func benchmarkSort(b *testing.B, sort func([]int)) {
+	data, _, err := loadDataset(DatasetConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	input := make([]int, len(data))
+	b.ResetTimer()
+	for range b.N {
+		b.StopTimer()
+		copy(input, data)
+		b.StartTimer()
+		sort(input)
+	}
+	b.StopTimer()
+	if !slices.IsSorted(input) {
+		b.Fatal("output is not sorted")
+	}
+}
+
+func BenchmarkBubbleSort(b *testing.B) {
+	benchmarkSort(b, bubbleSort)
+}
+
+func BenchmarkQuickSort(b *testing.B) {
+	benchmarkSort(b, quickSort)
+}
+
+func BenchmarkBuiltinSort(b *testing.B) {
+	benchmarkSort(b, slices.Sort[[]int])
+}
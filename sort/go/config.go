@@ -0,0 +1,412 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+
+	"jsconf/internal/bench"
+)
+
+// Config is the schema of config.json. Unset keys take the defaults filled in
+// by parseConfig, and validate reports bad values by key.
+type Config struct {
+	// Iterations is the number of timed iterations of each benchmark,
+	// defaulting to 10
+	Iterations int `json:"iterations"`
+	// BudgetSeconds, when set, runs each benchmark's timed iterations for
+	// this long instead of a fixed count, overriding iterations including
+	// per-algorithm ones, so fast algorithms get more samples and the suite
+	// takes a predictable time
+	BudgetSeconds float64 `json:"budgetSeconds"`
+	// MinSampleSeconds, when set, calibrates how many runs each timed
+	// sample batches so it takes at least this long, like testing.B, so
+	// timer resolution doesn't dominate tiny workloads. Samples are recorded
+	// per run.
+	MinSampleSeconds float64 `json:"minSampleSeconds"`
+	// Warmup is the number of untimed iterations run and verified before
+	// the measured ones, so first-run effects don't skew medians. With
+	// steadyState enabled it's the minimum.
+	Warmup int `json:"warmup"`
+	// Dataset selects a generator for the integer dataset, falling back to
+	// ../data.json when unset
+	Dataset DatasetConfig `json:"dataset"`
+	// Datasets runs the whole suite once per dataset instead of on Dataset,
+	// naming each in the output and results
+	Datasets []DatasetConfig `json:"datasets"`
+	// Seed is where every random choice of the run is drawn from: datasets
+	// generated without their own seed, the string and record datasets, the
+	// stability check's keys and the shuffled order. When unset one is
+	// picked from the clock. Either way it's recorded in results, and
+	// passing it back with -seed reproduces the run.
+	Seed int64 `json:"seed"`
+	// Shuffle runs the benchmarks in an order drawn from Seed instead of
+	// the fixed one, so whatever a benchmark leaves behind, such as a grown
+	// heap, doesn't always weigh on the same next one, overridden by
+	// -shuffle
+	Shuffle bool `json:"shuffle"`
+	// Interleave goes further, running each dataset's benchmarks together
+	// one iteration of each in turn, in an order drawn from Seed, so
+	// ordering effects between them average out instead of only moving,
+	// overridden by -interleave. It can't be combined with isolate,
+	// parallel, profiles, traces or memory limits, which need benchmarks
+	// run one at a time.
+	Interleave bool `json:"interleave"`
+	// QuadraticMaxSize is the largest dataset the O(n^2) bubble, insertion
+	// and selection sorts are run against when they don't set their own
+	// maxSize
+	QuadraticMaxSize int `json:"quadraticMaxSize"`
+	// Algorithms overrides iterations and sets a maxSize per algorithm,
+	// keyed by the names in algorithmKeys
+	Algorithms map[string]AlgorithmConfig `json:"algorithms"`
+	// Algos limits the run to these algorithms, overridden by -algos
+	Algos []string `json:"algos"`
+	// Exclude skips these algorithms, overridden by -exclude
+	Exclude []string `json:"exclude"`
+	// Tags limits the run to algorithms with all of these tags, such as
+	// "wasm" for those that also run in the WASM builds, overridden by
+	// -tags. The tags are listed in algorithmTagNames.
+	Tags []string `json:"tags"`
+	// SkipTags skips algorithms with any of these tags, such as
+	// "quadratic", overridden by -skip-tags
+	SkipTags []string `json:"skipTags"`
+	// ExternalMemory is the memory budget in bytes of the external merge
+	// sort, which sets how large each spilled run is
+	ExternalMemory int `json:"externalMemory"`
+	// TopK is how many of the largest elements the top-K benchmarks find
+	TopK int `json:"topK"`
+	// ShellGaps selects the shell sort gap sequence: "ciura" (default) or
+	// "knuth"
+	ShellGaps string `json:"shellGaps"`
+	// Workers bounds the goroutines used by parallel quicksort, defaulting
+	// to GOMAXPROCS
+	Workers int `json:"workers"`
+	// Buckets is the number of buckets used by bucket sort
+	Buckets int `json:"buckets"`
+	// RadixBase is the base of the shift/mask radix sort and must be a
+	// power of two, e.g. 16, 256 (default) or 65536
+	RadixBase int `json:"radixBase"`
+	// FloatDataFile is an optional JSON array of numbers for the float64
+	// benchmarks, with null standing in for NaN. When unset the integer
+	// dataset is converted, which matches what JS sorts.
+	FloatDataFile string `json:"floatDataFile"`
+	// NaNPolicy is how float64 benchmarks treat NaNs: "first" (default)
+	// sorts them before all other values like slices.Sort, "exclude" drops
+	// them from the dataset before benchmarking
+	NaNPolicy string `json:"nanPolicy"`
+	// StringCount is the size of the generated string dataset, defaulting
+	// to the size of the integer dataset
+	StringCount int `json:"stringCount"`
+	// RecordCount is the size of the generated record dataset, defaulting
+	// to the size of the integer dataset
+	RecordCount int `json:"recordCount"`
+	// Sizes runs the whole suite once per dataset size and prints a table
+	// of medians per size. Generated datasets are generated at each size,
+	// file datasets use their first N elements.
+	Sizes []float64 `json:"sizes"`
+	// Verify is how often sorted output is checked: "every" (default)
+	// iteration, "once" on the first iteration, "checksum" compares a hash
+	// of every iteration's output against the expected hash, or "off".
+	// Record benchmarks have no single expected order, so checksum mode
+	// checks they are sorted instead.
+	Verify string `json:"verify"`
+	// TimeoutSeconds stops an algorithm once a single iteration runs longer
+	// than this and marks it timed out. Quadratic sorts are interrupted
+	// mid-iteration, others when the iteration finishes.
+	TimeoutSeconds float64 `json:"timeoutSeconds"`
+	// Chart is how the bar chart of medians printed after each run is
+	// drawn: "unicode" (default), "ascii" for terminals without Unicode, or
+	// "none" to leave it out
+	Chart string `json:"chart"`
+	// Quiet turns off progress reports and per-iteration lines, overridden
+	// by -q or its alias -quiet
+	Quiet bool `json:"quiet"`
+	// ResultsFile is where a JSON copy of every run's samples and statistics
+	// is written, overridden by -results
+	ResultsFile string `json:"resultsFile"`
+	// Out also writes the results in these formats from bench.OutputFormats,
+	// next to ResultsFile with their own extensions, e.g. ["csv", "md",
+	// "html"] for slide assets, overridden by -out
+	Out []string `json:"out"`
+	// CSVFile is where one row per iteration of every benchmark is written
+	// as CSV, overridden by -out-csv
+	CSVFile string `json:"csvFile"`
+	// BenchstatFile is where every sample is written in the go test -bench
+	// format read by benchstat, overridden by -out-benchstat
+	BenchstatFile string `json:"benchstatFile"`
+	// PushURL is where the results document is POSTed after the run, for
+	// collecting results from several machines, overridden by -push-url
+	PushURL string `json:"pushURL"`
+	// PushHeaders are sent with the upload, e.g. an Authorization header.
+	// $VAR in values is expanded from the environment so tokens can stay
+	// out of config.json. -push-header adds to these.
+	PushHeaders map[string]string `json:"pushHeaders"`
+	// SortedInput reruns the int benchmarks on already sorted input and
+	// times slices.IsSorted, to show which algorithms exploit existing order
+	SortedInput bool `json:"sortedInput"`
+	// PerfCounters records hardware cache misses and branch mispredictions
+	// per iteration on Linux, where perf_event_open is permitted
+	PerfCounters bool `json:"perfCounters"`
+	// CPUProfileDir is where a pprof CPU profile of each benchmark's timed
+	// iterations is written, in a subdirectory per dataset and size when
+	// the config sweeps several, overridden by -cpuprofile-dir
+	CPUProfileDir string `json:"cpuProfileDir"`
+	// MemProfileDir is where heap profiles of the allocations up to the
+	// start and end of each benchmark's timed iterations are written, laid
+	// out like CPUProfileDir, overridden by -memprofile-dir
+	MemProfileDir string `json:"memProfileDir"`
+	// TraceDir is where runtime/trace execution traces of each benchmark's
+	// timed iterations are written, laid out like CPUProfileDir, overridden
+	// by -trace-dir
+	TraceDir string `json:"traceDir"`
+	// Trace limits tracing to these algorithms, such as parallel-quick,
+	// since traces grow quickly, overridden by -trace
+	Trace []string `json:"trace"`
+	// Isolate runs each algorithm in its own child process, overridden by
+	// -isolate. Only native builds can start processes.
+	Isolate bool `json:"isolate"`
+	// Parallel runs up to this many algorithms at once, each in its own
+	// child process as with Isolate, overridden by -parallel. It's meant
+	// for quick smoke runs: the children compete for cores and memory
+	// bandwidth, so timings are only comparable with other parallel runs.
+	// Zero or one runs the algorithms one after another.
+	Parallel int `json:"parallel"`
+	// CheckStability checks which algorithms keep equal keys in input order
+	// before the benchmarks run, printing the verdicts and recording them
+	// as each algorithm's stable field in the results
+	CheckStability bool `json:"checkStability"`
+	// Outliers selects how outlying samples are detected and whether they
+	// are reported, trimmed or winsorized before computing statistics
+	Outliers bench.OutlierConfig `json:"outliers"`
+	// Confidence sets the level of the bootstrap confidence interval
+	// reported on each median, and optionally a targetWidth to keep
+	// iterating until
+	Confidence bench.ConfidenceConfig `json:"confidence"`
+	// SteadyState, when enabled, keeps warming up each benchmark until the
+	// variation of its recent iteration times drops below a threshold,
+	// overridden by -steady-state
+	SteadyState bench.SteadyStateConfig `json:"steadyState"`
+	// Scheduling sets the niceness, real-time policy and CPU affinity of
+	// the process and the isolated children it starts, to reduce noise from
+	// other work on shared machines, overridden by -nice, -realtime and
+	// -cpu-affinity
+	Scheduling bench.SchedulingConfig `json:"scheduling"`
+	// Cooldown sleeps before each benchmark, overridden by -cooldown, and
+	// pauses benchmarks whose iteration times drift upward, so thermal
+	// throttling on laptops doesn't slow the later part of long runs
+	Cooldown bench.CooldownConfig `json:"cooldown"`
+	// ColdData evicts the CPU caches before each iteration, and can shuffle
+	// the input too, so the suite can report cold-cache timings instead of,
+	// or next to, warm ones, overridden by -cold-data. The sorted-input
+	// reruns are never shuffled.
+	ColdData bench.ColdDataConfig `json:"coldData"`
+	// Empty also times the harness's empty benchmark before the
+	// algorithms, overridden by -empty, and SubtractOverhead takes the
+	// overhead it measures off every sample, overridden by
+	// -subtract-overhead, for algorithms fast enough that it shows
+	Empty            bool `json:"empty"`
+	SubtractOverhead bool `json:"subtractOverhead"`
+	// MemoryLimitMB is the soft memory limit each benchmark runs under, so
+	// a memory-hungry one makes the GC work harder rather than running the
+	// machine out of memory. Results record the collections it forced.
+	MemoryLimitMB float64 `json:"memoryLimitMB"`
+}
+
+// defaultIterations is used when config.json doesn't set iterations
+const defaultIterations = 10
+
+// profiles are the bundled configurations selected with -profile
+//
+//go:embed profiles/*.json
+var profiles embed.FS
+
+// parseConfig decodes config.json, rejecting unknown keys, applies the
+// BENCH_ environment overrides and fills in defaults for unset keys
+func parseConfig(data []byte) (Config, error) {
+	var config Config
+	if err := bench.DecodeConfig(data, &config); err != nil {
+		return config, err
+	}
+	if err := bench.ApplyEnv(&config); err != nil {
+		return config, err
+	}
+
+	if config.Iterations == 0 {
+		config.Iterations = defaultIterations
+	}
+	if config.Seed == 0 {
+		config.Seed = bench.NewSeed()
+	}
+	if config.QuadraticMaxSize == 0 {
+		config.QuadraticMaxSize = defaultQuadraticMaxSize
+	}
+	if config.ExternalMemory == 0 {
+		config.ExternalMemory = defaultExternalMemory
+	}
+	if config.TopK == 0 {
+		config.TopK = defaultTopK
+	}
+	if config.ShellGaps == "" {
+		config.ShellGaps = "ciura"
+	}
+	if config.Workers == 0 {
+		config.Workers = runtime.GOMAXPROCS(0)
+	}
+	if config.Buckets == 0 {
+		config.Buckets = defaultBuckets
+	}
+	if config.RadixBase == 0 {
+		config.RadixBase = defaultRadixBase
+	}
+	if config.NaNPolicy == "" {
+		config.NaNPolicy = "first"
+	}
+	if config.Verify == "" {
+		config.Verify = "every"
+	}
+	if config.Chart == "" {
+		config.Chart = "unicode"
+	}
+	config.Outliers = config.Outliers.WithDefaults()
+	config.Confidence = config.Confidence.WithDefaults()
+	config.SteadyState = config.SteadyState.WithDefaults()
+	config.Cooldown = config.Cooldown.WithDefaults()
+	config.ColdData = config.ColdData.WithDefaults()
+	return config, nil
+}
+
+// validate checks every key, after command line overrides, and returns one
+// error per bad value naming its key
+func (config Config) validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(config.Iterations >= 1, "iterations", "must be at least 1, got %d", config.Iterations)
+	check(config.BudgetSeconds >= 0, "budgetSeconds", "must not be negative, got %v", config.BudgetSeconds)
+	check(config.MinSampleSeconds >= 0, "minSampleSeconds", "must not be negative, got %v", config.MinSampleSeconds)
+	check(config.Warmup >= 0, "warmup", "must not be negative, got %d", config.Warmup)
+	check(config.QuadraticMaxSize >= 0, "quadraticMaxSize", "must not be negative, got %d", config.QuadraticMaxSize)
+	check(config.ExternalMemory > 0, "externalMemory", "must be positive, got %d", config.ExternalMemory)
+	check(config.TopK > 0, "topK", "must be positive, got %d", config.TopK)
+	check(config.ShellGaps == "ciura" || config.ShellGaps == "knuth",
+		"shellGaps", "expected \"ciura\" or \"knuth\", got %q", config.ShellGaps)
+	check(config.Workers > 0, "workers", "must be positive, got %d", config.Workers)
+	check(config.Buckets > 0, "buckets", "must be positive, got %d", config.Buckets)
+	check(config.RadixBase >= 2 && config.RadixBase <= 1<<16 && config.RadixBase&(config.RadixBase-1) == 0,
+		"radixBase", "must be a power of two between 2 and 65536, got %d", config.RadixBase)
+	check(config.NaNPolicy == "first" || config.NaNPolicy == "exclude",
+		"nanPolicy", "expected \"first\" or \"exclude\", got %q", config.NaNPolicy)
+	check(config.StringCount >= 0, "stringCount", "must not be negative, got %d", config.StringCount)
+	check(config.RecordCount >= 0, "recordCount", "must not be negative, got %d", config.RecordCount)
+	check(slices.Contains(verifyPolicies, config.Verify),
+		"verify", "expected one of %s, got %q", strings.Join(verifyPolicies, ", "), config.Verify)
+	check(slices.Contains(bench.ChartStyles, config.Chart),
+		"chart", "expected one of %s, got %q", strings.Join(bench.ChartStyles, ", "), config.Chart)
+	check(config.Parallel >= 0, "parallel", "must not be negative, got %d", config.Parallel)
+	check(config.TimeoutSeconds >= 0, "timeoutSeconds", "must not be negative, got %v", config.TimeoutSeconds)
+	if err := config.Outliers.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("outliers.%w", err))
+	}
+	if err := config.Confidence.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("confidence.%w", err))
+	}
+	if err := config.SteadyState.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("steadyState.%w", err))
+	}
+	if err := config.Scheduling.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("scheduling.%w", err))
+	}
+	if err := config.Cooldown.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("cooldown.%w", err))
+	}
+	if err := config.ColdData.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("coldData.%w", err))
+	}
+
+	check(config.MemoryLimitMB >= 0, "memoryLimitMB", "must not be negative, got %v", config.MemoryLimitMB)
+	if config.Interleave {
+		check(!config.Isolate && config.Parallel <= 1, "interleave", "can't be combined with isolate or parallel")
+		check(config.CPUProfileDir == "" && config.MemProfileDir == "" && config.TraceDir == "",
+			"interleave", "can't be combined with profiles or traces")
+		limited := config.MemoryLimitMB > 0
+		for _, override := range config.Algorithms {
+			limited = limited || override.MemoryLimitMB > 0
+		}
+		check(!limited, "interleave", "can't be combined with memory limits")
+	}
+
+	for i, size := range config.Sizes {
+		check(size >= 1 && size == float64(int(size)), fmt.Sprintf("sizes[%d]", i), "must be a positive integer, got %v", size)
+	}
+
+	for key, override := range config.Algorithms {
+		prefix := "algorithms." + key
+		check(slices.Contains(algorithmKeys, key), prefix, "unknown algorithm, expected one of %s", strings.Join(algorithmKeys, ", "))
+		check(override.Iterations >= 0, prefix+".iterations", "must not be negative, got %d", override.Iterations)
+		check(override.Warmup == nil || *override.Warmup >= 0, prefix+".warmup", "must not be negative")
+		check(override.MaxSize >= 0, prefix+".maxSize", "must not be negative, got %d", override.MaxSize)
+		check(override.MemoryLimitMB >= 0, prefix+".memoryLimitMB", "must not be negative, got %v", override.MemoryLimitMB)
+	}
+	for _, list := range []struct {
+		key  string
+		keys []string
+	}{{"algos", config.Algos}, {"exclude", config.Exclude}, {"trace", config.Trace}} {
+		for i, key := range list.keys {
+			check(slices.Contains(algorithmKeys, key), fmt.Sprintf("%s[%d]", list.key, i),
+				"unknown algorithm %q, expected one of %s", key, strings.Join(algorithmKeys, ", "))
+		}
+	}
+	if len(config.Out) > 0 {
+		if err := bench.CheckOutputFormats(config.Out); err != nil {
+			errs = append(errs, fmt.Errorf("out: %w", err))
+		}
+		check(config.ResultsFile != "", "out", "needs resultsFile or -results to name the files")
+	}
+	for _, list := range []struct {
+		key  string
+		tags []string
+	}{{"tags", config.Tags}, {"skipTags", config.SkipTags}} {
+		for i, tag := range list.tags {
+			check(slices.Contains(algorithmTagNames, tag), fmt.Sprintf("%s[%d]", list.key, i),
+				"unknown tag %q, expected one of %s", tag, strings.Join(algorithmTagNames, ", "))
+		}
+	}
+
+	if len(config.Datasets) == 0 {
+		errs = append(errs, config.Dataset.validate("dataset")...)
+	}
+	for i, dataset := range config.Datasets {
+		errs = append(errs, dataset.validate(fmt.Sprintf("datasets[%d]", i))...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks a dataset's generator and bounds, naming keys under prefix
+func (cfg DatasetConfig) validate(prefix string) []error {
+	var errs []error
+	if cfg.Generator != "" && cfg.Generator != "file" {
+		if _, ok := generators[cfg.Generator]; !ok {
+			errs = append(errs, fmt.Errorf("%s.generator: unknown generator %q, expected \"file\" or one of %s",
+				prefix, cfg.Generator, strings.Join(slices.Sorted(maps.Keys(generators)), ", ")))
+		}
+		if cfg.Path != "" {
+			errs = append(errs, fmt.Errorf("%s.path: only used by the \"file\" generator", prefix))
+		}
+	}
+	if cfg.Size < 0 {
+		errs = append(errs, fmt.Errorf("%s.size: must not be negative, got %d", prefix, cfg.Size))
+	}
+	if (cfg.Min != 0 || cfg.Max != 0) && cfg.Max <= cfg.Min {
+		errs = append(errs, fmt.Errorf("%s.max: must be greater than min (%d), got %d", prefix, cfg.Min, cfg.Max))
+	}
+	return errs
+}
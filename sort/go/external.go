@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// defaultExternalMemory is used when config.json doesn't set externalMemory
+const defaultExternalMemory = 1 << 20
+
+// minMergeBuffer is the smallest read buffer given to each run while
+// merging, however many runs share the memory budget
+const minMergeBuffer = 4096
+
+// externalSort sorts data as if it didn't fit in memory: it is cut into runs
+// of memory bytes that are sorted and spilled to temp files, then k-way
+// merged back into data. Element values are stored as 8-byte little-endian
+// integers. I/O errors panic, like a failed check.
+func externalSort(data []int, memory int) {
+	dir, err := os.MkdirTemp("", "external-sort-")
+	if err != nil {
+		panic(fmt.Sprintf("External sort: %v", err))
+	}
+	defer os.RemoveAll(dir)
+
+	runLength := max(memory/8, 1)
+	var runs []*os.File
+	defer func() {
+		for _, run := range runs {
+			run.Close()
+		}
+	}()
+
+	for lo := 0; lo < len(data); lo += runLength {
+		run, err := spillRun(dir, data[lo:min(lo+runLength, len(data))])
+		if err != nil {
+			panic(fmt.Sprintf("External sort: %v", err))
+		}
+		runs = append(runs, run)
+	}
+
+	if err := mergeRuns(runs, data, memory); err != nil {
+		panic(fmt.Sprintf("External sort: %v", err))
+	}
+}
+
+// spillRun sorts a copy of run and writes it to a new temp file in dir,
+// returning the file rewound to the start
+func spillRun(dir string, run []int) (*os.File, error) {
+	sorted := slices.Clone(run)
+	slices.Sort(sorted)
+
+	file, err := os.CreateTemp(dir, "run-")
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(file)
+	var buf [8]byte
+	for _, v := range sorted {
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		if _, err := w.Write(buf[:]); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// runCursor is the next unmerged value of a spilled run
+type runCursor struct {
+	r     *bufio.Reader
+	value int
+}
+
+// runHeap is a min-heap of run cursors ordered by their next value
+type runHeap []*runCursor
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return h[i].value < h[j].value }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*runCursor)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// next reads the cursor's next value, returning io.EOF at the end of the run
+func (c *runCursor) next() error {
+	var buf [8]byte
+	if _, err := io.ReadFull(c.r, buf[:]); err != nil {
+		return err
+	}
+	c.value = int(binary.LittleEndian.Uint64(buf[:]))
+	return nil
+}
+
+// mergeRuns k-way merges the sorted runs into out, splitting memory between
+// the runs' read buffers
+func mergeRuns(runs []*os.File, out []int, memory int) error {
+	bufferSize := max(memory/max(len(runs), 1), minMergeBuffer)
+
+	h := make(runHeap, 0, len(runs))
+	for _, run := range runs {
+		c := &runCursor{r: bufio.NewReaderSize(run, bufferSize)}
+		if err := c.next(); err != nil {
+			return err
+		}
+		h = append(h, c)
+	}
+	heap.Init(&h)
+
+	for i := range out {
+		c := h[0]
+		out[i] = c.value
+		err := c.next()
+		switch err {
+		case nil:
+			heap.Fix(&h, 0)
+		case io.EOF:
+			heap.Pop(&h)
+		default:
+			return err
+		}
+	}
+	return nil
+}
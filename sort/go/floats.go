@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// loadFloatData returns the float64 dataset with config.NaNPolicy applied
+func loadFloatData(config Config, ints []int) ([]float64, error) {
+	var data []float64
+	if config.FloatDataFile == "" {
+		data = make([]float64, len(ints))
+		for i, v := range ints {
+			data[i] = float64(v)
+		}
+	} else {
+		file, err := os.ReadFile(config.FloatDataFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// JSON has no NaN literal, so NaNs are written as null
+		var values []*float64
+		if err := json.Unmarshal(file, &values); err != nil {
+			return nil, err
+		}
+		data = make([]float64, len(values))
+		for i, v := range values {
+			if v == nil {
+				data[i] = math.NaN()
+			} else {
+				data[i] = *v
+			}
+		}
+	}
+
+	switch config.NaNPolicy {
+	case "", "first":
+		return data, nil
+	case "exclude":
+		filtered := data[:0]
+		for _, v := range data {
+			if !math.IsNaN(v) {
+				filtered = append(filtered, v)
+			}
+		}
+		return filtered, nil
+	default:
+		return nil, fmt.Errorf("unknown nanPolicy %q, expected \"first\" or \"exclude\"", config.NaNPolicy)
+	}
+}
+
+// sortNaNsFirst moves NaNs to the front, matching slices.Sort, and sorts the
+// remaining values with sortFn. NaN compares false against everything, so the
+// comparison sorts can't be handed NaNs directly.
+func sortNaNsFirst(data []float64, sortFn func([]float64)) {
+	nans := 0
+	for i, v := range data {
+		if math.IsNaN(v) {
+			data[i], data[nans] = data[nans], data[i]
+			nans++
+		}
+	}
+	sortFn(data[nans:])
+}
+
+func floatQuickSort(data []float64) {
+	sortNaNsFirst(data, genericQuickSort[float64])
+}
+
+func floatMergeSort(data []float64) {
+	sortNaNsFirst(data, orderedMergeSort[float64])
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+
+	"jsconf/internal/bench"
+)
+
+// DatasetConfig describes where the integer dataset comes from. With no
+// generator (or "file") it is read from Path, otherwise it is generated from
+// Seed so every run of the same config sorts identical data.
+type DatasetConfig struct {
+	// Name labels the dataset in output when config.json lists several
+	Name string `json:"name"`
+	// Generator is one of "file", "uniform", "sorted", "reversed",
+	// "nearly-sorted", "few-unique", "sawtooth", "all-equal", "two-values",
+	// "organ-pipe" or "quicksort-killer"
+	Generator string `json:"generator"`
+	// Path is the JSON file read by the "file" generator, or "-" for stdin
+	Path string `json:"path"`
+	Size int    `json:"size"`
+	// Seed drives the generator; when zero it is drawn from the config's
+	// top-level seed, and printed so the dataset can be reproduced
+	Seed int64 `json:"seed"`
+	// Min and Max bound generated values to [Min, Max)
+	Min int `json:"min"`
+	Max int `json:"max"`
+	// Swaps is the number of random swaps applied by "nearly-sorted"
+	Swaps int `json:"swaps"`
+	// Unique is the number of distinct values used by "few-unique"
+	Unique int `json:"unique"`
+	// Period is the length of each ramp produced by "sawtooth"
+	Period int `json:"period"`
+}
+
+// defaultDataPath is the pre-baked dataset shared with the other languages
+const defaultDataPath = "../data.json"
+
+// Defaults matching the shape of data.json
+const (
+	defaultDatasetSize = 100000
+	defaultDatasetMax  = 32768
+	defaultSwaps       = 100
+	defaultUnique      = 10
+	defaultPeriod      = 1000
+)
+
+type generator func(rng *rand.Rand, cfg DatasetConfig) []int
+
+var generators = map[string]generator{
+	"uniform":       generateUniform,
+	"sorted":        generateSorted,
+	"reversed":      generateReversed,
+	"nearly-sorted": generateNearlySorted,
+	"few-unique":    generateFewUnique,
+	"sawtooth":      generateSawtooth,
+
+	// Duplicate-heavy and adversarial profiles
+	"all-equal":        generateAllEqual,
+	"two-values":       generateTwoValues,
+	"organ-pipe":       generateOrganPipe,
+	"quicksort-killer": generateQuickSortKiller,
+}
+
+// loadDataset reads or generates the integer dataset and returns it along
+// with a one-line description, including the seed, for the run output
+func loadDataset(cfg DatasetConfig) ([]int, string, error) {
+	if cfg.Generator == "" || cfg.Generator == "file" {
+		path := cfg.Path
+		if path == "" {
+			path = defaultDataPath
+		}
+
+		dataFile, err := readInput(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		data, emitted, err := parseDataFile(dataFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if emitted != nil {
+			return data, fmt.Sprintf("file %s, %s", path, describeDataset(*emitted, len(data))), nil
+		}
+		return data, fmt.Sprintf("file %s, %d elements", path, len(data)), nil
+	}
+
+	data, resolved, err := generateDataset(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, describeDataset(resolved, len(data)), nil
+}
+
+// generateDataset runs cfg's generator and returns the data along with cfg
+// with its defaults and seed filled in, which regenerates the same data
+func generateDataset(cfg DatasetConfig) ([]int, DatasetConfig, error) {
+	generate, ok := generators[cfg.Generator]
+	if !ok {
+		return nil, cfg, fmt.Errorf("unknown generator %q", cfg.Generator)
+	}
+
+	if cfg.Size <= 0 {
+		cfg.Size = defaultDatasetSize
+	}
+	if cfg.Min == 0 && cfg.Max == 0 {
+		cfg.Max = defaultDatasetMax
+	}
+	if cfg.Max <= cfg.Min {
+		return nil, cfg, fmt.Errorf("dataset max (%d) must be greater than min (%d)", cfg.Max, cfg.Min)
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = bench.NewSeed()
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	return generate(rng, cfg), cfg, nil
+}
+
+// derivedSeed is the seed of one use of randomness in a run, such as a
+// dataset or the benchmark order, drawn from the run's seed so that one
+// number reproduces the whole run while each use gets its own sequence
+func derivedSeed(seed int64, use string) int64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	h.Write([]byte(use))
+	return int64(h.Sum64() & bench.SeedMask)
+}
+
+// seededDataset fills in the seed of the i-th dataset of config from the
+// run's seed, unless the dataset sets its own
+func seededDataset(config Config, cfg DatasetConfig, i int) DatasetConfig {
+	if cfg.Seed == 0 {
+		cfg.Seed = derivedSeed(config.Seed, fmt.Sprintf("dataset %d", i))
+	}
+	return cfg
+}
+
+func describeDataset(cfg DatasetConfig, n int) string {
+	return fmt.Sprintf("%s, %d elements in [%d, %d), seed %d", cfg.Generator, n, cfg.Min, cfg.Max, cfg.Seed)
+}
+
+// datasetFile is the format written by -emit-data: the data together with
+// the resolved generator settings that produced it. Data files may also be
+// a bare JSON array, like data.json.
+type datasetFile struct {
+	Dataset DatasetConfig `json:"dataset"`
+	Data    []int         `json:"data"`
+}
+
+// parseDataFile parses a bare array or a datasetFile, returning the
+// generator settings for the latter
+func parseDataFile(contents []byte) ([]int, *DatasetConfig, error) {
+	var data []int
+	if err := json.Unmarshal(contents, &data); err == nil {
+		return data, nil, nil
+	}
+
+	var file datasetFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, nil, err
+	}
+	return file.Data, &file.Dataset, nil
+}
+
+// emitDataset generates cfg's dataset and writes it with its settings to
+// path, so other languages sort byte-identical input
+func emitDataset(cfg DatasetConfig, path string) error {
+	if cfg.Generator == "" || cfg.Generator == "file" {
+		return fmt.Errorf("emitting data needs a dataset generator")
+	}
+
+	data, resolved, err := generateDataset(cfg)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.Marshal(datasetFile{Dataset: resolved, Data: data})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(out, '\n'), 0o644); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Wrote %s to %s", describeDataset(resolved, len(data)), path), "path", path, "size", len(data), "seed", resolved.Seed)
+	return nil
+}
+
+func generateUniform(rng *rand.Rand, cfg DatasetConfig) []int {
+	data := make([]int, cfg.Size)
+	for i := range data {
+		data[i] = cfg.Min + rng.Intn(cfg.Max-cfg.Min)
+	}
+	return data
+}
+
+func generateSorted(rng *rand.Rand, cfg DatasetConfig) []int {
+	data := generateUniform(rng, cfg)
+	radixSort(data)
+	return data
+}
+
+func generateReversed(rng *rand.Rand, cfg DatasetConfig) []int {
+	data := generateSorted(rng, cfg)
+	reverseRange(data, 0, len(data))
+	return data
+}
+
+// generateNearlySorted is sorted data with cfg.Swaps random pairs exchanged
+func generateNearlySorted(rng *rand.Rand, cfg DatasetConfig) []int {
+	data := generateSorted(rng, cfg)
+	swaps := cfg.Swaps
+	if swaps <= 0 {
+		swaps = defaultSwaps
+	}
+	for i := 0; i < swaps; i++ {
+		a, b := rng.Intn(len(data)), rng.Intn(len(data))
+		data[a], data[b] = data[b], data[a]
+	}
+	return data
+}
+
+// generateFewUnique draws from cfg.Unique distinct values
+func generateFewUnique(rng *rand.Rand, cfg DatasetConfig) []int {
+	unique := cfg.Unique
+	if unique <= 0 {
+		unique = defaultUnique
+	}
+	values := generateUniform(rng, DatasetConfig{Size: unique, Min: cfg.Min, Max: cfg.Max})
+
+	data := make([]int, cfg.Size)
+	for i := range data {
+		data[i] = values[rng.Intn(unique)]
+	}
+	return data
+}
+
+// generateSawtooth repeats an ascending ramp across [Min, Max) every
+// cfg.Period elements
+func generateSawtooth(rng *rand.Rand, cfg DatasetConfig) []int {
+	period := cfg.Period
+	if period <= 0 {
+		period = defaultPeriod
+	}
+	span := cfg.Max - cfg.Min
+
+	data := make([]int, cfg.Size)
+	for i := range data {
+		data[i] = cfg.Min + (i%period)*span/period
+	}
+	return data
+}
+
+// generateAllEqual repeats Min
+func generateAllEqual(rng *rand.Rand, cfg DatasetConfig) []int {
+	data := make([]int, cfg.Size)
+	for i := range data {
+		data[i] = cfg.Min
+	}
+	return data
+}
+
+// generateTwoValues picks Min or Max-1 at random for each element
+func generateTwoValues(rng *rand.Rand, cfg DatasetConfig) []int {
+	data := make([]int, cfg.Size)
+	for i := range data {
+		data[i] = cfg.Min
+		if rng.Intn(2) == 1 {
+			data[i] = cfg.Max - 1
+		}
+	}
+	return data
+}
+
+// generateOrganPipe ramps up from Min towards Max over the first half and
+// back down over the second
+func generateOrganPipe(rng *rand.Rand, cfg DatasetConfig) []int {
+	half := max((cfg.Size+1)/2, 1)
+	span := cfg.Max - cfg.Min
+
+	data := make([]int, cfg.Size)
+	for i := range data {
+		data[i] = cfg.Min + min(i, cfg.Size-1-i)*span/half
+	}
+	return data
+}
+
+// generateQuickSortKiller builds an input that drives quickSort's
+// median-of-three pivot to near the smallest element of every partition,
+// making it quadratic. It uses McIlroy's adversary ("A Killer Adversary for
+// Quicksort", 1999): sorting starts with every value undecided ("gas"), and
+// a value is only fixed ("frozen") when a comparison needs it, always as the
+// smallest remaining value, so the pivot candidate ends up small. Because
+// the adversary has to run the sort, generating n elements takes as long as
+// one quadratic sort of them. Values are a permutation of Min..Min+Size,
+// ignoring Max, since ties would let the partition escape.
+func generateQuickSortKiller(rng *rand.Rand, cfg DatasetConfig) []int {
+	n := cfg.Size
+	gas := n
+	values := make([]int, n)
+	indices := make([]int, n)
+	for i := range values {
+		values[i] = gas
+		indices[i] = i
+	}
+
+	frozen := 0
+	candidate := 0
+	freeze := func(i int) {
+		values[i] = frozen
+		frozen++
+	}
+	compare := func(a, b int) int {
+		if values[a] == gas && values[b] == gas {
+			if a == candidate {
+				freeze(a)
+			} else {
+				freeze(b)
+			}
+		}
+		if values[a] == gas {
+			candidate = a
+		} else if values[b] == gas {
+			candidate = b
+		}
+		return values[a] - values[b]
+	}
+
+	// quickSortFunc partitions exactly like quickSort, so the comparisons it
+	// makes on indices are the ones quickSort will make on the values
+	quickSortFunc(indices, compare)
+
+	// Anything still gas was only ever compared as larger than frozen
+	// values, so any distinct larger values keep the same comparisons
+	for i, v := range values {
+		if v == gas {
+			freeze(i)
+		}
+	}
+
+	data := make([]int, n)
+	for i, v := range values {
+		data[i] = cfg.Min + v
+	}
+	return data
+}
+
+// quickSortFunc is quickSort comparing elements with compare, which lets
+// generateQuickSortKiller's adversary see every comparison quickSort makes
+func quickSortFunc[T any](data []T, compare func(a, b T) int) {
+	for len(data) > insertionSortCutoff {
+		lo, hi := 0, len(data)-1
+		mid := hi / 2
+		if compare(data[mid], data[lo]) < 0 {
+			data[mid], data[lo] = data[lo], data[mid]
+		}
+		if compare(data[hi], data[lo]) < 0 {
+			data[hi], data[lo] = data[lo], data[hi]
+		}
+		if compare(data[hi], data[mid]) < 0 {
+			data[hi], data[mid] = data[mid], data[hi]
+		}
+		data[mid], data[hi-1] = data[hi-1], data[mid]
+		pivot := data[hi-1]
+
+		i, j := lo, hi-1
+		for {
+			for i++; compare(data[i], pivot) < 0; i++ {
+			}
+			for j--; compare(data[j], pivot) > 0; j-- {
+			}
+			if i >= j {
+				break
+			}
+			data[i], data[j] = data[j], data[i]
+		}
+		data[i], data[hi-1] = data[hi-1], data[i]
+
+		quickSortFunc(data[:i], compare)
+		data = data[i+1:]
+	}
+	insertionSortFunc(data, compare)
+}
+
+func insertionSortFunc[T any](data []T, compare func(a, b T) int) {
+	for i := 1; i < len(data); i++ {
+		v := data[i]
+		j := i - 1
+		for j >= 0 && compare(data[j], v) > 0 {
+			data[j+1] = data[j]
+			j--
+		}
+		data[j+1] = v
+	}
+}
+
+// readInput reads the file at path, or stdin when path is "-", which is the
+// simplest way to get data into the wasip1 build when no directory is
+// preopened. Only one of the config and dataset can come from stdin.
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,69 @@
+package main
+
+import "cmp"
+
+//go:generate sh -c "sed -f monomorphize.sed generic.go | gofmt > generic_int.go"
+
+// genericQuickSort is the same median-of-three quicksort as quickSort, written
+// once with a type parameter. generic_int.go is generated from this file with
+// the type parameter replaced by int so both versions share a single source
+// and any timing difference comes from how Go compiles generics.
+func genericQuickSort[T cmp.Ordered](data []T) {
+	genericQuickSortRange(data, 0, len(data)-1)
+}
+
+func genericQuickSortRange[T cmp.Ordered](data []T, lo, hi int) {
+	for hi-lo >= insertionSortCutoff {
+		p := genericPartition(data, lo, hi)
+		if p-lo < hi-p {
+			genericQuickSortRange(data, lo, p-1)
+			lo = p + 1
+		} else {
+			genericQuickSortRange(data, p+1, hi)
+			hi = p - 1
+		}
+	}
+	genericInsertionSortRange(data, lo, hi)
+}
+
+func genericPartition[T cmp.Ordered](data []T, lo, hi int) int {
+	mid := lo + (hi-lo)/2
+	if data[mid] < data[lo] {
+		data[mid], data[lo] = data[lo], data[mid]
+	}
+	if data[hi] < data[lo] {
+		data[hi], data[lo] = data[lo], data[hi]
+	}
+	if data[hi] < data[mid] {
+		data[hi], data[mid] = data[mid], data[hi]
+	}
+
+	data[mid], data[hi-1] = data[hi-1], data[mid]
+	pivot := data[hi-1]
+
+	i, j := lo, hi-1
+	for {
+		for i++; data[i] < pivot; i++ {
+		}
+		for j--; data[j] > pivot; j-- {
+		}
+		if i >= j {
+			break
+		}
+		data[i], data[j] = data[j], data[i]
+	}
+	data[i], data[hi-1] = data[hi-1], data[i]
+	return i
+}
+
+func genericInsertionSortRange[T cmp.Ordered](data []T, lo, hi int) {
+	for i := lo + 1; i <= hi; i++ {
+		v := data[i]
+		j := i - 1
+		for j >= lo && data[j] > v {
+			data[j+1] = data[j]
+			j--
+		}
+		data[j+1] = v
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"jsconf/internal/bench"
+)
+
+// isolatedChild is set in a child process started by runIsolated
+var isolatedChild bool
+
+// childLogArgs are the logging flags passed on to isolated children
+var childLogArgs []string
+
+// childResultsFD is the file descriptor a child writes its results to, the
+// first of exec.Cmd.ExtraFiles, leaving stdout for its normal output
+const childResultsFD = 3
+
+// childResult is a benchmarkResult as sent from a child to its parent
+type childResult struct {
+	Name      string                   `json:"name"`
+	Durations []time.Duration          `json:"durations"`
+	Warmup    []time.Duration          `json:"warmup"`
+	Unsteady  bool                     `json:"unsteady"`
+	Pauses    int                      `json:"pauses"`
+	Median    time.Duration            `json:"median"`
+	Stats     bench.Stats              `json:"stats"`
+	Outliers  int                      `json:"outliers"`
+	CI        bench.ConfidenceInterval `json:"ci"`
+	Batch     int                      `json:"batch"`
+	Adaptive  bool                     `json:"adaptive"`
+	TimedOut  bool                     `json:"timedOut"`
+	Cold      bool                     `json:"cold"`
+	Perf      []childPerf              `json:"perf"`
+	Mem       []childMem               `json:"mem"`
+
+	// Overhead is the harness's overhead per run, which OverheadSubtracted
+	// says was taken off Durations
+	Overhead           time.Duration `json:"overhead"`
+	OverheadSubtracted bool          `json:"overheadSubtracted"`
+
+	// OutlierIndexes are the indexes in Durations of the outliers
+	OutlierIndexes []int `json:"outlierIndexes"`
+
+	// VerifyError is set when the output failed verification, and Failure
+	// when it did or the benchmark panicked
+	VerifyError string         `json:"verifyError"`
+	Failure     *bench.Failure `json:"failure"`
+
+	// ClocksBefore and ClocksAfter are the CPU clock readings around the
+	// benchmark
+	ClocksBefore *bench.ClockSample `json:"clocksBefore"`
+	ClocksAfter  *bench.ClockSample `json:"clocksAfter"`
+
+	// MemoryLimit is the soft memory limit the benchmark ran under, and
+	// LimitGCs the collections it forced
+	MemoryLimit int64  `json:"memoryLimit"`
+	LimitGCs    uint32 `json:"limitGCs"`
+}
+
+type childPerf struct {
+	CacheMisses  uint64 `json:"cacheMisses"`
+	BranchMisses uint64 `json:"branchMisses"`
+}
+
+type childMem struct {
+	Allocs  uint64        `json:"allocs"`
+	Bytes   uint64        `json:"bytes"`
+	GCs     uint32        `json:"gcs"`
+	GCPause time.Duration `json:"gcPause"`
+}
+
+// runIsolated runs each selected algorithm against data in a fresh child
+// process, so heap growth and GC state from one algorithm can't carry over
+// into the next one's timings. With config.Parallel above 1 that many
+// children run at once. The children's results are added to suiteResults in
+// the order the algorithms started, as if the suite had run in this process,
+// and a child that dies is added as a failure of its algorithm. When the run
+// is interrupted no more children start, the running ones are interrupted
+// too, and only the algorithms whose child finished are added.
+func runIsolated(config Config, data []int) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("isolate: %w", err)
+	}
+
+	// Children read the exact dataset from a file rather than regenerating
+	// it, which would give each a different seed when none is configured
+	dataFile, err := os.CreateTemp("", "sort-data-*.json")
+	if err != nil {
+		return fmt.Errorf("isolate: %w", err)
+	}
+	defer os.Remove(dataFile.Name())
+	err = json.NewEncoder(dataFile).Encode(data)
+	if closeErr := dataFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("isolate: %w", err)
+	}
+
+	var keys []string
+	for _, key := range algorithmKeys {
+		if algorithmSelected(config, key) {
+			keys = append(keys, key)
+		}
+	}
+	if config.Shuffle {
+		shuffle(config, keys)
+	}
+	// The empty benchmark times the harness, so it runs in this process
+	runEmptyBenchmark(config)
+
+	// With parallel children each one's output is held back until it
+	// finishes, so the output of different algorithms doesn't interleave
+	parallel := max(config.Parallel, 1)
+	var outputMu sync.Mutex
+	results := make([][]childResult, len(keys))
+	errs := make([]error, len(keys))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		childConfig := config
+		childConfig.Dataset = DatasetConfig{Path: dataFile.Name()}
+		childConfig.Datasets = nil
+		childConfig.Sizes = nil
+		childConfig.Algos = []string{key}
+		childConfig.Exclude = nil
+		childConfig.Tags = nil
+		childConfig.SkipTags = nil
+		childConfig.Isolate = false
+		childConfig.Parallel = 0
+		childConfig.ResultsFile = ""
+		childConfig.Out = nil
+		childConfig.CSVFile = ""
+		childConfig.BenchstatFile = ""
+		childConfig.PushURL = ""
+		childConfig.PushHeaders = nil
+		// This process checks stability and marks the children's results
+		childConfig.CheckStability = false
+		childConfig.Empty = false
+		// The child runs a single dataset, so it profiles into this run's
+		// directories
+		childConfig.CPUProfileDir = harnessOptions.CPUProfileDir
+		childConfig.MemProfileDir = harnessOptions.MemProfileDir
+		childConfig.TraceDir = harnessOptions.TraceDir
+
+		sem <- struct{}{}
+		if bench.Interrupted() {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if parallel == 1 {
+				results[i], errs[i] = runChild(executable, childConfig, os.Stdout, os.Stderr)
+				return
+			}
+			var stdout, stderr bytes.Buffer
+			results[i], errs[i] = runChild(executable, childConfig, &stdout, &stderr)
+			outputMu.Lock()
+			defer outputMu.Unlock()
+			os.Stdout.Write(stdout.Bytes())
+			os.Stderr.Write(stderr.Bytes())
+		}()
+	}
+	wg.Wait()
+
+	for i, key := range keys {
+		if errs[i] != nil && bench.Interrupted() {
+			continue
+		}
+		// A child that died, e.g. of a fatal error the harness can't
+		// recover from, is recorded as a failure under the algorithm's key
+		// and the rest of the suite still counts
+		if errs[i] != nil {
+			slog.Warn(fmt.Sprintf("Isolated child running %s failed: %v, skipping it", key, errs[i]), "algorithm", key, "err", errs[i])
+			suiteResults = append(suiteResults, benchmarkResult{name: key, failure: &bench.Failure{
+				Kind: "crash", Where: "isolated child for " + key, Message: errs[i].Error()}})
+			continue
+		}
+		for _, r := range results[i] {
+			result := benchmarkResult{
+				name:      r.Name,
+				durations: r.Durations,
+				warmup:    r.Warmup,
+				unsteady:  r.Unsteady,
+				pauses:    r.Pauses,
+				median:    r.Median,
+				stats:     r.Stats,
+				outliers:  r.Outliers,
+				ci:        r.CI,
+				batch:     r.Batch,
+				adaptive:  r.Adaptive,
+				timedOut:  r.TimedOut,
+				cold:      r.Cold,
+
+				outlierIndexes: r.OutlierIndexes,
+				verifyError:    r.VerifyError,
+				failure:        r.Failure,
+				clocksBefore:   r.ClocksBefore,
+				clocksAfter:    r.ClocksAfter,
+				memoryLimit:    r.MemoryLimit,
+				limitGCs:       r.LimitGCs,
+
+				overhead:           r.Overhead,
+				overheadSubtracted: r.OverheadSubtracted,
+			}
+			for _, perf := range r.Perf {
+				result.perf = append(result.perf, bench.PerfCounts{CacheMisses: perf.CacheMisses, BranchMisses: perf.BranchMisses})
+			}
+			for _, mem := range r.Mem {
+				result.mem = append(result.mem, bench.MemDelta{Allocs: mem.Allocs, Bytes: mem.Bytes, GCs: mem.GCs, GCPause: mem.GCPause})
+			}
+			suiteResults = append(suiteResults, result)
+			markStable(key)
+		}
+	}
+	return nil
+}
+
+// runChild runs the benchmark binary as an isolated child with config on
+// stdin, passing its output to stdout and stderr, and returns the results it
+// writes back over a pipe. The child is interrupted along with this process.
+func runChild(executable string, config Config, stdout, stderr io.Writer) ([]childResult, error) {
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(executable, append([]string{"-isolated-child", "-config=-"}, childLogArgs...)...)
+	cmd.Stdin = bytes.NewReader(configJSON)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.ExtraFiles = []*os.File{w}
+	// The config already has the environment's overrides, which would
+	// otherwise undo the child's single algorithm
+	cmd.Env = slices.DeleteFunc(os.Environ(), func(v string) bool { return strings.HasPrefix(v, bench.EnvPrefix) })
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-bench.Interrupts():
+			cmd.Process.Signal(os.Interrupt)
+		case <-done:
+		}
+	}()
+
+	// Decode while the child runs so a full pipe can't block it
+	var results []childResult
+	decodeErr := json.NewDecoder(r).Decode(&results)
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("reading child results: %w", decodeErr)
+	}
+	return results, nil
+}
+
+// writeChildResults sends this child's results to its parent
+func writeChildResults(sweep []sizeResults) error {
+	results := []childResult{}
+	for _, size := range sweep {
+		for _, r := range size.results {
+			result := childResult{
+				Name:      r.name,
+				Durations: r.durations,
+				Warmup:    r.warmup,
+				Unsteady:  r.unsteady,
+				Pauses:    r.pauses,
+				Median:    r.median,
+				Stats:     r.stats,
+				Outliers:  r.outliers,
+				CI:        r.ci,
+				Batch:     r.batch,
+				Adaptive:  r.adaptive,
+				TimedOut:  r.timedOut,
+				Cold:      r.cold,
+
+				OutlierIndexes: r.outlierIndexes,
+				VerifyError:    r.verifyError,
+				Failure:        r.failure,
+				ClocksBefore:   r.clocksBefore,
+				ClocksAfter:    r.clocksAfter,
+				MemoryLimit:    r.memoryLimit,
+				LimitGCs:       r.limitGCs,
+
+				Overhead:           r.overhead,
+				OverheadSubtracted: r.overheadSubtracted,
+			}
+			for _, perf := range r.perf {
+				result.Perf = append(result.Perf, childPerf{CacheMisses: perf.CacheMisses, BranchMisses: perf.BranchMisses})
+			}
+			for _, mem := range r.mem {
+				result.Mem = append(result.Mem, childMem{Allocs: mem.Allocs, Bytes: mem.Bytes, GCs: mem.GCs, GCPause: mem.GCPause})
+			}
+			results = append(results, result)
+		}
+	}
+
+	pipe := os.NewFile(childResultsFD, "results")
+	defer pipe.Close()
+	return json.NewEncoder(pipe).Encode(results)
+}
@@ -0,0 +1,227 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+
+	"jsconf/internal/bench"
+)
+
+func main() {
+	algos := flag.String("algos", "", "comma-separated algorithms to run, e.g. quick,radix,builtin")
+	exclude := flag.String("exclude", "", "comma-separated algorithms to skip, e.g. bubble")
+	tags := flag.String("tags", "", "only run algorithms with all of these comma-separated tags: "+strings.Join(algorithmTagNames, ", "))
+	skipTags := flag.String("skip-tags", "", "skip algorithms with any of these comma-separated tags, e.g. quadratic")
+	configPath := flag.String("config", "../config.json", "path to the config file, or - to read it from stdin")
+	profile := flag.String("profile", "", "read the settings from this bundled configuration instead of -config: "+strings.Join(bench.ProfileNames, ", "))
+	resultsFile := flag.String("results", os.Getenv(bench.OutEnv), "write results as JSON to this file (default $"+bench.OutEnv+")")
+	out := flag.String("out", "", "also write the results in these comma-separated formats next to -results, e.g. json,csv,md,html: "+strings.Join(bench.OutputFormats, ", "))
+	csvFile := flag.String("out-csv", "", "write one row per benchmark iteration as CSV to this file")
+	benchstatFile := flag.String("out-benchstat", "", "write every sample in the go test -bench format read by benchstat to this file")
+	pushURL := flag.String("push-url", "", "POST the results document to this URL after the run")
+	pushHeaders := bench.HeaderFlag{}
+	flag.Var(pushHeaders, "push-header", "header sent with -push-url as \"Name: value\", e.g. \"Authorization: Bearer $TOKEN\" (repeatable)")
+	cpuProfileDir := flag.String("cpuprofile-dir", "", "write a pprof CPU profile of each benchmark's timed iterations to this directory")
+	memProfileDir := flag.String("memprofile-dir", "", "write heap profiles from before and after each benchmark's timed iterations to this directory, for go tool pprof -base")
+	traceDir := flag.String("trace-dir", "", "write an execution trace of each benchmark's timed iterations to this directory, for go tool trace")
+	trace := flag.String("trace", "", "comma-separated algorithms to trace with -trace-dir, e.g. parallel-quick (default all)")
+	cooldown := flag.Float64("cooldown", 0, "sleep this many seconds before each benchmark to let the CPU cool down")
+	coldData := flag.String("cold-data", "", "evict the CPU caches before each iteration: warm (no), cold, or both to run each benchmark both ways")
+	interleave := flag.Bool("interleave", false, "run each dataset's benchmarks together, one iteration of each in turn in an order drawn from the seed")
+	empty := flag.Bool("empty", false, "also run the harness's empty benchmark first, whose median is the harness's own overhead per sample")
+	subtractOverhead := flag.Bool("subtract-overhead", false, "take the harness's own overhead, timed with the empty benchmark, off every sample")
+	steadyState := flag.Bool("steady-state", false, "warm up each benchmark until its iteration times settle, with warmup as the minimum")
+	isolate := flag.Bool("isolate", false, "run each algorithm in a fresh child process")
+	parallel := flag.Int("parallel", 0, "run up to this many algorithms at once in child processes, for smoke runs; timings suffer from contention (default serial)")
+	nice := flag.Int("nice", 0, "run at this niceness, from -20 (highest priority) to 19")
+	realtime := flag.Bool("realtime", false, "run under the SCHED_FIFO real-time policy where permitted (Linux)")
+	cpuAffinity := flag.String("cpu-affinity", "", "pin the benchmarks to these CPUs, e.g. 2,3 or 0-3 (Linux)")
+	seed := flag.Int64("seed", 0, "draw every dataset and random choice from this seed, e.g. a previous run's, to reproduce it (default picked from the clock)")
+	shuffle := flag.Bool("shuffle", false, "run the benchmarks in an order drawn from the seed")
+	list := flag.Bool("list", false, "list the algorithms the config and -algos, -exclude, -tags and -skip-tags select, with their tags, instead of running them")
+	dryRun := flag.Bool("dry-run", false, "print the benchmarks the config would run, with estimated durations, instead of running them")
+	estimateFrom := flag.String("estimate-from", "", "with -dry-run, estimate durations from the medians in this results file (default the config's resultsFile, if present)")
+	emitData := flag.String("emit-data", "", "write the generated dataset and its seed to this file for the other languages, then exit")
+	child := flag.Bool("isolated-child", false, "internal: run as a child of -isolate and report results on fd 3")
+	var quietLog, verbose bool
+	var logFormat string
+	bench.DefineLogFlags(flag.CommandLine, &quietLog, &verbose, &logFormat)
+	flag.BoolVar(&quietLog, "quiet", false, "alias of -q, which also turns off progress reports and per-iteration output")
+	flag.Parse()
+
+	if err := bench.SetupLogging(logFormat, quietLog, verbose); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+	// Isolated children log the same way as their parent
+	childLogArgs = []string{"-log-format=" + logFormat, fmt.Sprintf("-q=%t", quietLog), fmt.Sprintf("-v=%t", verbose)}
+
+	// Read config.json, or the bundled profile
+	var configFile []byte
+	var err error
+	if *profile != "" {
+		configFile, err = bench.ReadProfile(profiles, *profile)
+	} else {
+		configFile, err = readInput(*configPath)
+	}
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error reading config.json: %v", err), "err", err)
+		os.Exit(1)
+	}
+
+	config, err := parseConfig(configFile)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error parsing config.json: %v", err), "err", err)
+		os.Exit(1)
+	}
+
+	if *algos != "" {
+		config.Algos = strings.Split(*algos, ",")
+	}
+	if *exclude != "" {
+		config.Exclude = strings.Split(*exclude, ",")
+	}
+	if *tags != "" {
+		config.Tags = bench.ParseTags(*tags)
+	}
+	if *skipTags != "" {
+		config.SkipTags = bench.ParseTags(*skipTags)
+	}
+	if quietLog {
+		config.Quiet = true
+	}
+	if *resultsFile != "" {
+		config.ResultsFile = *resultsFile
+	}
+	if *out != "" {
+		config.Out = strings.Split(*out, ",")
+	}
+	if *csvFile != "" {
+		config.CSVFile = *csvFile
+	}
+	if *benchstatFile != "" {
+		config.BenchstatFile = *benchstatFile
+	}
+	if *pushURL != "" {
+		config.PushURL = *pushURL
+	}
+	if len(pushHeaders) > 0 && config.PushHeaders == nil {
+		config.PushHeaders = map[string]string{}
+	}
+	maps.Copy(config.PushHeaders, pushHeaders)
+	if *cpuProfileDir != "" {
+		config.CPUProfileDir = *cpuProfileDir
+	}
+	if *memProfileDir != "" {
+		config.MemProfileDir = *memProfileDir
+	}
+	if *traceDir != "" {
+		config.TraceDir = *traceDir
+	}
+	if *trace != "" {
+		config.Trace = strings.Split(*trace, ",")
+	}
+	if *steadyState {
+		config.SteadyState.Enabled = true
+	}
+	if *cooldown != 0 {
+		config.Cooldown.Seconds = *cooldown
+	}
+	if *coldData != "" {
+		config.ColdData.Mode = *coldData
+	}
+	if *interleave {
+		config.Interleave = true
+	}
+	if *empty {
+		config.Empty = true
+	}
+	if *subtractOverhead {
+		config.SubtractOverhead = true
+	}
+	if *isolate {
+		config.Isolate = true
+	}
+	if *parallel > 0 {
+		config.Parallel = *parallel
+	}
+	if *seed != 0 {
+		config.Seed = *seed
+	}
+	if *shuffle {
+		config.Shuffle = true
+	}
+	if *nice != 0 {
+		config.Scheduling.Nice = *nice
+	}
+	if *realtime {
+		config.Scheduling.Realtime = true
+	}
+	if *cpuAffinity != "" {
+		if config.Scheduling.CPUAffinity, err = bench.ParseCPUList(*cpuAffinity); err != nil {
+			fmt.Printf("Error: -cpu-affinity: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if *list {
+		for _, key := range algorithmKeys {
+			if algorithmSelected(config, key) {
+				fmt.Printf("%s\t%s\n", key, strings.Join(algorithmTags(key), ","))
+			}
+		}
+		return
+	}
+
+	if *dryRun {
+		if err := planConfig(config, *estimateFrom); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *emitData != "" {
+		if err := emitDataset(seededDataset(config, config.Dataset, 0), *emitData); err != nil {
+			slog.Error(fmt.Sprintf("Error emitting data: %v", err), "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Isolated children are stopped by their parent, which drops the
+	// algorithm they were running, so they keep the default handling
+	if !*child {
+		bench.HandleInterrupts()
+	}
+
+	if *child {
+		isolatedChild = true
+		sweep, err := runConfig(config)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error in child: %v", err), "err", err)
+			os.Exit(1)
+		}
+		if err := writeChildResults(sweep); err != nil {
+			slog.Error(fmt.Sprintf("Error writing child results: %v", err), "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if _, err := runConfig(config); err != nil {
+		if errors.Is(err, errBenchmarksFailed) || errors.Is(err, bench.ErrInterrupted) {
+			slog.Error(fmt.Sprintf("Error: %v", err), "err", err)
+			os.Exit(1)
+		}
+		slog.Error(fmt.Sprintf("Error in config.json: %v", err), "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import "cmp"
+
+// Comparison sorts shared by the non-int datasets. The int benchmarks keep
+// their own hand-written copies so those numbers stay comparable with the
+// other languages.
+
+func orderedMergeSort[T cmp.Ordered](data []T) {
+	aux := make([]T, len(data))
+	orderedMergeSortRange(data, aux, 0, len(data))
+}
+
+func orderedMergeSortRange[T cmp.Ordered](data, aux []T, lo, hi int) {
+	if hi-lo < 2 {
+		return
+	}
+	mid := lo + (hi-lo)/2
+	orderedMergeSortRange(data, aux, lo, mid)
+	orderedMergeSortRange(data, aux, mid, hi)
+
+	copy(aux[lo:hi], data[lo:hi])
+	i, j := lo, mid
+	for k := lo; k < hi; k++ {
+		if j >= hi || (i < mid && aux[i] <= aux[j]) {
+			data[k] = aux[i]
+			i++
+		} else {
+			data[k] = aux[j]
+			j++
+		}
+	}
+}
+
+func orderedHeapSort[T cmp.Ordered](data []T) {
+	n := len(data)
+	for i := n/2 - 1; i >= 0; i-- {
+		orderedSiftDown(data, i, n)
+	}
+	for end := n - 1; end > 0; end-- {
+		data[0], data[end] = data[end], data[0]
+		orderedSiftDown(data, 0, end)
+	}
+}
+
+func orderedSiftDown[T cmp.Ordered](data []T, root, n int) {
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && data[child+1] > data[child] {
+			child++
+		}
+		if data[root] >= data[child] {
+			return
+		}
+		data[root], data[child] = data[child], data[root]
+		root = child
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// pdqSort is pattern-defeating quicksort (Orson Peters, 2021), the algorithm
+// slices.Sort uses since Go 1.19. On top of the plain quicksort it:
+//   - picks the pivot from a median of three, or of three medians on large
+//     ranges, and counts the swaps that took to spot sorted or reversed input
+//   - finishes nearly sorted ranges with a bounded insertion sort
+//   - groups runs of elements equal to an earlier pivot in one linear pass
+//   - shuffles a few elements after an unbalanced partition and falls back
+//     to heap sort once that has happened log(n) times, bounding the worst
+//     case at O(n log n)
+func pdqSort(data []int) {
+	limit := bits.Len(uint(len(data)))
+	pdqSortRange(data, 0, len(data), limit)
+}
+
+// pdqInsertionSortCutoff is the range length below which pdqSort uses
+// insertion sort
+const pdqInsertionSortCutoff = 12
+
+type sortedHint int
+
+const (
+	unknownHint sortedHint = iota
+	increasingHint
+	decreasingHint
+)
+
+func pdqSortRange(data []int, a, b, limit int) {
+	wasBalanced := true
+	wasPartitioned := true
+
+	for {
+		length := b - a
+		if length <= pdqInsertionSortCutoff {
+			insertionSort(data[a:b])
+			return
+		}
+
+		// Too many bad pivots, fall back to heap sort
+		if limit == 0 {
+			heapSort(data[a:b])
+			return
+		}
+
+		// The last partition was unbalanced, so shuffle to break the pattern
+		// that caused it
+		if !wasBalanced {
+			breakPatterns(data, a, b)
+			limit--
+		}
+
+		pivot, hint := choosePivot(data, a, b)
+		if hint == decreasingHint {
+			slices.Reverse(data[a:b])
+			pivot = (b - 1) - (pivot - a)
+			hint = increasingHint
+		}
+
+		// Likely already sorted, try to finish with a few insertions
+		if wasBalanced && wasPartitioned && hint == increasingHint {
+			if partialInsertionSort(data, a, b) {
+				return
+			}
+		}
+
+		// The element before the range is an earlier pivot no greater than
+		// anything in it. If it equals this pivot, everything equal to the
+		// pivot is already in place once grouped at the front.
+		if a > 0 && data[a-1] >= data[pivot] {
+			a = partitionEqual(data, a, b, pivot)
+			continue
+		}
+
+		mid, alreadyPartitioned := pdqPartition(data, a, b, pivot)
+		wasPartitioned = alreadyPartitioned
+
+		// Recurse into the smaller side and loop on the larger one
+		leftLen, rightLen := mid-a, b-mid
+		balanceThreshold := length / 8
+		if leftLen < rightLen {
+			wasBalanced = leftLen >= balanceThreshold
+			pdqSortRange(data, a, mid, limit)
+			a = mid + 1
+		} else {
+			wasBalanced = rightLen >= balanceThreshold
+			pdqSortRange(data, mid+1, b, limit)
+			b = mid
+		}
+	}
+}
+
+// pdqPartition partitions data[a:b] around data[pivot] and returns the
+// pivot's final index, and whether the range was already partitioned
+func pdqPartition(data []int, a, b, pivot int) (int, bool) {
+	data[a], data[pivot] = data[pivot], data[a]
+	i, j := a+1, b-1
+
+	for i <= j && data[i] < data[a] {
+		i++
+	}
+	for i <= j && data[j] >= data[a] {
+		j--
+	}
+	if i > j {
+		data[j], data[a] = data[a], data[j]
+		return j, true
+	}
+	data[i], data[j] = data[j], data[i]
+	i++
+	j--
+
+	for {
+		for i <= j && data[i] < data[a] {
+			i++
+		}
+		for i <= j && data[j] >= data[a] {
+			j--
+		}
+		if i > j {
+			break
+		}
+		data[i], data[j] = data[j], data[i]
+		i++
+		j--
+	}
+	data[j], data[a] = data[a], data[j]
+	return j, false
+}
+
+// partitionEqual moves every element equal to data[pivot] to the front of
+// data[a:b], given none are smaller, and returns the index after them
+func partitionEqual(data []int, a, b, pivot int) int {
+	data[a], data[pivot] = data[pivot], data[a]
+	i, j := a+1, b-1
+
+	for {
+		for i <= j && data[i] <= data[a] {
+			i++
+		}
+		for i <= j && data[j] > data[a] {
+			j--
+		}
+		if i > j {
+			break
+		}
+		data[i], data[j] = data[j], data[i]
+		i++
+		j--
+	}
+	return i
+}
+
+// partialInsertionSort fixes up to five out-of-order elements in data[a:b]
+// and reports whether that left it sorted
+func partialInsertionSort(data []int, a, b int) bool {
+	const (
+		maxSteps = 5
+		// Shifting isn't worth it below this length, where a normal
+		// partition is cheap
+		shortestShifting = 50
+	)
+
+	i := a + 1
+	for step := 0; step < maxSteps; step++ {
+		for i < b && data[i] >= data[i-1] {
+			i++
+		}
+		if i == b {
+			return true
+		}
+		if b-a < shortestShifting {
+			return false
+		}
+
+		data[i], data[i-1] = data[i-1], data[i]
+
+		// Shift the smaller element left and the larger one right
+		for j := i - 1; j > a && data[j] < data[j-1]; j-- {
+			data[j], data[j-1] = data[j-1], data[j]
+		}
+		for j := i + 1; j < b && data[j] < data[j-1]; j++ {
+			data[j], data[j-1] = data[j-1], data[j]
+		}
+	}
+	return false
+}
+
+// breakPatterns swaps three elements around the middle of data[a:b] with
+// pseudo-random others, seeded from the length so runs are reproducible
+func breakPatterns(data []int, a, b int) {
+	length := b - a
+	if length < 8 {
+		return
+	}
+
+	random := uint64(length)
+	mask := uint(1)<<bits.Len(uint(length)) - 1
+	idx := a + (length/4)*2 - 1
+	for i := 0; i < 3; i++ {
+		// xorshift64
+		random ^= random << 13
+		random ^= random >> 7
+		random ^= random << 17
+
+		other := int(uint(random) & mask)
+		if other >= length {
+			other -= length
+		}
+		data[idx-1+i], data[a+other] = data[a+other], data[idx-1+i]
+	}
+}
+
+// choosePivot returns a pivot index for data[a:b] and a hint of whether the
+// range looks sorted, based on how many swaps finding the median took
+func choosePivot(data []int, a, b int) (int, sortedHint) {
+	const (
+		shortestNinther = 50
+		maxSwaps        = 4 * 3
+	)
+
+	length := b - a
+	swaps := 0
+	i := a + length/4*1
+	j := a + length/4*2
+	k := a + length/4*3
+
+	if length >= 8 {
+		if length >= shortestNinther {
+			i = medianAdjacent(data, i, &swaps)
+			j = medianAdjacent(data, j, &swaps)
+			k = medianAdjacent(data, k, &swaps)
+		}
+		j = medianOfThree(data, i, j, k, &swaps)
+	}
+
+	switch swaps {
+	case 0:
+		return j, increasingHint
+	case maxSwaps:
+		return j, decreasingHint
+	default:
+		return j, unknownHint
+	}
+}
+
+// order2 returns a and b ordered by their values, counting a swap if they
+// were out of order
+func order2(data []int, a, b int, swaps *int) (int, int) {
+	if data[b] < data[a] {
+		*swaps++
+		return b, a
+	}
+	return a, b
+}
+
+func medianOfThree(data []int, a, b, c int, swaps *int) int {
+	a, b = order2(data, a, b, swaps)
+	b, c = order2(data, b, c, swaps)
+	_, b = order2(data, a, b, swaps)
+	return b
+}
+
+func medianAdjacent(data []int, a int, swaps *int) int {
+	return medianOfThree(data, a-1, a, a+1, swaps)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jsconf/internal/bench"
+)
+
+// planning makes runAlgorithm add benchmarks to plan instead of running
+// them, for -dry-run
+var planning bool
+
+// plannedBenchmark is a benchmark the suite would run
+type plannedBenchmark struct {
+	key  string
+	name string
+	size int
+	opts bench.Options
+}
+
+var plan []plannedBenchmark
+
+// planConfig prints every benchmark config would run, dataset by dataset,
+// with its iterations and an estimate of how long it takes from the medians
+// in a previous results document at estimateFrom, or at config's results
+// file when it's empty and that exists. Datasets are generated as for a run,
+// but no benchmark runs.
+func planConfig(config Config, estimateFrom string) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	configureHarness(config)
+
+	var calibration *bench.Results
+	path := estimateFrom
+	if path == "" {
+		path = config.ResultsFile
+	}
+	if path != "" {
+		results, err := bench.ReadResults(path)
+		switch {
+		case err == nil:
+			calibration = &results
+		case estimateFrom != "" || !errors.Is(err, fs.ErrNotExist):
+			return fmt.Errorf("reading estimates: %w", err)
+		}
+	}
+
+	planning = true
+	defer func() { planning = false }()
+
+	var total time.Duration
+	benchmarks, iterations, unestimated := 0, 0, 0
+	sizes, datasets := sweepSizes(config), sweepDatasets(config)
+	for _, datasetConfig := range datasets {
+		for _, size := range sizes {
+			data, dataset, err := loadDatasetOfSize(datasetConfig, size)
+			if err != nil {
+				return fmt.Errorf("loading dataset: %w", err)
+			}
+			if datasetConfig.Name != "" {
+				dataset = fmt.Sprintf("%s (%s)", datasetConfig.Name, dataset)
+			}
+			fmt.Printf("Dataset: %s\n", dataset)
+
+			plan = nil
+			if err := runSuite(config, data); err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, p := range plan {
+				runs := fmt.Sprintf("%d iterations", p.opts.Iterations)
+				if p.opts.Budget > 0 {
+					runs = fmt.Sprintf("%s budget", p.opts.Budget)
+				}
+				if steady := p.opts.SteadyState; steady.Enabled {
+					runs = fmt.Sprintf("%d-%d warmup + %s", minWarmup(p.opts), max(p.opts.Warmup, steady.MaxWarmup), runs)
+				} else if p.opts.Warmup > 0 {
+					runs = fmt.Sprintf("%d warmup + %s", p.opts.Warmup, runs)
+				}
+				cell := "unknown"
+				if estimate, ok := estimateDuration(calibration, datasetConfig.Name, p); ok {
+					cell = "~" + formatEstimate(estimate)
+					total += estimate
+				} else {
+					unestimated++
+				}
+				fmt.Fprintf(w, "  %s\t%s\t%s\n", p.name, runs, cell)
+				benchmarks++
+				iterations += minWarmup(p.opts) + p.opts.Iterations
+			}
+			w.Flush()
+		}
+	}
+
+	summary := fmt.Sprintf("\nPlan: %d datasets x %d sizes, %d benchmarks", len(datasets), len(sizes), benchmarks)
+	if config.BudgetSeconds == 0 {
+		summary += fmt.Sprintf(", %d iterations", iterations)
+	}
+	switch {
+	case calibration == nil:
+		summary += ", no estimate without a previous results file (-estimate-from)"
+	case unestimated == benchmarks:
+		summary += fmt.Sprintf(", no estimate since none of them are in %s", path)
+	default:
+		summary += ", estimated " + formatEstimate(total)
+		if unestimated > 0 {
+			summary += fmt.Sprintf(" plus %d benchmarks not in %s", unestimated, path)
+		}
+	}
+	fmt.Println(summary)
+	return nil
+}
+
+// estimateDuration estimates how long p takes from the median of the same
+// benchmark on the same dataset in calibration, scaled from the nearest
+// size measured by the algorithm's growth rate. Each iteration is assumed
+// to take the median, with Budget and Timeout bounding the timed ones, and
+// steady-state warmup to settle as soon as it can. The cooldown before the
+// benchmark is added, but not pauses for drift, which can't be foreseen.
+func estimateDuration(calibration *bench.Results, datasetName string, p plannedBenchmark) (time.Duration, bool) {
+	if calibration == nil {
+		return 0, false
+	}
+	var median float64
+	measured := 0
+	for _, run := range calibration.Runs {
+		if run.Name != datasetName || run.Size <= 0 {
+			continue
+		}
+		i := slices.IndexFunc(run.Benchmarks, func(b bench.BenchmarkSummary) bool { return b.Name == p.name })
+		if i < 0 || run.Benchmarks[i].Median == 0 {
+			continue
+		}
+		if measured == 0 || math.Abs(math.Log(float64(run.Size)/float64(p.size))) < math.Abs(math.Log(float64(measured)/float64(p.size))) {
+			median, measured = run.Benchmarks[i].Median, run.Size
+		}
+	}
+	if measured == 0 {
+		return 0, false
+	}
+
+	perIteration := time.Duration(median * growth(p.key, p.size) / growth(p.key, measured) * float64(time.Millisecond))
+	timed := perIteration * time.Duration(p.opts.Iterations)
+	if p.opts.Budget > 0 {
+		timed = max(p.opts.Budget, perIteration)
+	}
+	if p.opts.Timeout > 0 {
+		timed = min(timed, p.opts.Timeout)
+	}
+	cooldown := time.Duration(p.opts.Cooldown.Seconds * float64(time.Second))
+	return cooldown + perIteration*time.Duration(minWarmup(p.opts)) + timed, true
+}
+
+// minWarmup is the fewest warmup iterations opts runs
+func minWarmup(opts bench.Options) int {
+	if opts.SteadyState.Enabled {
+		return max(opts.Warmup, opts.SteadyState.Window)
+	}
+	return opts.Warmup
+}
+
+// growth is the relative cost of sorting n elements with the algorithm key
+func growth(key string, n int) float64 {
+	x := float64(max(n, 2))
+	if key == "bubble" || slices.Contains(quadraticKeys, key) {
+		return x * x
+	}
+	return x * math.Log2(x)
+}
+
+// formatEstimate rounds an estimate to a precision that suits its size
+func formatEstimate(d time.Duration) string {
+	switch {
+	case d >= time.Minute:
+		s := d.Round(time.Second).String()
+		if strings.HasSuffix(s, "m0s") {
+			s = strings.TrimSuffix(s, "0s")
+		}
+		return s
+	case d >= time.Second:
+		return d.Round(100 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// Record mirrors the "sort an array of objects" workload on the JS side
+type Record struct {
+	ID    int
+	Name  string
+	Score float64
+}
+
+// generateRecords returns n records whose names come from a small pool and
+// whose scores have two decimal places, so both keys contain many duplicates
+// and multi-key comparisons have to fall through to later keys. The same
+// seed gives the same records.
+func generateRecords(n int, seed int64) []Record {
+	rng := rand.New(rand.NewSource(seed))
+	names := []string{"ada", "brendan", "grace", "guido", "james", "ken", "linus", "margaret", "rob", "yukihiro"}
+
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = Record{
+			ID:    i,
+			Name:  names[rng.Intn(len(names))],
+			Score: float64(rng.Intn(10000)) / 100,
+		}
+	}
+	return records
+}
+
+func compareByScore(a, b Record) int {
+	return cmp.Compare(a.Score, b.Score)
+}
+
+// compareByNameScoreID orders by name, then highest score first, then ID
+func compareByNameScoreID(a, b Record) int {
+	if c := cmp.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(b.Score, a.Score); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// checkRecords panics unless records are sorted according to compare
+func checkRecords(records []Record, count int, compare func(a, b Record) int) {
+	if len(records) != count {
+		panic(fmt.Sprintf("Length mismatch: got %d, expected %d", len(records), count))
+	}
+	for i := 1; i < len(records); i++ {
+		if compare(records[i-1], records[i]) > 0 {
+			panic(fmt.Sprintf("Records out of order at index %d: %+v before %+v", i, records[i-1], records[i]))
+		}
+	}
+}
+
+func runRecordBenchmarks(config Config, records []Record) {
+	keys := []struct {
+		name    string
+		compare func(a, b Record) int
+	}{
+		{"by score", compareByScore},
+		{"by name, score, id", compareByNameScoreID},
+	}
+
+	for _, key := range keys {
+		check := func(sorted []Record) {
+			checkRecords(sorted, len(records), key.compare)
+		}
+
+		runAlgorithmWithCheck(config, "slices-sortfunc", fmt.Sprintf("slices.SortFunc (records %s)", key.name), records, func(data []Record) {
+			slices.SortFunc(data, key.compare)
+		}, check)
+		runAlgorithmWithCheck(config, "sort-slice", fmt.Sprintf("sort.Slice (records %s)", key.name), records, func(data []Record) {
+			sort.Slice(data, func(i, j int) bool {
+				return key.compare(data[i], data[j]) < 0
+			})
+		}, check)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import "jsconf/internal/bench"
+
+// buildResults collects every run into a bench.Results document
+func buildResults(config Config, sweep []sizeResults) bench.Results {
+	// Headers may hold credentials, which don't belong in a shared document
+	config.PushHeaders = nil
+	file := bench.NewResults("sort", config)
+	file.Seed = config.Seed
+	if config.Parallel > 1 {
+		file.Execution = bench.ExecutionParallel
+		file.Parallelism = config.Parallel
+	}
+	if config.Interleave {
+		file.Execution = bench.ExecutionInterleaved
+	}
+	for _, results := range sweep {
+		run := bench.ResultsRun{Name: results.name, Size: results.size, Dataset: results.dataset, Benchmarks: []bench.BenchmarkSummary{}}
+		for _, result := range results.results {
+			run.Benchmarks = append(run.Benchmarks, summarize(result))
+		}
+		file.Runs = append(file.Runs, run)
+	}
+	return file
+}
+
+func summarize(result benchmarkResult) bench.BenchmarkSummary {
+	summary := bench.Result{
+		Name:      result.name,
+		Durations: result.durations,
+		Warmup:    result.warmup,
+		Unsteady:  result.unsteady,
+		Median:    result.median,
+		Stats:     result.stats,
+		Outliers:  result.outliers,
+		CI:        result.ci,
+		Batch:     result.batch,
+		Perf:      result.perf,
+		Mem:       result.mem,
+		TimedOut:  result.timedOut,
+		Cold:      result.cold,
+
+		OutlierIndexes: result.outlierIndexes,
+		VerifyError:    result.verifyError,
+		Failure:        result.failure,
+		DriftPauses:    result.pauses,
+		ClocksBefore:   result.clocksBefore,
+		ClocksAfter:    result.clocksAfter,
+		MemoryLimit:    result.memoryLimit,
+		LimitGCs:       result.limitGCs,
+
+		Overhead:           result.overhead,
+		OverheadSubtracted: result.overheadSubtracted,
+	}.Summarize()
+	summary.Adaptive = result.adaptive
+	summary.Stable = result.stable
+	return summary
+}
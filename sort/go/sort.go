@@ -1,57 +1,272 @@
 package main
 
 import (
-	"encoding/json"
+	"cmp"
+	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"math/bits"
+	"math/rand"
+	"path/filepath"
 	"slices"
 	"sort"
+	"sync"
 	"time"
+
+	"jsconf/internal/bench"
 )
 
-type Config struct {
-	Iterations int `json:"iterations"`
-}
+// defaultRadixBase is used when config.json doesn't set radixBase
+const defaultRadixBase = 256
+
+// defaultBuckets is used when config.json doesn't set buckets
+const defaultBuckets = 1024
+
+// defaultQuadraticMaxSize is used when config.json doesn't set
+// quadraticMaxSize
+const defaultQuadraticMaxSize = 10000
 
 // Helper functions
-func copySlice(src []int) []int {
-	dst := make([]int, len(src))
+func copySlice[T any](src []T) []T {
+	dst := make([]T, len(src))
 	copy(dst, src)
 	return dst
 }
 
-func checkResults(data, expected []int) {
+// checkResults compares with cmp.Compare so NaNs in float data match each
+// other
+func checkResults[T cmp.Ordered](data, expected []T) {
 	if len(data) != len(expected) {
 		panic(fmt.Sprintf("Length mismatch: got %d, expected %d", len(data), len(expected)))
 	}
 
 	for i := 0; i < len(data); i++ {
-		if data[i] != expected[i] {
-			panic(fmt.Sprintf("Mismatch at index %d. Expected %d, got %d", i, expected[i], data[i]))
+		if cmp.Compare(data[i], expected[i]) != 0 {
+			panic(fmt.Sprintf("Mismatch at index %d. Expected %v, got %v", i, expected[i], data[i]))
 		}
 	}
 }
 
-func runBenchmark(name string, data []int, expected []int, iterations int, sortFn func([]int)) {
-	var durations []time.Duration
+// harnessOptions are the bench.Options from config.json shared by every
+// benchmark, set by runConfig
+var harnessOptions bench.Options
 
-	for i := 0; i < iterations; i++ {
-		clonedData := copySlice(data)
-		start := time.Now()
-		sortFn(clonedData)
-		end := time.Now()
-		duration := end.Sub(start)
-		checkResults(clonedData, expected)
-		durations = append(durations, duration)
-		fmt.Printf("%s iteration %d completed in %.2fms\n", name, i+1, float64(duration.Nanoseconds())/1000000)
+func runBenchmark[T cmp.Ordered](name string, data []T, expected []T, opts bench.Options, sortFn func([]T)) {
+	check := func(sorted []T) {
+		checkResults(sorted, expected)
 	}
+	if verifyPolicy == "checksum" {
+		expectedSum := checksum(expected)
+		check = func(sorted []T) {
+			if sum := checksum(sorted); sum != expectedSum {
+				panic(fmt.Sprintf("Checksum mismatch: got %016x, expected %016x", sum, expectedSum))
+			}
+		}
+	}
+	runBenchmarkWithCheck(name, data, opts, sortFn, check)
+}
 
-	// Calculate median
-	sort.Slice(durations, func(i, j int) bool {
-		return durations[i] < durations[j]
-	})
-	median := durations[len(durations)/2]
-	fmt.Printf("%s: %.2fms\n", name, float64(median.Nanoseconds())/1000000)
+// runBenchmarkWithCheck is runBenchmark for element types that can't be
+// compared against an expected slice directly, such as records sorted by a
+// key where equal keys may legitimately end up in any order
+func runBenchmarkWithCheck[T any](name string, data []T, opts bench.Options, sortFn func([]T), check func([]T)) {
+	runHarnessBenchmark(&sliceBenchmark[T]{name: name, data: data, sortFn: sortFn, check: check}, opts)
+}
+
+// runEmptyBenchmark times the harness's empty benchmark with the shared
+// options, when config asks for it
+func runEmptyBenchmark(config Config) {
+	if !config.Empty {
+		return
+	}
+	opts := harnessOptions
+	opts.Warmup = config.Warmup
+	opts.Iterations = config.Iterations
+	if planning {
+		plan = append(plan, plannedBenchmark{key: bench.EmptyName, name: bench.EmptyName, opts: opts})
+		return
+	}
+	runHarnessBenchmark(bench.NewEmptyBenchmark(), opts)
+}
+
+// interleaving is set while runSuite collects the benchmarks of an
+// interleaved suite in pending instead of running them one by one
+var (
+	interleaving bool
+	pending      []pendingBenchmark
+	// afterInterleaving are the reports to print once the pending
+	// benchmarks have run
+	afterInterleaving []func()
+)
+
+// pendingBenchmark is a benchmark waiting to be interleaved, whose result
+// goes at index in suiteResults
+type pendingBenchmark struct {
+	benchmark bench.Benchmark
+	opts      bench.Options
+	index     int
+}
+
+// runHarnessBenchmark runs b and adds its result to suiteResults. While
+// interleaving it only adds a placeholder result, for runInterleaved to
+// fill in.
+func runHarnessBenchmark(b bench.Benchmark, opts bench.Options) {
+	if interleaving {
+		pending = append(pending, pendingBenchmark{benchmark: b, opts: opts, index: len(suiteResults)})
+		suiteResults = append(suiteResults, benchmarkResult{name: plannedName(b.Name(), opts)})
+		return
+	}
+	result, err := bench.Run(b, opts)
+	if r, ok := newBenchmarkResult(b.Name(), result, err); ok {
+		suiteResults = append(suiteResults, r)
+	}
+}
+
+// runInterleaved runs the pending benchmarks with bench.RunInterleaved,
+// in an order drawn from config's seed, and fills in their results
+func runInterleaved(config Config) {
+	benchmarks := make([]bench.Benchmark, len(pending))
+	opts := make([]bench.Options, len(pending))
+	for i, p := range pending {
+		benchmarks[i], opts[i] = p.benchmark, p.opts
+	}
+	results, errs := bench.RunInterleaved(benchmarks, opts, derivedSeed(config.Seed, "interleave"))
+	interrupted := make([]bool, len(suiteResults))
+	for i, p := range pending {
+		r, ok := newBenchmarkResult(p.benchmark.Name(), results[i], errs[i])
+		if !ok {
+			interrupted[p.index] = true
+			continue
+		}
+		r.adaptive = suiteResults[p.index].adaptive
+		r.stable = suiteResults[p.index].stable
+		suiteResults[p.index] = r
+	}
+	kept := suiteResults[:0]
+	for i, r := range suiteResults {
+		if !interrupted[i] {
+			kept = append(kept, r)
+		}
+	}
+	suiteResults = kept
+	pending = nil
+
+	for _, report := range afterInterleaving {
+		report()
+	}
+	afterInterleaving = nil
+}
+
+// whenRun calls report once the benchmarks added so far have run: at once,
+// or after runInterleaved while interleaving
+func whenRun(report func()) {
+	if interleaving {
+		afterInterleaving = append(afterInterleaving, report)
+		return
+	}
+	report()
+}
+
+// newBenchmarkResult converts what bench.Run returned for the benchmark
+// named name, reporting false when it was interrupted, which leaves it out
+func newBenchmarkResult(name string, result bench.Result, err error) (benchmarkResult, bool) {
+	// An interrupted benchmark is left out, and the rest return at once
+	if errors.Is(err, bench.ErrInterrupted) {
+		return benchmarkResult{}, false
+	}
+	if err != nil {
+		if !errors.Is(err, bench.ErrVerification) && !errors.Is(err, bench.ErrPanicked) {
+			panic(err)
+		}
+		slog.Warn(fmt.Sprintf("%v, skipping %s", err, name), "benchmark", name, "err", err)
+	}
+	return benchmarkResult{
+		name:      result.Name,
+		durations: result.Durations,
+		warmup:    result.Warmup,
+		unsteady:  result.Unsteady,
+		pauses:    result.DriftPauses,
+		median:    result.Median,
+		stats:     result.Stats,
+		outliers:  result.Outliers,
+		ci:        result.CI,
+		batch:     result.Batch,
+		perf:      result.Perf,
+		mem:       result.Mem,
+		timedOut:  result.TimedOut,
+		cold:      result.Cold,
+
+		outlierIndexes: result.OutlierIndexes,
+		verifyError:    result.VerifyError,
+		failure:        result.Failure,
+		clocksBefore:   result.ClocksBefore,
+		clocksAfter:    result.ClocksAfter,
+		memoryLimit:    result.MemoryLimit,
+		limitGCs:       result.LimitGCs,
+
+		overhead:           result.Overhead,
+		overheadSubtracted: result.OverheadSubtracted,
+	}, true
+}
+
+// sliceBenchmark adapts a sort function to bench.BatchBenchmark, sorting a
+// fresh copy of data each run
+type sliceBenchmark[T any] struct {
+	name   string
+	data   []T
+	sorted []T
+	sortFn func([]T)
+	check  func([]T)
+	// inputs are the copies prepared for the runs of a batch
+	inputs [][]T
+}
+
+func (b *sliceBenchmark[T]) Name() string {
+	return b.name
+}
+
+func (b *sliceBenchmark[T]) Setup() {
+	b.SetupBatch(1)
+}
+
+func (b *sliceBenchmark[T]) SetupBatch(n int) {
+	b.inputs = b.inputs[:0]
+	for range n {
+		b.inputs = append(b.inputs, copySlice(b.data))
+	}
+}
+
+// Run sorts the next prepared copy, leaving it in sorted for Verify
+func (b *sliceBenchmark[T]) Run() {
+	b.sorted, b.inputs = b.inputs[0], b.inputs[1:]
+	b.sortFn(b.sorted)
+}
+
+// Shuffle reorders the prepared copies, which sorting doesn't notice, for
+// cold data runs
+func (b *sliceBenchmark[T]) Shuffle(rng *rand.Rand) {
+	for _, input := range b.inputs {
+		rng.Shuffle(len(input), func(i, j int) { input[i], input[j] = input[j], input[i] })
+	}
+}
+
+// Verify turns the panics from check into an error
+func (b *sliceBenchmark[T]) Verify() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	b.check(b.sorted)
+	return nil
+}
+
+// reportPass is called by quadratic sorts after each outer-loop pass of n so
+// long sorts report progress and can be stopped by the timeout
+func reportPass(pass, n int) {
+	// Pass i of a quadratic sort does n-i units of work, so the first k
+	// passes cover k(2n-k)/n² of the total
+	bench.ReportProgress(float64(pass+1) * float64(2*n-pass-1) / (float64(n) * float64(n)))
 }
 
 func bubbleSort(data []int) {
@@ -65,6 +280,7 @@ func bubbleSort(data []int) {
 				data[j+1] = temp
 			}
 		}
+		reportPass(i, n)
 	}
 }
 
@@ -73,24 +289,72 @@ func radixSort(data []int) {
 		return
 	}
 
-	// Find maximum value
-	max := data[0]
+	// Find minimum and maximum value
+	min, max := data[0], data[0]
 	for _, v := range data {
 		if v > max {
 			max = v
 		}
+		if v < min {
+			min = v
+		}
+	}
+
+	// Negative values need signed digits, keep the common case unchanged
+	if min < 0 {
+		for exp := 1; max/exp > 0 || min/exp < 0; exp *= 10 {
+			countingSortBySignedDigit(data, exp)
+		}
+		return
 	}
 
 	// Do counting sort for every digit
 	for exp := 1; max/exp > 0; exp *= 10 {
-		countingSort(data, exp)
+		countingSortByDigit(data, exp)
+	}
+}
+
+// countingSortBySignedDigit is countingSortByDigit for inputs containing
+// negative values. Go's truncated division gives negative numbers digits in
+// -9..0, so digits are offset by 9 into 19 buckets; because every digit of a
+// number shares its sign, this orders negative values before positive ones.
+func countingSortBySignedDigit(data []int, exp int) {
+	countingSortBySignedDigitInto(data, make([]int, len(data)), exp)
+}
+
+// countingSortBySignedDigitInto is countingSortBySignedDigit using output,
+// which must be as long as data, as scratch space
+func countingSortBySignedDigitInto(data, output []int, exp int) {
+	n := len(data)
+	var count [19]int
+
+	for i := 0; i < n; i++ {
+		count[(data[i]/exp)%10+9]++
+	}
+
+	for i := 1; i < 19; i++ {
+		count[i] += count[i-1]
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		digit := (data[i]/exp)%10 + 9
+		output[count[digit]-1] = data[i]
+		count[digit]--
 	}
+
+	copy(data, output)
+}
+
+// countingSortByDigit stably sorts data by the decimal digit selected by exp
+func countingSortByDigit(data []int, exp int) {
+	countingSortByDigitInto(data, make([]int, len(data)), exp)
 }
 
-func countingSort(data []int, exp int) {
+// countingSortByDigitInto is countingSortByDigit using output, which must be
+// as long as data, as scratch space
+func countingSortByDigitInto(data, output []int, exp int) {
 	n := len(data)
-	output := make([]int, n)
-	count := make([]int, 10)
+	var count [10]int
 
 	// Store count of occurrences
 	for i := 0; i < n; i++ {
@@ -114,45 +378,808 @@ func countingSort(data []int, exp int) {
 	}
 }
 
-func builtinSort(data []int) {
-	slices.Sort(data)
+// insertionSortCutoff is the partition size below which quicksort hands off
+// to insertion sort
+const insertionSortCutoff = 16
+
+func quickSort(data []int) {
+	quickSortRange(data, 0, len(data)-1)
 }
 
-func main() {
-	// Read data.json
-	dataFile, err := os.ReadFile("../data.json")
-	if err != nil {
-		fmt.Printf("Error reading data.json: %v\n", err)
+func quickSortRange(data []int, lo, hi int) {
+	for hi-lo >= insertionSortCutoff {
+		p := partition(data, lo, hi)
+
+		// Recurse into the smaller half to bound stack depth
+		if p-lo < hi-p {
+			quickSortRange(data, lo, p-1)
+			lo = p + 1
+		} else {
+			quickSortRange(data, p+1, hi)
+			hi = p - 1
+		}
+	}
+	insertionSortRange(data, lo, hi)
+}
+
+// partition uses a median-of-three pivot and returns its final index
+func partition(data []int, lo, hi int) int {
+	mid := lo + (hi-lo)/2
+
+	// Order lo, mid, hi so the median ends up at mid
+	if data[mid] < data[lo] {
+		data[mid], data[lo] = data[lo], data[mid]
+	}
+	if data[hi] < data[lo] {
+		data[hi], data[lo] = data[lo], data[hi]
+	}
+	if data[hi] < data[mid] {
+		data[hi], data[mid] = data[mid], data[hi]
+	}
+
+	// Park the pivot next to hi; data[hi] is already >= pivot
+	data[mid], data[hi-1] = data[hi-1], data[mid]
+	pivot := data[hi-1]
+
+	i, j := lo, hi-1
+	for {
+		for i++; data[i] < pivot; i++ {
+		}
+		for j--; data[j] > pivot; j-- {
+		}
+		if i >= j {
+			break
+		}
+		data[i], data[j] = data[j], data[i]
+	}
+	data[i], data[hi-1] = data[hi-1], data[i]
+	return i
+}
+
+func insertionSort(data []int) {
+	insertionSortRange(data, 0, len(data)-1)
+}
+
+func insertionSortRange(data []int, lo, hi int) {
+	for i := lo + 1; i <= hi; i++ {
+		v := data[i]
+		j := i - 1
+		for j >= lo && data[j] > v {
+			data[j+1] = data[j]
+			j--
+		}
+		data[j+1] = v
+	}
+}
+
+// parallelCutoff is the partition size below which parallel quicksort stops
+// handing work to other goroutines
+const parallelCutoff = 4096
+
+// parallelQuickSort is quickSort with one side of each large partition handed
+// to another goroutine when a worker slot is free. The semaphore bounds the
+// number of extra goroutines so large inputs don't spawn thousands of them.
+func parallelQuickSort(data []int, workers int) {
+	sem := make(chan struct{}, max(workers-1, 0))
+	var wg sync.WaitGroup
+	parallelQuickSortRange(data, 0, len(data)-1, sem, &wg)
+	wg.Wait()
+}
+
+func parallelQuickSortRange(data []int, lo, hi int, sem chan struct{}, wg *sync.WaitGroup) {
+	for hi-lo >= parallelCutoff {
+		p := partition(data, lo, hi)
+
+		select {
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func(lo, hi int) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				parallelQuickSortRange(data, lo, hi, sem, wg)
+			}(lo, p-1)
+		default:
+			// No free worker, sort the left side on this goroutine
+			parallelQuickSortRange(data, lo, p-1, sem, wg)
+		}
+		lo = p + 1
+	}
+	quickSortRange(data, lo, hi)
+}
+
+func mergeSort(data []int) {
+	// One auxiliary buffer is allocated up front and reused by every merge
+	aux := make([]int, len(data))
+	mergeSortRange(data, aux, 0, len(data))
+}
+
+// mergeSortRange sorts data[lo:hi]
+func mergeSortRange(data, aux []int, lo, hi int) {
+	if hi-lo < 2 {
 		return
 	}
+	mid := lo + (hi-lo)/2
+	mergeSortRange(data, aux, lo, mid)
+	mergeSortRange(data, aux, mid, hi)
+	merge(data, aux, lo, mid, hi)
+}
 
-	var data []int
-	err = json.Unmarshal(dataFile, &data)
-	if err != nil {
-		fmt.Printf("Error parsing data.json: %v\n", err)
+// merge combines the sorted runs data[lo:mid] and data[mid:hi]
+func merge(data, aux []int, lo, mid, hi int) {
+	copy(aux[lo:hi], data[lo:hi])
+
+	i, j := lo, mid
+	for k := lo; k < hi; k++ {
+		// Take from the left run on ties to keep the sort stable
+		if j >= hi || (i < mid && aux[i] <= aux[j]) {
+			data[k] = aux[i]
+			i++
+		} else {
+			data[k] = aux[j]
+			j++
+		}
+	}
+}
+
+func heapSort(data []int) {
+	n := len(data)
+
+	// Build a max-heap
+	for i := n/2 - 1; i >= 0; i-- {
+		siftDown(data, i, n)
+	}
+
+	// Repeatedly move the max to the end and restore the heap
+	for end := n - 1; end > 0; end-- {
+		data[0], data[end] = data[end], data[0]
+		siftDown(data, 0, end)
+	}
+}
+
+// siftDown restores the heap property for the subtree rooted at root within
+// data[:n]
+func siftDown(data []int, root, n int) {
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && data[child+1] > data[child] {
+			child++
+		}
+		if data[root] >= data[child] {
+			return
+		}
+		data[root], data[child] = data[child], data[root]
+		root = child
+	}
+}
+
+func selectionSort(data []int) {
+	n := len(data)
+	for i := 0; i < n-1; i++ {
+		minIndex := i
+		for j := i + 1; j < n; j++ {
+			if data[j] < data[minIndex] {
+				minIndex = j
+			}
+		}
+		data[i], data[minIndex] = data[minIndex], data[i]
+		reportPass(i, n)
+	}
+}
+
+// ciuraGaps returns Ciura's empirically derived gap sequence, extended by a
+// factor of 2.25 for large inputs, in descending order
+func ciuraGaps(n int) []int {
+	gaps := []int{1, 4, 10, 23, 57, 132, 301, 701}
+	for next := 701 * 9 / 4; next < n; next = next * 9 / 4 {
+		gaps = append(gaps, next)
+	}
+	return descendingGaps(gaps, n)
+}
+
+// knuthGaps returns the (3^k - 1) / 2 sequence 1, 4, 13, 40, ... in
+// descending order
+func knuthGaps(n int) []int {
+	var gaps []int
+	for gap := 1; gap < n; gap = gap*3 + 1 {
+		gaps = append(gaps, gap)
+	}
+	return descendingGaps(gaps, n)
+}
+
+// descendingGaps drops gaps that don't fit in n elements and reverses the
+// ascending sequence, always keeping the final gap of 1
+func descendingGaps(ascending []int, n int) []int {
+	var gaps []int
+	for i := len(ascending) - 1; i >= 0; i-- {
+		if ascending[i] < n || ascending[i] == 1 {
+			gaps = append(gaps, ascending[i])
+		}
+	}
+	return gaps
+}
+
+func shellGapsFor(name string, n int) ([]int, error) {
+	switch name {
+	case "", "ciura":
+		return ciuraGaps(n), nil
+	case "knuth":
+		return knuthGaps(n), nil
+	default:
+		return nil, fmt.Errorf("unknown shellGaps %q, expected \"ciura\" or \"knuth\"", name)
+	}
+}
+
+func shellSort(data []int, gaps []int) {
+	n := len(data)
+	for _, gap := range gaps {
+		// Gapped insertion sort
+		for i := gap; i < n; i++ {
+			v := data[i]
+			j := i
+			for j >= gap && data[j-gap] > v {
+				data[j] = data[j-gap]
+				j -= gap
+			}
+			data[j] = v
+		}
+	}
+}
+
+// bucketSort scatters values into equal-width buckets across the value range,
+// insertion sorts each bucket and concatenates them. It assumes roughly
+// uniform input; skewed input degrades towards insertion sort.
+func bucketSort(data []int, bucketCount int) {
+	if len(data) < 2 {
 		return
 	}
 
-	// Read config.json
-	configFile, err := os.ReadFile("../config.json")
-	if err != nil {
-		fmt.Printf("Error reading config.json: %v\n", err)
+	lo, hi := data[0], data[0]
+	for _, v := range data {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	width := (hi-lo)/bucketCount + 1
+
+	buckets := make([][]int, bucketCount)
+	for _, v := range data {
+		b := (v - lo) / width
+		buckets[b] = append(buckets[b], v)
+	}
+
+	i := 0
+	for _, bucket := range buckets {
+		insertionSort(bucket)
+		i += copy(data[i:], bucket)
+	}
+}
+
+// shiftRadixSort is an LSD radix sort on binary digits of digitBits bits,
+// extracting each digit with a shift and mask instead of the division and
+// modulo radixSort needs for decimal digits. Flipping the sign bit makes
+// negative values order before positive ones as unsigned keys, and passes
+// above the highest bit that differs between any two values are skipped.
+func shiftRadixSort(data []int, digitBits uint) {
+	if len(data) < 2 {
 		return
 	}
 
-	var config Config
-	err = json.Unmarshal(configFile, &config)
-	if err != nil {
-		fmt.Printf("Error parsing config.json: %v\n", err)
+	const signBit = 1 << 63
+	first := uint64(data[0]) ^ signBit
+	var diff uint64
+	for _, v := range data {
+		diff |= (uint64(v) ^ signBit) ^ first
+	}
+
+	radix := 1 << digitBits
+	mask := uint64(radix - 1)
+	count := make([]int, radix)
+	src, dst := data, make([]int, len(data))
+
+	for shift := uint(0); shift < uint(bits.Len64(diff)); shift += digitBits {
+		clear(count)
+		for _, v := range src {
+			count[(uint64(v)^signBit)>>shift&mask]++
+		}
+
+		// Turn counts into starting offsets
+		offset := 0
+		for i, c := range count {
+			count[i] = offset
+			offset += c
+		}
+
+		for _, v := range src {
+			digit := (uint64(v) ^ signBit) >> shift & mask
+			dst[count[digit]] = v
+			count[digit]++
+		}
+		src, dst = dst, src
+	}
+
+	// After an odd number of passes the result is in the scratch buffer
+	if &src[0] != &data[0] {
+		copy(data, src)
+	}
+}
+
+// msdRadixSort is a most-significant-digit radix sort on bytes, permuting
+// elements into their buckets in place (American flag sort) and recursing
+// into each bucket on the next byte. Unlike LSD radix sort it only looks at
+// as many low bytes as needed to separate values, which pays off when the
+// high bits are clustered.
+func msdRadixSort(data []int) {
+	if len(data) < 2 {
+		return
+	}
+
+	const signBit = 1 << 63
+	first := uint64(data[0]) ^ signBit
+	var diff uint64
+	for _, v := range data {
+		diff |= (uint64(v) ^ signBit) ^ first
+	}
+	if diff == 0 {
 		return
 	}
 
+	// Start at the highest byte in which any two values differ
+	msdRadixSortRange(data, uint(bits.Len64(diff)-1)/8*8)
+}
+
+func msdRadixSortRange(data []int, shift uint) {
+	if len(data) <= insertionSortCutoff {
+		insertionSort(data)
+		return
+	}
+
+	const signBit = 1 << 63
+	digit := func(v int) int {
+		return int((uint64(v) ^ signBit) >> shift & 0xff)
+	}
+
+	var count, next, end [256]int
+	for _, v := range data {
+		count[digit(v)]++
+	}
+	offset := 0
+	for b := range count {
+		next[b] = offset
+		offset += count[b]
+		end[b] = offset
+	}
+
+	// Cycle each misplaced element into the next free slot of its bucket
+	for b := range count {
+		for next[b] < end[b] {
+			v := data[next[b]]
+			for d := digit(v); d != b; d = digit(v) {
+				data[next[d]], v = v, data[next[d]]
+				next[d]++
+			}
+			data[next[b]] = v
+			next[b]++
+		}
+	}
+
+	if shift == 0 {
+		return
+	}
+	start := 0
+	for b := range count {
+		if count[b] > 1 {
+			msdRadixSortRange(data[start:end[b]], shift-8)
+		}
+		start = end[b]
+	}
+}
+
+// maxCountingSortRange is the largest value range counting sort will allocate
+// a count array for before falling back to radix sort
+const maxCountingSortRange = 1 << 24
+
+// countingSort detects the range of values and, when it is small enough,
+// sorts in a single counting pass with no comparisons
+func countingSort(data []int) {
+	if len(data) < 2 {
+		return
+	}
+
+	lo, hi := data[0], data[0]
+	for _, v := range data {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	if uint(hi-lo) >= maxCountingSortRange {
+		radixSort(data)
+		return
+	}
+
+	count := make([]int, hi-lo+1)
+	for _, v := range data {
+		count[v-lo]++
+	}
+
+	i := 0
+	for offset, c := range count {
+		for ; c > 0; c-- {
+			data[i] = lo + offset
+			i++
+		}
+	}
+}
+
+func builtinSort(data []int) {
+	slices.Sort(data)
+}
+
+// The remaining standard library entry points sort the same way but differ in
+// how comparisons are dispatched: through sort.Interface methods, a closure
+// over indices, or a closure over values
+
+func sortInts(data []int) {
+	sort.Ints(data)
+}
+
+func sortSlice(data []int) {
+	sort.Slice(data, func(i, j int) bool {
+		return data[i] < data[j]
+	})
+}
+
+func slicesSortFunc(data []int) {
+	slices.SortFunc(data, func(a, b int) int {
+		return cmp.Compare(a, b)
+	})
+}
+
+// runProfileDir is where the profiles of one run are written under dir:
+// dir itself when there's a single run, and otherwise a subdirectory per
+// dataset and size, e.g. "profiles/reversed/n10000"
+func runProfileDir(dir string, config Config, datasetConfig DatasetConfig, datasetIndex, size int) string {
+	if len(config.Datasets) > 0 {
+		name := datasetConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("dataset-%d", datasetIndex+1)
+		}
+		dir = filepath.Join(dir, name)
+	}
+	if len(config.Sizes) > 0 {
+		dir = filepath.Join(dir, fmt.Sprintf("n%d", size))
+	}
+	return dir
+}
+
+// runConfig runs the suite once per dataset and size, or just once when
+// config sets neither, and returns the results of every run. When the run
+// is interrupted, it writes the results of the benchmarks that completed,
+// marked partial, and returns bench.ErrInterrupted.
+func runConfig(config Config) ([]sizeResults, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	configureHarness(config)
+	// Isolated children inherit the scheduling settings from their parent
+	if !isolatedChild {
+		if err := config.Scheduling.Apply(); err != nil {
+			slog.Warn(fmt.Sprintf("Couldn't apply scheduling settings, continuing without them: %v", err), "err", err)
+		}
+		slog.Info(fmt.Sprintf("Seed: %d", config.Seed), "seed", config.Seed)
+	}
+	sizes, datasets := sweepSizes(config), sweepDatasets(config)
+
+	// Stability doesn't depend on the dataset, so it's checked once up front
+	// and marked on every run's results
+	stability = nil
+	if config.CheckStability {
+		stable, err := checkStability(config, derivedSeed(config.Seed, "stability"))
+		if err != nil {
+			return nil, err
+		}
+		stability = stable
+	}
+
+	var sweep []sizeResults
+	for i, datasetConfig := range datasets {
+		var datasetSweep []sizeResults
+		for _, size := range sizes {
+			data, dataset, err := loadDatasetOfSize(datasetConfig, size)
+			if err != nil {
+				return nil, fmt.Errorf("loading dataset: %w", err)
+			}
+			if datasetConfig.Name != "" {
+				dataset = fmt.Sprintf("%s (%s)", datasetConfig.Name, dataset)
+			}
+			if !isolatedChild {
+				slog.Info("Dataset: "+dataset, "dataset", dataset, "size", len(data))
+			}
+
+			if config.CPUProfileDir != "" {
+				harnessOptions.CPUProfileDir = runProfileDir(config.CPUProfileDir, config, datasetConfig, i, len(data))
+			}
+			if config.MemProfileDir != "" {
+				harnessOptions.MemProfileDir = runProfileDir(config.MemProfileDir, config, datasetConfig, i, len(data))
+			}
+			if config.TraceDir != "" {
+				harnessOptions.TraceDir = runProfileDir(config.TraceDir, config, datasetConfig, i, len(data))
+			}
+
+			suiteResults = nil
+			if config.Isolate || config.Parallel > 1 {
+				err = runIsolated(config, data)
+			} else {
+				err = runSuite(config, data)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !isolatedChild && config.Chart != "none" {
+				printChart(suiteResults, config.Chart)
+			}
+			datasetSweep = append(datasetSweep, sizeResults{
+				name:    datasetConfig.Name,
+				size:    len(data),
+				dataset: dataset,
+				results: suiteResults,
+			})
+			if bench.Interrupted() {
+				break
+			}
+		}
+
+		if len(config.Sizes) > 0 {
+			printSweep(datasetSweep)
+		}
+		sweep = append(sweep, datasetSweep...)
+		if bench.Interrupted() {
+			break
+		}
+	}
+
+	if config.ResultsFile != "" || config.CSVFile != "" || config.BenchstatFile != "" || config.PushURL != "" {
+		results := buildResults(config, sweep)
+		results.Partial = bench.Interrupted()
+		if config.ResultsFile != "" {
+			if err := results.Write(config.ResultsFile); err != nil {
+				return nil, fmt.Errorf("writing results: %w", err)
+			}
+			// ResultsFile is already the JSON
+			formats := slices.DeleteFunc(slices.Clone(config.Out), func(format string) bool { return format == "json" })
+			if err := results.WriteFormats(config.ResultsFile, formats); err != nil {
+				return nil, err
+			}
+		}
+		if config.CSVFile != "" {
+			if err := results.WriteCSV(config.CSVFile); err != nil {
+				return nil, fmt.Errorf("writing CSV results: %w", err)
+			}
+		}
+		if config.BenchstatFile != "" {
+			if err := results.WriteBenchstat(config.BenchstatFile); err != nil {
+				return nil, fmt.Errorf("writing benchstat results: %w", err)
+			}
+		}
+		if config.PushURL != "" {
+			if err := results.Push(config.PushURL, config.PushHeaders); err != nil {
+				return nil, err
+			}
+			slog.Info(fmt.Sprintf("Pushed results to %s", config.PushURL), "url", config.PushURL)
+		}
+	}
+
+	if bench.Interrupted() {
+		return sweep, bench.ErrInterrupted
+	}
+	// Children leave failures to be reported once by their parent
+	if failed := countFailures(sweep); failed > 0 && !isolatedChild {
+		return sweep, fmt.Errorf("%d benchmarks %w", failed, errBenchmarksFailed)
+	}
+	return sweep, nil
+}
+
+// configureHarness sets the harness options and verification policy shared
+// by every benchmark in config
+func configureHarness(config Config) {
+	verifyPolicy = config.Verify
+
+	harnessOptions = bench.Options{
+		ShouldVerify:  shouldVerify,
+		Quiet:         config.Quiet,
+		Timeout:       time.Duration(config.TimeoutSeconds * float64(time.Second)),
+		Budget:        time.Duration(config.BudgetSeconds * float64(time.Second)),
+		MinSampleTime: time.Duration(config.MinSampleSeconds * float64(time.Second)),
+		PerfCounters:  config.PerfCounters,
+		Outliers:      config.Outliers,
+		Confidence:    config.Confidence,
+		SteadyState:   config.SteadyState,
+		Cooldown:      config.Cooldown,
+
+		SubtractOverhead: config.SubtractOverhead,
+	}
+}
+
+// errBenchmarksFailed is returned by runConfig, after the whole suite has
+// run and its results are written, when any benchmark panicked or failed
+// verification
+var errBenchmarksFailed = errors.New("failed")
+
+func countFailures(sweep []sizeResults) int {
+	failed := 0
+	for _, results := range sweep {
+		for _, r := range results.results {
+			if r.failure != nil {
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+// runSuite runs every benchmark against data and the float, string and record
+// datasets derived from it
+func runSuite(config Config, data []int) error {
+	shellGaps, err := shellGapsFor(config.ShellGaps, len(data))
+	if err != nil {
+		return err
+	}
+
+	workers := config.Workers
+	buckets := config.Buckets
+	radixBase := config.RadixBase
+	radixDigitBits := uint(bits.TrailingZeros(uint(radixBase)))
+	externalMemory := config.ExternalMemory
+
+	floatData, err := loadFloatData(config, data)
+	if err != nil {
+		return fmt.Errorf("loading float data: %w", err)
+	}
+
 	// Create expected sorted data for validation
 	expected := copySlice(data)
 	slices.Sort(expected)
 
+	// Buffers for the reusing variants live across iterations
+	mergeScratch := &scratchBuffer{}
+	radixScratch := &scratchBuffer{}
+
 	// Run benchmarks
-	runBenchmark("Bubble sort", data, expected, config.Iterations, bubbleSort)
-	runBenchmark("Radix sort", data, expected, config.Iterations, radixSort)
-	runBenchmark("Built-in sort", data, expected, config.Iterations, builtinSort)
+	intBenchmarks := []intBenchmark{
+		{"bubble", "Bubble sort", bubbleSort},
+		{"radix", "Radix sort", radixSort},
+		{"radix-reuse", "Radix sort (reused buffer)", func(data []int) {
+			radixSortReusing(data, radixScratch)
+		}},
+		{"shift-radix", fmt.Sprintf("Radix sort (base %d)", radixBase), func(data []int) {
+			shiftRadixSort(data, radixDigitBits)
+		}},
+		{"msd-radix", "MSD radix sort", msdRadixSort},
+		{"counting", "Counting sort", countingSort},
+		{"quick", "Quicksort", quickSort},
+		{"branchless-quick", "Branchless quicksort", branchlessQuickSort},
+		{"pdq", "Pdqsort", pdqSort},
+		{"generic-quick", "Generic quicksort", genericQuickSort[int]},
+		{"mono-quick", "Monomorphic quicksort", monoQuickSort},
+		{"parallel-quick", fmt.Sprintf("Parallel quicksort (%d workers)", workers), func(data []int) {
+			parallelQuickSort(data, workers)
+		}},
+		{"merge", "Merge sort", mergeSort},
+		{"merge-alloc", "Merge sort (allocate per merge)", mergeSortAllocating},
+		{"merge-reuse", "Merge sort (reused buffer)", func(data []int) {
+			mergeSortReusing(data, mergeScratch)
+		}},
+		{"heap", "Heap sort", heapSort},
+		{"timsort", "Timsort", timSort},
+		{"bucket", fmt.Sprintf("Bucket sort (%d buckets)", buckets), func(data []int) {
+			bucketSort(data, buckets)
+		}},
+		{"external", fmt.Sprintf("External merge sort (%dKB runs)", externalMemory/1024), func(data []int) {
+			externalSort(data, externalMemory)
+		}},
+		{"shell", "Shell sort", func(data []int) {
+			shellSort(data, shellGaps)
+		}},
+		// Quadratic sorts only make sense on small inputs
+		{"insertion", "Insertion sort", insertionSort},
+		{"selection", "Selection sort", selectionSort},
+		{"builtin", "Built-in sort", builtinSort},
+		{"sort-ints", "sort.Ints", sortInts},
+		{"sort-slice", "sort.Slice", sortSlice},
+		{"slices-sortfunc", "slices.SortFunc", slicesSortFunc},
+	}
+	// Each step runs one algorithm, or a group of benchmarks on a dataset of
+	// their own, in this order unless config.Shuffle reorders them
+	var steps []func()
+	for _, b := range intBenchmarks {
+		steps = append(steps, func() { runAlgorithm(config, b.key, b.name, data, expected, b.sortFn) })
+	}
+
+	if config.SortedInput {
+		steps = append(steps, func() { runSortedInputBenchmarks(config, intBenchmarks, expected) })
+	}
+
+	// Partial sort benchmarks
+	steps = append(steps, func() { runTopKBenchmarks(config, data, expected, config.TopK) })
+
+	// Element width benchmarks
+	steps = append(steps,
+		func() { runWidthBenchmarks[int32](config, "int32", data) },
+		func() { runWidthBenchmarks[int64](config, "int64", data) },
+		func() { runWidthBenchmarks[uint64](config, "uint64", data) },
+	)
+
+	// Float benchmarks
+	expectedFloats := copySlice(floatData)
+	slices.Sort(expectedFloats)
+
+	steps = append(steps,
+		func() {
+			runAlgorithm(config, "quick", "Quicksort (float64)", floatData, expectedFloats, floatQuickSort)
+		},
+		func() {
+			runAlgorithm(config, "merge", "Merge sort (float64)", floatData, expectedFloats, floatMergeSort)
+		},
+		func() {
+			runAlgorithm(config, "builtin", "Built-in sort (float64)", floatData, expectedFloats, slices.Sort[[]float64])
+		},
+	)
+
+	// String benchmarks
+	stringCount := config.StringCount
+	if stringCount <= 0 {
+		stringCount = len(data)
+	}
+	stringData := generateStrings(stringCount, derivedSeed(config.Seed, "strings"))
+	expectedStrings := copySlice(stringData)
+	slices.Sort(expectedStrings)
+
+	steps = append(steps,
+		func() {
+			runAlgorithm(config, "quick", "Quicksort (string)", stringData, expectedStrings, genericQuickSort[string])
+		},
+		func() {
+			runAlgorithm(config, "merge", "Merge sort (string)", stringData, expectedStrings, orderedMergeSort[string])
+		},
+		func() {
+			runAlgorithm(config, "heap", "Heap sort (string)", stringData, expectedStrings, orderedHeapSort[string])
+		},
+		func() {
+			runAlgorithm(config, "multikey", "Multikey quicksort (string)", stringData, expectedStrings, multikeyQuickSort)
+		},
+		func() {
+			runAlgorithm(config, "builtin", "Built-in sort (string)", stringData, expectedStrings, slices.Sort[[]string])
+		},
+	)
+
+	// Struct benchmarks
+	recordCount := config.RecordCount
+	if recordCount <= 0 {
+		recordCount = len(data)
+	}
+	records := generateRecords(recordCount, derivedSeed(config.Seed, "records"))
+	steps = append(steps, func() { runRecordBenchmarks(config, records) })
+
+	if config.Shuffle {
+		shuffle(config, steps)
+	}
+	interleaving = config.Interleave && !planning
+	defer func() { interleaving = false }()
+	runEmptyBenchmark(config)
+	for _, step := range steps {
+		step()
+	}
+	if interleaving {
+		runInterleaved(config)
+	}
+	return nil
+}
+
+// shuffle reorders steps, such as the benchmarks of a run, in an order drawn
+// from config's seed
+func shuffle[T any](config Config, steps []T) {
+	rng := rand.New(rand.NewSource(derivedSeed(config.Seed, "order")))
+	rng.Shuffle(len(steps), func(i, j int) { steps[i], steps[j] = steps[j], steps[i] })
 }
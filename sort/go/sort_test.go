@@ -0,0 +1,445 @@
+package main
+
+import (
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"jsconf/internal/bench"
+)
+
+func TestRadixSortNegative(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	inputs := map[string][]int{
+		"mixed sign":    make([]int, 10000),
+		"all negative":  make([]int, 10000),
+		"small values":  {-1, 0, 1, -10, 10, -9, 9, -100, 100, 0, -1},
+		"wide range":    {1 << 40, -(1 << 40), 3, -3, 1<<40 - 1, -(1<<40 - 1)},
+		"single":        {-5},
+		"no negatives":  {5, 3, 9, 0, 12},
+		"negative only": {-3, -30, -300, -31, -2},
+	}
+	for i := range inputs["mixed sign"] {
+		inputs["mixed sign"][i] = rng.Intn(65536) - 32768
+		inputs["all negative"][i] = -rng.Intn(1000000) - 1
+	}
+
+	for name, input := range inputs {
+		expected := slices.Clone(input)
+		slices.Sort(expected)
+
+		got := slices.Clone(input)
+		radixSort(got)
+		if !slices.Equal(got, expected) {
+			t.Errorf("radixSort(%s) = %v, want %v", name, got, expected)
+		}
+	}
+}
+
+func TestShiftRadixSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]int, 10000)
+	for i := range data {
+		data[i] = rng.Int() - rng.Int()
+	}
+
+	for _, digitBits := range []uint{1, 4, 8, 11, 16} {
+		expected := slices.Clone(data)
+		slices.Sort(expected)
+
+		got := slices.Clone(data)
+		shiftRadixSort(got, digitBits)
+		if !slices.Equal(got, expected) {
+			t.Errorf("shiftRadixSort(%d bits) did not sort its input", digitBits)
+		}
+	}
+}
+
+func TestMSDRadixSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	inputs := map[string][]int{
+		"mixed sign":     make([]int, 10000),
+		"clustered high": make([]int, 10000),
+		"few unique":     make([]int, 10000),
+		"small":          {3, -1, 2},
+		"equal":          {4, 4, 4, 4},
+	}
+	for i := 0; i < 10000; i++ {
+		inputs["mixed sign"][i] = rng.Int() - rng.Int()
+		inputs["clustered high"][i] = 1<<50 + rng.Intn(1<<12)
+		inputs["few unique"][i] = rng.Intn(3) << 40
+	}
+
+	for name, input := range inputs {
+		expected := slices.Clone(input)
+		slices.Sort(expected)
+
+		got := slices.Clone(input)
+		msdRadixSort(got)
+		if !slices.Equal(got, expected) {
+			t.Errorf("msdRadixSort(%s) did not sort its input", name)
+		}
+	}
+}
+
+func TestMultikeyQuickSort(t *testing.T) {
+	inputs := map[string][]string{
+		"generated":       generateStrings(10000, 1),
+		"shared prefixes": {"abc", "ab", "abcd", "a", "", "abd", "abc", "b", "aa", "abcde", "ab", "abb", "abcc", "abca", "abcb", "a", "abz", "ab"},
+		"empty":           {},
+	}
+	for name, input := range inputs {
+		expected := slices.Clone(input)
+		slices.Sort(expected)
+
+		got := slices.Clone(input)
+		multikeyQuickSort(got)
+		if !slices.Equal(got, expected) {
+			t.Errorf("multikeyQuickSort(%s) did not sort its input", name)
+		}
+	}
+}
+
+func TestChecksumOrderSensitive(t *testing.T) {
+	if checksum([]int{1, 2, 3}) == checksum([]int{2, 1, 3}) {
+		t.Fatal("checksum of reordered ints matched")
+	}
+	if checksum([]string{"a", "bc"}) == checksum([]string{"ab", "c"}) {
+		t.Fatal("checksum of differently split strings matched")
+	}
+	if checksum([]float64{math.NaN(), 1}) != checksum([]float64{-math.NaN(), 1}) {
+		t.Fatal("checksum of NaNs with different payloads differed")
+	}
+}
+
+func TestPdqSortPatterns(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 20000
+	inputs := map[string][]int{
+		"random":     make([]int, n),
+		"sorted":     make([]int, n),
+		"reversed":   make([]int, n),
+		"few unique": make([]int, n),
+		"sawtooth":   make([]int, n),
+		"organ pipe": make([]int, n),
+	}
+	for i := 0; i < n; i++ {
+		inputs["random"][i] = rng.Intn(1 << 20)
+		inputs["sorted"][i] = i
+		inputs["reversed"][i] = n - i
+		inputs["few unique"][i] = rng.Intn(4)
+		inputs["sawtooth"][i] = i % 100
+		inputs["organ pipe"][i] = min(i, n-i)
+	}
+	// Nearly sorted exercises partialInsertionSort
+	nearly := slices.Clone(inputs["sorted"])
+	for i := 0; i < 5; i++ {
+		a, b := rng.Intn(n), rng.Intn(n)
+		nearly[a], nearly[b] = nearly[b], nearly[a]
+	}
+	inputs["nearly sorted"] = nearly
+
+	for name, input := range inputs {
+		for _, size := range []int{0, 1, 12, 13, 50, n} {
+			data := slices.Clone(input[:size])
+			pdqSort(data)
+			if !slices.Equal(data, slices.Sorted(slices.Values(input[:size]))) {
+				t.Fatalf("%s, %d elements: not sorted", name, size)
+			}
+		}
+	}
+}
+
+func TestExternalSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]int, 5000)
+	for i := range data {
+		data[i] = rng.Intn(1<<20) - 1<<19
+	}
+	expected := slices.Clone(data)
+	slices.Sort(expected)
+
+	// 1KB runs of 128 elements, so the merge has 40 runs
+	externalSort(data, 1024)
+	if !slices.Equal(data, expected) {
+		t.Fatal("external sort output not sorted")
+	}
+
+	var empty []int
+	externalSort(empty, 1024)
+}
+
+func TestTopK(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	input := make([]int, 1000)
+	for i := range input {
+		input[i] = rng.Intn(50)
+	}
+	sorted := slices.Sorted(slices.Values(input))
+
+	for _, k := range []int{0, 1, 10, 999, 1000, 2000} {
+		want := sorted[len(sorted)-min(k, len(sorted)):]
+		for name, topK := range map[string]func([]int, int){"heap": heapTopK, "quickselect": quickSelectTopK} {
+			data := slices.Clone(input)
+			topK(data, k)
+			got := data[len(data)-len(want):]
+			if !slices.Equal(got, want) {
+				t.Fatalf("%s, k=%d: got %v, want %v", name, k, got, want)
+			}
+			if !slices.Equal(slices.Sorted(slices.Values(data)), sorted) {
+				t.Fatalf("%s, k=%d: output is not a permutation of the input", name, k)
+			}
+		}
+	}
+}
+
+func TestBranchlessQuickSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 15, 16, 17, 1000, 50000} {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = rng.Intn(100) - 50
+		}
+		expected := slices.Sorted(slices.Values(data))
+		branchlessQuickSort(data)
+		if !slices.Equal(data, expected) {
+			t.Fatalf("%d elements: not sorted", n)
+		}
+	}
+}
+
+func TestAllocationVariants(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	scratch := &scratchBuffer{}
+	for _, n := range []int{0, 1, 100, 5000, 300} {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = rng.Intn(20000) - 10000
+		}
+		expected := slices.Sorted(slices.Values(data))
+
+		variants := map[string]func([]int){
+			"merge alloc": mergeSortAllocating,
+			"merge reuse": func(d []int) { mergeSortReusing(d, scratch) },
+			"radix reuse": func(d []int) { radixSortReusing(d, scratch) },
+		}
+		for name, sortFn := range variants {
+			got := slices.Clone(data)
+			sortFn(got)
+			if !slices.Equal(got, expected) {
+				t.Fatalf("%s, %d elements: not sorted", name, n)
+			}
+		}
+	}
+}
+
+func TestIntSorts(t *testing.T) {
+	sorts := []struct {
+		name   string
+		cutoff int
+		sortFn func([]int)
+	}{
+		{"quickSort", insertionSortCutoff, quickSort},
+		{"mergeSort", insertionSortCutoff, mergeSort},
+		{"heapSort", insertionSortCutoff, heapSort},
+		{"insertionSort", insertionSortCutoff, insertionSort},
+		{"selectionSort", insertionSortCutoff, selectionSort},
+		{"shellSort ciura", insertionSortCutoff, func(d []int) { shellSort(d, ciuraGaps(len(d))) }},
+		{"shellSort knuth", insertionSortCutoff, func(d []int) { shellSort(d, knuthGaps(len(d))) }},
+		{"parallelQuickSort", parallelCutoff, func(d []int) { parallelQuickSort(d, 4) }},
+		{"bucketSort", insertionSortCutoff, func(d []int) { bucketSort(d, 16) }},
+		{"countingSort", insertionSortCutoff, countingSort},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range sorts {
+		for _, n := range []int{0, 1, s.cutoff - 1, s.cutoff, s.cutoff + 1, 5000} {
+			inputs := map[string][]int{
+				"random":    make([]int, n),
+				"all equal": make([]int, n),
+			}
+			for i := 0; i < n; i++ {
+				inputs["random"][i] = rng.Intn(2000) - 1000
+				inputs["all equal"][i] = -7
+			}
+			for name, input := range inputs {
+				expected := slices.Sorted(slices.Values(input))
+				got := slices.Clone(input)
+				s.sortFn(got)
+				if !slices.Equal(got, expected) {
+					t.Errorf("%s(%s, %d elements) did not sort its input", s.name, name, n)
+				}
+			}
+		}
+	}
+
+	// Past maxCountingSortRange counting sort falls back to radix sort
+	wide := []int{maxCountingSortRange, -3, 0, -maxCountingSortRange, 7, -3, maxCountingSortRange - 1}
+	expected := slices.Sorted(slices.Values(wide))
+	countingSort(wide)
+	if !slices.Equal(wide, expected) {
+		t.Errorf("countingSort(wide range) = %v, want %v", wide, expected)
+	}
+}
+
+func TestShellGaps(t *testing.T) {
+	for name, gapsFor := range map[string]func(int) []int{"ciura": ciuraGaps, "knuth": knuthGaps} {
+		// Inputs of fewer than two elements need no gaps at all
+		for _, n := range []int{2, 5, 100, 1000, 1000000} {
+			gaps := gapsFor(n)
+			if len(gaps) == 0 || gaps[len(gaps)-1] != 1 {
+				t.Errorf("%sGaps(%d) = %v, want it to end in 1", name, n, gaps)
+				continue
+			}
+			for i, gap := range gaps {
+				if i > 0 && gap >= gaps[i-1] {
+					t.Errorf("%sGaps(%d) = %v, want it descending", name, n, gaps)
+					break
+				}
+				if gap != 1 && gap >= n {
+					t.Errorf("%sGaps(%d) = %v, has gap %d beyond the input", name, n, gaps, gap)
+				}
+			}
+		}
+	}
+	if got, want := ciuraGaps(1000), []int{701, 301, 132, 57, 23, 10, 4, 1}; !slices.Equal(got, want) {
+		t.Errorf("ciuraGaps(1000) = %v, want %v", got, want)
+	}
+	if got, want := knuthGaps(1000), []int{364, 121, 40, 13, 4, 1}; !slices.Equal(got, want) {
+		t.Errorf("knuthGaps(1000) = %v, want %v", got, want)
+	}
+}
+
+func TestCheckStability(t *testing.T) {
+	config, err := parseConfig([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stable, err := checkStability(config, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		"bubble": true, "radix": true, "radix-reuse": true, "shift-radix": true,
+		"merge": true, "merge-alloc": true, "merge-reuse": true, "timsort": true,
+		"bucket": true, "insertion": true,
+		"msd-radix": false, "quick": false, "branchless-quick": false, "pdq": false,
+		"parallel-quick": false, "heap": false, "shell": false, "selection": false,
+		"sort-slice": false, "slices-sortfunc": false,
+	}
+	if !maps.Equal(stable, want) {
+		t.Errorf("checkStability = %v, want %v", stable, want)
+	}
+}
+
+func TestQuickSortKiller(t *testing.T) {
+	const n = 2000
+	comparisons := func(data []int) int {
+		count := 0
+		quickSortFunc(slices.Clone(data), func(a, b int) int {
+			count++
+			return a - b
+		})
+		return count
+	}
+
+	killer := generateQuickSortKiller(nil, DatasetConfig{Size: n})
+	for i, v := range slices.Sorted(slices.Values(killer)) {
+		if v != i {
+			t.Fatalf("killer input is not a permutation of 0..%d", n)
+		}
+	}
+	if got := comparisons(killer); got < n*n/8 {
+		t.Fatalf("killer input took %d comparisons, want at least %d", got, n*n/8)
+	}
+
+	data := slices.Clone(killer)
+	quickSort(data)
+	if !slices.IsSorted(data) {
+		t.Fatal("quickSort failed on killer input")
+	}
+}
+
+func TestEstimateDuration(t *testing.T) {
+	calibration := &bench.Results{Runs: []bench.ResultsRun{
+		{Size: 1000, Benchmarks: []bench.BenchmarkSummary{{Name: "Quicksort", Median: 1}, {Name: "Bubble sort", Median: 2}}},
+		{Size: 100000, Benchmarks: []bench.BenchmarkSummary{{Name: "Quicksort", Median: 100}}},
+		{Name: "sorted", Size: 1000, Benchmarks: []bench.BenchmarkSummary{{Name: "Quicksort", Median: 50}}},
+	}}
+	opts := bench.Options{Warmup: 1, Iterations: 3}
+	tests := []struct {
+		p    plannedBenchmark
+		want time.Duration
+	}{
+		// Same size: the median for each of the four iterations
+		{plannedBenchmark{key: "quick", name: "Quicksort", size: 1000, opts: opts}, 4 * time.Millisecond},
+		// The nearest size, 100000, scaled by n log n
+		{plannedBenchmark{key: "quick", name: "Quicksort", size: 50000, opts: opts}, time.Duration(4 * 100 * growth("quick", 50000) / growth("quick", 100000) * float64(time.Millisecond))},
+		// Quadratic algorithms scale by n squared
+		{plannedBenchmark{key: "bubble", name: "Bubble sort", size: 2000, opts: opts}, 4 * 8 * time.Millisecond},
+	}
+	for _, test := range tests {
+		got, ok := estimateDuration(calibration, "", test.p)
+		if !ok || got != test.want {
+			t.Errorf("estimateDuration(%s at %d) = %v, %t, want %v", test.p.name, test.p.size, got, ok, test.want)
+		}
+	}
+
+	if _, ok := estimateDuration(calibration, "", plannedBenchmark{key: "heap", name: "Heap sort", size: 1000, opts: opts}); ok {
+		t.Error("estimateDuration estimated a benchmark missing from the calibration")
+	}
+	budget := plannedBenchmark{key: "quick", name: "Quicksort", size: 1000, opts: bench.Options{Iterations: 3, Budget: time.Second}}
+	if got, _ := estimateDuration(calibration, "sorted", budget); got != time.Second {
+		t.Errorf("estimateDuration with a 1s budget = %v, want 1s", got)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	for _, name := range bench.ProfileNames {
+		data, err := bench.ReadProfile(profiles, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := parseConfig(data)
+		if err == nil {
+			err = config.validate()
+		}
+		if err != nil {
+			t.Errorf("profile %s: %v", name, err)
+		}
+	}
+	if _, err := bench.ReadProfile(profiles, "huge"); err == nil {
+		t.Error("ReadProfile read an unknown profile")
+	}
+}
+
+func TestAlgorithmTags(t *testing.T) {
+	tests := []struct {
+		tags, skipTags []string
+		want           []string
+	}{
+		{[]string{"parallel"}, nil, []string{"parallel-quick"}},
+		{[]string{"stdlib", "adaptive"}, nil, []string{"builtin", "sort-ints", "sort-slice", "slices-sortfunc"}},
+		{[]string{"quadratic"}, []string{"adaptive"}, []string{"bubble", "selection"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, key := range algorithmKeys {
+			if algorithmSelected(Config{Tags: test.tags, SkipTags: test.skipTags}, key) {
+				got = append(got, key)
+			}
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("tags %v, skipTags %v selected %v, want %v", test.tags, test.skipTags, got, test.want)
+		}
+	}
+	if algorithmSelected(Config{Tags: []string{"wasm"}}, "external") {
+		t.Error("the wasm tag selected the external sort, which needs temporary files")
+	}
+	if err := (Config{Iterations: 1, Tags: []string{"fast"}}).validate(); err == nil || !strings.Contains(err.Error(), "tags[0]") {
+		t.Errorf("validate accepted an unknown tag: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"jsconf/internal/bench"
+)
+
+// sortedInputSuffix marks the sorted input rerun of a benchmark
+const sortedInputSuffix = " (sorted input)"
+
+// runSortedInputBenchmarks times slices.IsSorted, the check a sort needs to
+// take a fast path, then reruns each int benchmark on sorted input and
+// compares it with the run on the original data
+func runSortedInputBenchmarks(config Config, benchmarks []intBenchmark, sorted []int) {
+	// Shuffling would undo the sorting the reruns are about
+	config.ColdData.Shuffle = false
+	runAlgorithmWithCheck(config, "is-sorted", "slices.IsSorted (sorted input)", sorted, func(data []int) {
+		if !slices.IsSorted(data) {
+			panic("slices.IsSorted reported sorted input as unsorted")
+		}
+	}, func([]int) {})
+
+	for _, b := range benchmarks {
+		runAlgorithm(config, b.key, b.name+sortedInputSuffix, sorted, sorted, b.sortFn)
+	}
+
+	whenRun(func() { printSortedInputComparison(benchmarks) })
+}
+
+// printSortedInputComparison prints how much faster each of benchmarks ran
+// on sorted input than on the original data
+func printSortedInputComparison(benchmarks []intBenchmark) {
+	fmt.Println("\nSorted input vs. original data:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Benchmark\tOriginal (ms)\tSorted (ms)\tSpeedup\tAdaptive\t")
+	for _, b := range benchmarks {
+		original, ok := findResult(b.name)
+		if !ok {
+			continue
+		}
+		rerun, ok := findResult(b.name + sortedInputSuffix)
+		if !ok || original.timedOut || rerun.timedOut || original.failure != nil || rerun.failure != nil {
+			continue
+		}
+		adaptive := ""
+		if original.adaptive {
+			adaptive = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%.3f\t%.3f\t%.1fx\t%s\t\n", b.name,
+			bench.Milliseconds(original.median), bench.Milliseconds(rerun.median),
+			float64(original.median)/float64(rerun.median), adaptive)
+	}
+	w.Flush()
+}
+
+// findResult returns the latest result in the current suite named name
+func findResult(name string) (benchmarkResult, bool) {
+	for i := len(suiteResults) - 1; i >= 0; i-- {
+		if suiteResults[i].name == name {
+			return suiteResults[i], true
+		}
+	}
+	return benchmarkResult{}, false
+}
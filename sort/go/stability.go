@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// taggedKey is a sort key carrying the index it had in the input. Sorting
+// compares Key only, so a stable algorithm must leave equal keys in
+// ascending Index order.
+type taggedKey struct {
+	Key   int
+	Index int
+}
+
+// stability holds whether each algorithm checked by checkStability kept
+// equal keys in input order, and is nil when the check didn't run
+var stability map[string]bool
+
+// stabilityCandidate runs the algorithm timed under key over tagged keys
+type stabilityCandidate struct {
+	key  string
+	sort func(data []taggedKey)
+}
+
+// stabilityCandidates are the algorithms checkStability can run on tagged
+// keys: the copies of the timed sorts in taggedsorts.go, and the standard
+// library's comparison sorts. Each runs with the settings runSuite gives it,
+// and variants that differ from another algorithm only in how they allocate
+// or how many goroutines they use share its copy.
+func stabilityCandidates(config Config) ([]stabilityCandidate, error) {
+	shellGaps, err := shellGapsFor(config.ShellGaps, stabilityInputSize)
+	if err != nil {
+		return nil, err
+	}
+	radixDigitBits := uint(bits.TrailingZeros(uint(config.RadixBase)))
+	compare := func(a, b taggedKey) int { return a.Key - b.Key }
+
+	return []stabilityCandidate{
+		{"bubble", taggedBubbleSort},
+		{"radix", taggedRadixSort},
+		{"radix-reuse", taggedRadixSort},
+		{"shift-radix", func(data []taggedKey) {
+			taggedShiftRadixSort(data, radixDigitBits)
+		}},
+		{"msd-radix", taggedMsdRadixSort},
+		{"quick", taggedQuickSort},
+		{"branchless-quick", taggedBranchlessQuickSort},
+		{"pdq", taggedPdqSort},
+		{"parallel-quick", taggedQuickSort},
+		{"merge", taggedMergeSort},
+		{"merge-alloc", taggedMergeSort},
+		{"merge-reuse", taggedMergeSort},
+		{"heap", taggedHeapSort},
+		{"timsort", taggedTimSort},
+		{"bucket", func(data []taggedKey) {
+			taggedBucketSort(data, config.Buckets)
+		}},
+		{"shell", func(data []taggedKey) {
+			taggedShellSort(data, shellGaps)
+		}},
+		{"insertion", taggedInsertionSort},
+		{"selection", taggedSelectionSort},
+		{"sort-slice", func(data []taggedKey) {
+			sort.Slice(data, func(i, j int) bool { return data[i].Key < data[j].Key })
+		}},
+		{"slices-sortfunc", func(data []taggedKey) {
+			slices.SortFunc(data, compare)
+		}},
+	}, nil
+}
+
+// stabilityInputSize is large enough that algorithms with small-input
+// special cases, up to parallel quicksort's cutoff, take their general path
+const stabilityInputSize = 2 * parallelCutoff
+
+// checkStability sorts keys with many duplicates, drawn from seed, through
+// every selected candidate and returns whether each one kept equal keys in
+// input order, logging each verdict
+func checkStability(config Config, seed int64) (map[string]bool, error) {
+	candidates, err := stabilityCandidates(config)
+	if err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	input := make([]taggedKey, stabilityInputSize)
+	for i := range input {
+		input[i] = taggedKey{Key: rng.Intn(50), Index: i}
+	}
+
+	stable := map[string]bool{}
+	for _, candidate := range candidates {
+		if !algorithmSelected(config, candidate.key) {
+			continue
+		}
+		data := copySlice(input)
+		candidate.sort(data)
+		checkTaggedOrder(candidate.key, data)
+		stable[candidate.key] = isStable(data)
+
+		verdict := "stable"
+		if !stable[candidate.key] {
+			verdict = "unstable"
+		}
+		slog.Info(fmt.Sprintf("Stability of %s: %s", candidate.key, verdict),
+			"algorithm", candidate.key, "stable", stable[candidate.key])
+	}
+	return stable, nil
+}
+
+func checkTaggedOrder(name string, data []taggedKey) {
+	for i := 1; i < len(data); i++ {
+		if data[i-1].Key > data[i].Key {
+			panic(fmt.Sprintf("%s: keys out of order at index %d", name, i))
+		}
+	}
+}
+
+func isStable(data []taggedKey) bool {
+	for i := 1; i < len(data); i++ {
+		if data[i-1].Key == data[i].Key && data[i-1].Index > data[i].Index {
+			return false
+		}
+	}
+	return true
+}
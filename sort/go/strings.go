@@ -0,0 +1,82 @@
+package main
+
+import "math/rand"
+
+// generateStrings returns n lowercase strings of 4 to 16 characters. A
+// quarter of them share one of a handful of prefixes so comparisons regularly
+// have to look past the first few bytes. The same seed gives the same
+// strings.
+func generateStrings(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	prefixes := []string{"http://", "user_", "benchmark", "aaaa"}
+
+	data := make([]string, n)
+	buf := make([]byte, 0, 32)
+	for i := range data {
+		buf = buf[:0]
+		if rng.Intn(4) == 0 {
+			buf = append(buf, prefixes[rng.Intn(len(prefixes))]...)
+		}
+		length := 4 + rng.Intn(13)
+		for j := 0; j < length; j++ {
+			buf = append(buf, byte('a'+rng.Intn(26)))
+		}
+		data[i] = string(buf)
+	}
+	return data
+}
+
+// multikeyQuickSort is Bentley and Sedgewick's three-way radix quicksort. It
+// partitions on a single byte at a time into <, = and > groups and only
+// advances to the next byte within the = group, so shared prefixes are
+// compared once instead of in every string comparison.
+func multikeyQuickSort(data []string) {
+	multikeyQuickSortDepth(data, 0)
+}
+
+// byteAt returns the byte of s at depth, or -1 past the end so shorter
+// strings sort first
+func byteAt(s string, depth int) int {
+	if depth < len(s) {
+		return int(s[depth])
+	}
+	return -1
+}
+
+func multikeyQuickSortDepth(data []string, depth int) {
+	for len(data) > insertionSortCutoff {
+		pivot := byteAt(data[len(data)/2], depth)
+
+		lt, i, gt := 0, 0, len(data)
+		for i < gt {
+			c := byteAt(data[i], depth)
+			if c < pivot {
+				data[lt], data[i] = data[i], data[lt]
+				lt++
+				i++
+			} else if c > pivot {
+				gt--
+				data[i], data[gt] = data[gt], data[i]
+			} else {
+				i++
+			}
+		}
+
+		multikeyQuickSortDepth(data[:lt], depth)
+		if pivot >= 0 {
+			multikeyQuickSortDepth(data[lt:gt], depth+1)
+		}
+		data = data[gt:]
+	}
+
+	// Insertion sort the remainder, skipping the prefix known to be equal
+	for i := 1; i < len(data); i++ {
+		v := data[i]
+		j := i - 1
+		for j >= 0 && data[j][depth:] > v[depth:] {
+			data[j+1] = data[j]
+			j--
+		}
+		data[j+1] = v
+	}
+}
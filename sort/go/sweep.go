@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jsconf/internal/bench"
+)
+
+type benchmarkResult struct {
+	name string
+	// durations are the measured iterations in run order
+	durations []time.Duration
+	// warmup are the warmup iterations before durations, and unsteady is
+	// set when steady-state detection gave up on them settling
+	warmup   []time.Duration
+	unsteady bool
+	// pauses is how many times the benchmark paused to cool down, and
+	// clocksBefore and clocksAfter the CPU clock readings around it
+	pauses       int
+	clocksBefore *bench.ClockSample
+	clocksAfter  *bench.ClockSample
+	// memoryLimit is the soft memory limit it ran under, and limitGCs the
+	// collections the limit forced
+	memoryLimit int64
+	limitGCs    uint32
+	// median and stats are computed from the samples retained under the
+	// outliers policy, of which outliers were detected as outliers, at
+	// outlierIndexes in durations
+	median         time.Duration
+	stats          bench.Stats
+	outliers       int
+	outlierIndexes []int
+	// ci is the bootstrap confidence interval on median
+	ci bench.ConfidenceInterval
+	// batch is the number of runs timed together in each of durations,
+	// which are per run
+	batch int
+	// overhead is the harness's overhead per run, which overheadSubtracted
+	// says was taken off durations
+	overhead           time.Duration
+	overheadSubtracted bool
+	// perf holds the hardware counters for each of durations when
+	// perfCounters is enabled
+	perf []bench.PerfCounts
+	// mem holds the heap activity of each of durations
+	mem []bench.MemDelta
+	// adaptive is set for algorithms in adaptiveKeys
+	adaptive bool
+	// stable is set from the stability check for the algorithms it covers
+	stable *bool
+	// cold is set when the iterations ran with cold data
+	cold bool
+	// timedOut is set when an iteration ran past timeoutSeconds, in which
+	// case durations holds only the iterations before it and median is unset
+	timedOut bool
+	// verifyError is set when the output failed verification, and failure
+	// when it did or the benchmark panicked, in which case durations holds
+	// only the iterations before it and median is unset
+	verifyError string
+	failure     *bench.Failure
+}
+
+// suiteResults collects every benchmark run by the current suite, in run
+// order
+var suiteResults []benchmarkResult
+
+type sizeResults struct {
+	// name is the configured dataset name, if any
+	name    string
+	size    int
+	dataset string
+	results []benchmarkResult
+}
+
+// loadDatasetOfSize loads the configured dataset at the given size, or at its
+// configured size when size is zero
+func loadDatasetOfSize(cfg DatasetConfig, size int) ([]int, string, error) {
+	if size == 0 {
+		return loadDataset(cfg)
+	}
+
+	if cfg.Generator != "" && cfg.Generator != "file" {
+		cfg.Size = size
+		return loadDataset(cfg)
+	}
+
+	data, dataset, err := loadDataset(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if size > len(data) {
+		return nil, "", fmt.Errorf("size %d is larger than the %d elements in the dataset file", size, len(data))
+	}
+	return data[:size], fmt.Sprintf("%s, first %d elements", dataset, size), nil
+}
+
+// printChart prints a bar chart of the medians of one run
+func printChart(results []benchmarkResult, style string) {
+	rows := make([]bench.ChartRow, len(results))
+	for i, r := range results {
+		rows[i] = bench.ChartRow{Name: r.name, Median: r.median, TimedOut: r.timedOut, Failed: r.failure != nil}
+	}
+	fmt.Println("\nMedians:")
+	bench.WriteBarChart(os.Stdout, rows, style)
+}
+
+// sweepSizes returns the sizes the suite runs at, with 0 for the configured
+// dataset's own size when the config has no sizes
+func sweepSizes(config Config) []int {
+	if len(config.Sizes) == 0 {
+		return []int{0}
+	}
+	sizes := make([]int, len(config.Sizes))
+	for i, size := range config.Sizes {
+		sizes[i] = int(size)
+	}
+	return sizes
+}
+
+// sweepDatasets returns the datasets the suite runs on, the single
+// configured dataset when the config has no datasets list, with their seeds
+// filled in so each is generated identically at every size
+func sweepDatasets(config Config) []DatasetConfig {
+	datasets := config.Datasets
+	if len(datasets) == 0 {
+		datasets = []DatasetConfig{config.Dataset}
+	}
+	seeded := make([]DatasetConfig, len(datasets))
+	for i, cfg := range datasets {
+		seeded[i] = seededDataset(config, cfg, i)
+	}
+	return seeded
+}
+
+// printSweep prints the runs of one dataset, with one row per benchmark and one column per size. Names
+// that include size-dependent details are matched by position within the
+// run, so benchmarks skipped at some sizes show as "-".
+func printSweep(sweep []sizeResults) {
+	if sweep[0].name != "" {
+		fmt.Printf("\nSize sweep medians for %s (ms):\n", sweep[0].name)
+	} else {
+		fmt.Println("\nSize sweep medians (ms):")
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	for _, results := range sweep {
+		for _, m := range results.results {
+			if !seen[m.name] {
+				seen[m.name] = true
+				names = append(names, m.name)
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := []string{"Benchmark"}
+	for _, results := range sweep {
+		header = append(header, fmt.Sprint(results.size))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t")+"\t")
+
+	for _, name := range names {
+		row := []string{name}
+		for _, results := range sweep {
+			cell := "-"
+			for _, m := range results.results {
+				if m.name == name {
+					cell = fmt.Sprintf("%.3f", float64(m.median.Nanoseconds())/1000000)
+					if m.timedOut {
+						cell = "timed out"
+					} else if m.failure != nil {
+						cell = "failed"
+					}
+					break
+				}
+			}
+			row = append(row, cell)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t")+"\t")
+	}
+	w.Flush()
+}
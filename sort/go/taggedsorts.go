@@ -0,0 +1,1152 @@
+package main
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// Copies of the timed sorts for taggedKey, which checkStability runs to see
+// whether each algorithm keeps equal keys in input order. Each moves elements
+// exactly as the int version in sort.go, timsort.go, pdqsort.go or
+// branchless.go does, comparing Key where that compares the ints, so a change
+// to one of those sorts needs the same change here.
+
+func taggedBubbleSort(data []taggedKey) {
+	n := len(data)
+	var temp taggedKey
+	for i := 0; i < n; i++ {
+		for j := 0; j < n-i-1; j++ {
+			if data[j].Key > data[j+1].Key {
+				temp = data[j]
+				data[j] = data[j+1]
+				data[j+1] = temp
+			}
+		}
+	}
+}
+
+func taggedRadixSort(data []taggedKey) {
+	if len(data) == 0 {
+		return
+	}
+
+	// Find minimum and maximum value
+	min, max := data[0].Key, data[0].Key
+	for _, v := range data {
+		if v.Key > max {
+			max = v.Key
+		}
+		if v.Key < min {
+			min = v.Key
+		}
+	}
+
+	// Negative values need signed digits, keep the common case unchanged
+	if min < 0 {
+		for exp := 1; max/exp > 0 || min/exp < 0; exp *= 10 {
+			taggedCountingSortBySignedDigit(data, exp)
+		}
+		return
+	}
+
+	// Do counting sort for every digit
+	for exp := 1; max/exp > 0; exp *= 10 {
+		taggedCountingSortByDigit(data, exp)
+	}
+}
+
+func taggedCountingSortBySignedDigit(data []taggedKey, exp int) {
+	taggedCountingSortBySignedDigitInto(data, make([]taggedKey, len(data)), exp)
+}
+
+func taggedCountingSortBySignedDigitInto(data, output []taggedKey, exp int) {
+	n := len(data)
+	var count [19]int
+
+	for i := 0; i < n; i++ {
+		count[(data[i].Key/exp)%10+9]++
+	}
+
+	for i := 1; i < 19; i++ {
+		count[i] += count[i-1]
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		digit := (data[i].Key/exp)%10 + 9
+		output[count[digit]-1] = data[i]
+		count[digit]--
+	}
+
+	copy(data, output)
+}
+
+func taggedCountingSortByDigit(data []taggedKey, exp int) {
+	taggedCountingSortByDigitInto(data, make([]taggedKey, len(data)), exp)
+}
+
+func taggedCountingSortByDigitInto(data, output []taggedKey, exp int) {
+	n := len(data)
+	var count [10]int
+
+	// Store count of occurrences
+	for i := 0; i < n; i++ {
+		count[(data[i].Key/exp)%10]++
+	}
+
+	// Change count[i] to actual position
+	for i := 1; i < 10; i++ {
+		count[i] += count[i-1]
+	}
+
+	// Build output array
+	for i := n - 1; i >= 0; i-- {
+		output[count[(data[i].Key/exp)%10]-1] = data[i]
+		count[(data[i].Key/exp)%10]--
+	}
+
+	// Copy output array to data
+	for i := 0; i < n; i++ {
+		data[i] = output[i]
+	}
+}
+
+func taggedQuickSort(data []taggedKey) {
+	taggedQuickSortRange(data, 0, len(data)-1)
+}
+
+func taggedQuickSortRange(data []taggedKey, lo, hi int) {
+	for hi-lo >= insertionSortCutoff {
+		p := taggedPartition(data, lo, hi)
+
+		// Recurse into the smaller half to bound stack depth
+		if p-lo < hi-p {
+			taggedQuickSortRange(data, lo, p-1)
+			lo = p + 1
+		} else {
+			taggedQuickSortRange(data, p+1, hi)
+			hi = p - 1
+		}
+	}
+	taggedInsertionSortRange(data, lo, hi)
+}
+
+func taggedPartition(data []taggedKey, lo, hi int) int {
+	mid := lo + (hi-lo)/2
+
+	// Order lo, mid, hi so the median ends up at mid
+	if data[mid].Key < data[lo].Key {
+		data[mid], data[lo] = data[lo], data[mid]
+	}
+	if data[hi].Key < data[lo].Key {
+		data[hi], data[lo] = data[lo], data[hi]
+	}
+	if data[hi].Key < data[mid].Key {
+		data[hi], data[mid] = data[mid], data[hi]
+	}
+
+	// Park the pivot next to hi; data[hi] is already >= pivot
+	data[mid], data[hi-1] = data[hi-1], data[mid]
+	pivot := data[hi-1]
+
+	i, j := lo, hi-1
+	for {
+		for i++; data[i].Key < pivot.Key; i++ {
+		}
+		for j--; data[j].Key > pivot.Key; j-- {
+		}
+		if i >= j {
+			break
+		}
+		data[i], data[j] = data[j], data[i]
+	}
+	data[i], data[hi-1] = data[hi-1], data[i]
+	return i
+}
+
+func taggedInsertionSort(data []taggedKey) {
+	taggedInsertionSortRange(data, 0, len(data)-1)
+}
+
+func taggedInsertionSortRange(data []taggedKey, lo, hi int) {
+	for i := lo + 1; i <= hi; i++ {
+		v := data[i]
+		j := i - 1
+		for j >= lo && data[j].Key > v.Key {
+			data[j+1] = data[j]
+			j--
+		}
+		data[j+1] = v
+	}
+}
+
+func taggedMergeSort(data []taggedKey) {
+	// One auxiliary buffer is allocated up front and reused by every merge
+	aux := make([]taggedKey, len(data))
+	taggedMergeSortRange(data, aux, 0, len(data))
+}
+
+func taggedMergeSortRange(data, aux []taggedKey, lo, hi int) {
+	if hi-lo < 2 {
+		return
+	}
+	mid := lo + (hi-lo)/2
+	taggedMergeSortRange(data, aux, lo, mid)
+	taggedMergeSortRange(data, aux, mid, hi)
+	taggedMerge(data, aux, lo, mid, hi)
+}
+
+func taggedMerge(data, aux []taggedKey, lo, mid, hi int) {
+	copy(aux[lo:hi], data[lo:hi])
+
+	i, j := lo, mid
+	for k := lo; k < hi; k++ {
+		// Take from the left run on ties to keep the sort stable
+		if j >= hi || (i < mid && aux[i].Key <= aux[j].Key) {
+			data[k] = aux[i]
+			i++
+		} else {
+			data[k] = aux[j]
+			j++
+		}
+	}
+}
+
+func taggedHeapSort(data []taggedKey) {
+	n := len(data)
+
+	// Build a max-heap
+	for i := n/2 - 1; i >= 0; i-- {
+		taggedSiftDown(data, i, n)
+	}
+
+	// Repeatedly move the max to the end and restore the heap
+	for end := n - 1; end > 0; end-- {
+		data[0], data[end] = data[end], data[0]
+		taggedSiftDown(data, 0, end)
+	}
+}
+
+func taggedSiftDown(data []taggedKey, root, n int) {
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && data[child+1].Key > data[child].Key {
+			child++
+		}
+		if data[root].Key >= data[child].Key {
+			return
+		}
+		data[root], data[child] = data[child], data[root]
+		root = child
+	}
+}
+
+func taggedSelectionSort(data []taggedKey) {
+	n := len(data)
+	for i := 0; i < n-1; i++ {
+		minIndex := i
+		for j := i + 1; j < n; j++ {
+			if data[j].Key < data[minIndex].Key {
+				minIndex = j
+			}
+		}
+		data[i], data[minIndex] = data[minIndex], data[i]
+	}
+}
+
+func taggedShellSort(data []taggedKey, gaps []int) {
+	n := len(data)
+	for _, gap := range gaps {
+		// Gapped insertion sort
+		for i := gap; i < n; i++ {
+			v := data[i]
+			j := i
+			for j >= gap && data[j-gap].Key > v.Key {
+				data[j] = data[j-gap]
+				j -= gap
+			}
+			data[j] = v
+		}
+	}
+}
+
+func taggedBucketSort(data []taggedKey, bucketCount int) {
+	if len(data) < 2 {
+		return
+	}
+
+	lo, hi := data[0].Key, data[0].Key
+	for _, v := range data {
+		lo = min(lo, v.Key)
+		hi = max(hi, v.Key)
+	}
+	width := (hi-lo)/bucketCount + 1
+
+	buckets := make([][]taggedKey, bucketCount)
+	for _, v := range data {
+		b := (v.Key - lo) / width
+		buckets[b] = append(buckets[b], v)
+	}
+
+	i := 0
+	for _, bucket := range buckets {
+		taggedInsertionSort(bucket)
+		i += copy(data[i:], bucket)
+	}
+}
+
+func taggedShiftRadixSort(data []taggedKey, digitBits uint) {
+	if len(data) < 2 {
+		return
+	}
+
+	const signBit = 1 << 63
+	first := uint64(data[0].Key) ^ signBit
+	var diff uint64
+	for _, v := range data {
+		diff |= (uint64(v.Key) ^ signBit) ^ first
+	}
+
+	radix := 1 << digitBits
+	mask := uint64(radix - 1)
+	count := make([]int, radix)
+	src, dst := data, make([]taggedKey, len(data))
+
+	for shift := uint(0); shift < uint(bits.Len64(diff)); shift += digitBits {
+		clear(count)
+		for _, v := range src {
+			count[(uint64(v.Key)^signBit)>>shift&mask]++
+		}
+
+		// Turn counts into starting offsets
+		offset := 0
+		for i, c := range count {
+			count[i] = offset
+			offset += c
+		}
+
+		for _, v := range src {
+			digit := (uint64(v.Key) ^ signBit) >> shift & mask
+			dst[count[digit]] = v
+			count[digit]++
+		}
+		src, dst = dst, src
+	}
+
+	// After an odd number of passes the result is in the scratch buffer
+	if &src[0] != &data[0] {
+		copy(data, src)
+	}
+}
+
+func taggedMsdRadixSort(data []taggedKey) {
+	if len(data) < 2 {
+		return
+	}
+
+	const signBit = 1 << 63
+	first := uint64(data[0].Key) ^ signBit
+	var diff uint64
+	for _, v := range data {
+		diff |= (uint64(v.Key) ^ signBit) ^ first
+	}
+	if diff == 0 {
+		return
+	}
+
+	// Start at the highest byte in which any two values differ
+	taggedMsdRadixSortRange(data, uint(bits.Len64(diff)-1)/8*8)
+}
+
+func taggedMsdRadixSortRange(data []taggedKey, shift uint) {
+	if len(data) <= insertionSortCutoff {
+		taggedInsertionSort(data)
+		return
+	}
+
+	const signBit = 1 << 63
+	digit := func(v taggedKey) int {
+		return int((uint64(v.Key) ^ signBit) >> shift & 0xff)
+	}
+
+	var count, next, end [256]int
+	for _, v := range data {
+		count[digit(v)]++
+	}
+	offset := 0
+	for b := range count {
+		next[b] = offset
+		offset += count[b]
+		end[b] = offset
+	}
+
+	// Cycle each misplaced element into the next free slot of its bucket
+	for b := range count {
+		for next[b] < end[b] {
+			v := data[next[b]]
+			for d := digit(v); d != b; d = digit(v) {
+				data[next[d]], v = v, data[next[d]]
+				next[d]++
+			}
+			data[next[b]] = v
+			next[b]++
+		}
+	}
+
+	if shift == 0 {
+		return
+	}
+	start := 0
+	for b := range count {
+		if count[b] > 1 {
+			taggedMsdRadixSortRange(data[start:end[b]], shift-8)
+		}
+		start = end[b]
+	}
+}
+
+type taggedTimSorter struct {
+	data      []taggedKey
+	minGallop int
+	tmp       []taggedKey
+
+	// Stack of pending runs yet to be merged
+	runBase []int
+	runLen  []int
+}
+
+func taggedTimSort(data []taggedKey) {
+	n := len(data)
+	if n < 2 {
+		return
+	}
+
+	// Small arrays are sorted without merging
+	if n < timsortMinMerge {
+		initRunLen := taggedCountRunAndMakeAscending(data, 0, n)
+		taggedBinaryInsertionSort(data, 0, n, initRunLen)
+		return
+	}
+
+	ts := &taggedTimSorter{data: data, minGallop: timsortMinGallop}
+	minRun := minRunLength(n)
+	lo, remaining := 0, n
+	for remaining != 0 {
+		runLen := taggedCountRunAndMakeAscending(data, lo, lo+remaining)
+
+		// Extend short runs to minRun with binary insertion sort
+		if runLen < minRun {
+			force := min(remaining, minRun)
+			taggedBinaryInsertionSort(data, lo, lo+force, lo+runLen)
+			runLen = force
+		}
+
+		ts.pushRun(lo, runLen)
+		ts.mergeCollapse()
+
+		lo += runLen
+		remaining -= runLen
+	}
+	ts.mergeForceCollapse()
+}
+
+func taggedBinaryInsertionSort(data []taggedKey, lo, hi, start int) {
+	if start == lo {
+		start++
+	}
+	for ; start < hi; start++ {
+		pivot := data[start]
+
+		left, right := lo, start
+		for left < right {
+			mid := int(uint(left+right) >> 1)
+			if pivot.Key < data[mid].Key {
+				right = mid
+			} else {
+				left = mid + 1
+			}
+		}
+
+		copy(data[left+1:start+1], data[left:start])
+		data[left] = pivot
+	}
+}
+
+func taggedCountRunAndMakeAscending(data []taggedKey, lo, hi int) int {
+	runHi := lo + 1
+	if runHi == hi {
+		return 1
+	}
+
+	if data[runHi].Key < data[lo].Key {
+		runHi++
+		for runHi < hi && data[runHi].Key < data[runHi-1].Key {
+			runHi++
+		}
+		taggedReverseRange(data, lo, runHi)
+	} else {
+		runHi++
+		for runHi < hi && data[runHi].Key >= data[runHi-1].Key {
+			runHi++
+		}
+	}
+
+	return runHi - lo
+}
+
+func taggedReverseRange(data []taggedKey, lo, hi int) {
+	for hi--; lo < hi; lo, hi = lo+1, hi-1 {
+		data[lo], data[hi] = data[hi], data[lo]
+	}
+}
+
+func (ts *taggedTimSorter) pushRun(base, length int) {
+	ts.runBase = append(ts.runBase, base)
+	ts.runLen = append(ts.runLen, length)
+}
+
+func (ts *taggedTimSorter) mergeCollapse() {
+	for len(ts.runLen) > 1 {
+		runLen := ts.runLen
+		n := len(runLen) - 2
+		if n > 0 && runLen[n-1] <= runLen[n]+runLen[n+1] ||
+			n > 1 && runLen[n-2] <= runLen[n]+runLen[n-1] {
+			if runLen[n-1] < runLen[n+1] {
+				n--
+			}
+		} else if runLen[n] > runLen[n+1] {
+			break
+		}
+		ts.mergeAt(n)
+	}
+}
+
+func (ts *taggedTimSorter) mergeForceCollapse() {
+	for len(ts.runLen) > 1 {
+		n := len(ts.runLen) - 2
+		if n > 0 && ts.runLen[n-1] < ts.runLen[n+1] {
+			n--
+		}
+		ts.mergeAt(n)
+	}
+}
+
+func (ts *taggedTimSorter) mergeAt(i int) {
+	data := ts.data
+	base1, len1 := ts.runBase[i], ts.runLen[i]
+	base2, len2 := ts.runBase[i+1], ts.runLen[i+1]
+
+	ts.runLen[i] = len1 + len2
+	if i == len(ts.runLen)-3 {
+		ts.runBase[i+1] = ts.runBase[i+2]
+		ts.runLen[i+1] = ts.runLen[i+2]
+	}
+	ts.runBase = ts.runBase[:len(ts.runBase)-1]
+	ts.runLen = ts.runLen[:len(ts.runLen)-1]
+
+	// Elements of run1 that are already in place can be ignored
+	k := taggedGallopRight(data[base2], data, base1, len1, 0)
+	base1 += k
+	len1 -= k
+	if len1 == 0 {
+		return
+	}
+
+	// Elements of run2 that are already in place can be ignored
+	len2 = taggedGallopLeft(data[base1+len1-1], data, base2, len2, len2-1)
+	if len2 == 0 {
+		return
+	}
+
+	if len1 <= len2 {
+		ts.mergeLo(base1, len1, base2, len2)
+	} else {
+		ts.mergeHi(base1, len1, base2, len2)
+	}
+}
+
+func taggedGallopLeft(key taggedKey, data []taggedKey, base, length, hint int) int {
+	lastOfs, ofs := 0, 1
+	if key.Key > data[base+hint].Key {
+		// Gallop right until data[base+hint+lastOfs] < key <= data[base+hint+ofs]
+		maxOfs := length - hint
+		for ofs < maxOfs && key.Key > data[base+hint+ofs].Key {
+			lastOfs = ofs
+			ofs = ofs<<1 + 1
+		}
+		ofs = min(ofs, maxOfs)
+		lastOfs += hint
+		ofs += hint
+	} else {
+		// Gallop left until data[base+hint-ofs] < key <= data[base+hint-lastOfs]
+		maxOfs := hint + 1
+		for ofs < maxOfs && key.Key <= data[base+hint-ofs].Key {
+			lastOfs = ofs
+			ofs = ofs<<1 + 1
+		}
+		ofs = min(ofs, maxOfs)
+		lastOfs, ofs = hint-ofs, hint-lastOfs
+	}
+
+	// Binary search within data[base+lastOfs+1:base+ofs]
+	lastOfs++
+	for lastOfs < ofs {
+		m := lastOfs + (ofs-lastOfs)>>1
+		if key.Key > data[base+m].Key {
+			lastOfs = m + 1
+		} else {
+			ofs = m
+		}
+	}
+	return ofs
+}
+
+func taggedGallopRight(key taggedKey, data []taggedKey, base, length, hint int) int {
+	lastOfs, ofs := 0, 1
+	if key.Key < data[base+hint].Key {
+		maxOfs := hint + 1
+		for ofs < maxOfs && key.Key < data[base+hint-ofs].Key {
+			lastOfs = ofs
+			ofs = ofs<<1 + 1
+		}
+		ofs = min(ofs, maxOfs)
+		lastOfs, ofs = hint-ofs, hint-lastOfs
+	} else {
+		maxOfs := length - hint
+		for ofs < maxOfs && key.Key >= data[base+hint+ofs].Key {
+			lastOfs = ofs
+			ofs = ofs<<1 + 1
+		}
+		ofs = min(ofs, maxOfs)
+		lastOfs += hint
+		ofs += hint
+	}
+
+	lastOfs++
+	for lastOfs < ofs {
+		m := lastOfs + (ofs-lastOfs)>>1
+		if key.Key < data[base+m].Key {
+			ofs = m
+		} else {
+			lastOfs = m + 1
+		}
+	}
+	return ofs
+}
+
+func (ts *taggedTimSorter) ensureCapacity(n int) []taggedKey {
+	if cap(ts.tmp) < n {
+		ts.tmp = make([]taggedKey, max(n, min(2*cap(ts.tmp), len(ts.data)/2)))
+	}
+	return ts.tmp[:n]
+}
+
+func (ts *taggedTimSorter) mergeLo(base1, len1, base2, len2 int) {
+	data := ts.data
+	tmp := ts.ensureCapacity(len1)
+	copy(tmp, data[base1:base1+len1])
+
+	cursor1, cursor2, dest := 0, base2, base1
+
+	data[dest] = data[cursor2]
+	dest++
+	cursor2++
+	len2--
+	if len2 == 0 {
+		copy(data[dest:dest+len1], tmp[cursor1:cursor1+len1])
+		return
+	}
+	if len1 == 1 {
+		copy(data[dest:dest+len2], data[cursor2:cursor2+len2])
+		data[dest+len2] = tmp[cursor1]
+		return
+	}
+
+	minGallop := ts.minGallop
+outer:
+	for {
+		count1, count2 := 0, 0
+
+		// Merge one element at a time until one run starts winning consistently
+		for {
+			if data[cursor2].Key < tmp[cursor1].Key {
+				data[dest] = data[cursor2]
+				dest++
+				cursor2++
+				count2++
+				count1 = 0
+				len2--
+				if len2 == 0 {
+					break outer
+				}
+			} else {
+				data[dest] = tmp[cursor1]
+				dest++
+				cursor1++
+				count1++
+				count2 = 0
+				len1--
+				if len1 == 1 {
+					break outer
+				}
+			}
+			if count1|count2 >= minGallop {
+				break
+			}
+		}
+
+		// Gallop until neither run is winning consistently anymore
+		for {
+			count1 = taggedGallopRight(data[cursor2], tmp, cursor1, len1, 0)
+			if count1 != 0 {
+				copy(data[dest:dest+count1], tmp[cursor1:cursor1+count1])
+				dest += count1
+				cursor1 += count1
+				len1 -= count1
+				if len1 <= 1 {
+					break outer
+				}
+			}
+			data[dest] = data[cursor2]
+			dest++
+			cursor2++
+			len2--
+			if len2 == 0 {
+				break outer
+			}
+
+			count2 = taggedGallopLeft(tmp[cursor1], data, cursor2, len2, 0)
+			if count2 != 0 {
+				copy(data[dest:dest+count2], data[cursor2:cursor2+count2])
+				dest += count2
+				cursor2 += count2
+				len2 -= count2
+				if len2 == 0 {
+					break outer
+				}
+			}
+			data[dest] = tmp[cursor1]
+			dest++
+			cursor1++
+			len1--
+			if len1 == 1 {
+				break outer
+			}
+
+			minGallop--
+			if count1 < timsortMinGallop && count2 < timsortMinGallop {
+				break
+			}
+		}
+
+		// Penalize leaving galloping mode
+		minGallop = max(minGallop, 0) + 2
+	}
+	ts.minGallop = max(minGallop, 1)
+
+	if len1 == 1 {
+		copy(data[dest:dest+len2], data[cursor2:cursor2+len2])
+		data[dest+len2] = tmp[cursor1]
+	} else if len1 == 0 {
+		panic("timsort: comparison violates its general contract")
+	} else {
+		copy(data[dest:dest+len1], tmp[cursor1:cursor1+len1])
+	}
+}
+
+func (ts *taggedTimSorter) mergeHi(base1, len1, base2, len2 int) {
+	data := ts.data
+	tmp := ts.ensureCapacity(len2)
+	copy(tmp, data[base2:base2+len2])
+
+	cursor1, cursor2, dest := base1+len1-1, len2-1, base2+len2-1
+
+	data[dest] = data[cursor1]
+	dest--
+	cursor1--
+	len1--
+	if len1 == 0 {
+		copy(data[dest-(len2-1):dest+1], tmp[:len2])
+		return
+	}
+	if len2 == 1 {
+		dest -= len1
+		cursor1 -= len1
+		copy(data[dest+1:dest+1+len1], data[cursor1+1:cursor1+1+len1])
+		data[dest] = tmp[cursor2]
+		return
+	}
+
+	minGallop := ts.minGallop
+outer:
+	for {
+		count1, count2 := 0, 0
+
+		for {
+			if tmp[cursor2].Key < data[cursor1].Key {
+				data[dest] = data[cursor1]
+				dest--
+				cursor1--
+				count1++
+				count2 = 0
+				len1--
+				if len1 == 0 {
+					break outer
+				}
+			} else {
+				data[dest] = tmp[cursor2]
+				dest--
+				cursor2--
+				count2++
+				count1 = 0
+				len2--
+				if len2 == 1 {
+					break outer
+				}
+			}
+			if count1|count2 >= minGallop {
+				break
+			}
+		}
+
+		for {
+			count1 = len1 - taggedGallopRight(tmp[cursor2], data, base1, len1, len1-1)
+			if count1 != 0 {
+				dest -= count1
+				cursor1 -= count1
+				len1 -= count1
+				copy(data[dest+1:dest+1+count1], data[cursor1+1:cursor1+1+count1])
+				if len1 == 0 {
+					break outer
+				}
+			}
+			data[dest] = tmp[cursor2]
+			dest--
+			cursor2--
+			len2--
+			if len2 == 1 {
+				break outer
+			}
+
+			count2 = len2 - taggedGallopLeft(data[cursor1], tmp, 0, len2, len2-1)
+			if count2 != 0 {
+				dest -= count2
+				cursor2 -= count2
+				len2 -= count2
+				copy(data[dest+1:dest+1+count2], tmp[cursor2+1:cursor2+1+count2])
+				if len2 <= 1 {
+					break outer
+				}
+			}
+			data[dest] = data[cursor1]
+			dest--
+			cursor1--
+			len1--
+			if len1 == 0 {
+				break outer
+			}
+
+			minGallop--
+			if count1 < timsortMinGallop && count2 < timsortMinGallop {
+				break
+			}
+		}
+
+		minGallop = max(minGallop, 0) + 2
+	}
+	ts.minGallop = max(minGallop, 1)
+
+	if len2 == 1 {
+		dest -= len1
+		cursor1 -= len1
+		copy(data[dest+1:dest+1+len1], data[cursor1+1:cursor1+1+len1])
+		data[dest] = tmp[cursor2]
+	} else if len2 == 0 {
+		panic("timsort: comparison violates its general contract")
+	} else {
+		copy(data[dest-(len2-1):dest+1], tmp[:len2])
+	}
+}
+
+func taggedPdqSort(data []taggedKey) {
+	limit := bits.Len(uint(len(data)))
+	taggedPdqSortRange(data, 0, len(data), limit)
+}
+
+func taggedPdqSortRange(data []taggedKey, a, b, limit int) {
+	wasBalanced := true
+	wasPartitioned := true
+
+	for {
+		length := b - a
+		if length <= pdqInsertionSortCutoff {
+			taggedInsertionSort(data[a:b])
+			return
+		}
+
+		// Too many bad pivots, fall back to heap sort
+		if limit == 0 {
+			taggedHeapSort(data[a:b])
+			return
+		}
+
+		// The last partition was unbalanced, so shuffle to break the pattern
+		// that caused it
+		if !wasBalanced {
+			taggedBreakPatterns(data, a, b)
+			limit--
+		}
+
+		pivot, hint := taggedChoosePivot(data, a, b)
+		if hint == decreasingHint {
+			slices.Reverse(data[a:b])
+			pivot = (b - 1) - (pivot - a)
+			hint = increasingHint
+		}
+
+		// Likely already sorted, try to finish with a few insertions
+		if wasBalanced && wasPartitioned && hint == increasingHint {
+			if taggedPartialInsertionSort(data, a, b) {
+				return
+			}
+		}
+
+		// The element before the range is an earlier pivot no greater than
+		// anything in it. If it equals this pivot, everything equal to the
+		// pivot is already in place once grouped at the front.
+		if a > 0 && data[a-1].Key >= data[pivot].Key {
+			a = taggedPartitionEqual(data, a, b, pivot)
+			continue
+		}
+
+		mid, alreadyPartitioned := taggedPdqPartition(data, a, b, pivot)
+		wasPartitioned = alreadyPartitioned
+
+		// Recurse into the smaller side and loop on the larger one
+		leftLen, rightLen := mid-a, b-mid
+		balanceThreshold := length / 8
+		if leftLen < rightLen {
+			wasBalanced = leftLen >= balanceThreshold
+			taggedPdqSortRange(data, a, mid, limit)
+			a = mid + 1
+		} else {
+			wasBalanced = rightLen >= balanceThreshold
+			taggedPdqSortRange(data, mid+1, b, limit)
+			b = mid
+		}
+	}
+}
+
+func taggedPdqPartition(data []taggedKey, a, b, pivot int) (int, bool) {
+	data[a], data[pivot] = data[pivot], data[a]
+	i, j := a+1, b-1
+
+	for i <= j && data[i].Key < data[a].Key {
+		i++
+	}
+	for i <= j && data[j].Key >= data[a].Key {
+		j--
+	}
+	if i > j {
+		data[j], data[a] = data[a], data[j]
+		return j, true
+	}
+	data[i], data[j] = data[j], data[i]
+	i++
+	j--
+
+	for {
+		for i <= j && data[i].Key < data[a].Key {
+			i++
+		}
+		for i <= j && data[j].Key >= data[a].Key {
+			j--
+		}
+		if i > j {
+			break
+		}
+		data[i], data[j] = data[j], data[i]
+		i++
+		j--
+	}
+	data[j], data[a] = data[a], data[j]
+	return j, false
+}
+
+func taggedPartitionEqual(data []taggedKey, a, b, pivot int) int {
+	data[a], data[pivot] = data[pivot], data[a]
+	i, j := a+1, b-1
+
+	for {
+		for i <= j && data[i].Key <= data[a].Key {
+			i++
+		}
+		for i <= j && data[j].Key > data[a].Key {
+			j--
+		}
+		if i > j {
+			break
+		}
+		data[i], data[j] = data[j], data[i]
+		i++
+		j--
+	}
+	return i
+}
+
+func taggedPartialInsertionSort(data []taggedKey, a, b int) bool {
+	const (
+		maxSteps = 5
+		// Shifting isn't worth it below this length, where a normal
+		// partition is cheap
+		shortestShifting = 50
+	)
+
+	i := a + 1
+	for step := 0; step < maxSteps; step++ {
+		for i < b && data[i].Key >= data[i-1].Key {
+			i++
+		}
+		if i == b {
+			return true
+		}
+		if b-a < shortestShifting {
+			return false
+		}
+
+		data[i], data[i-1] = data[i-1], data[i]
+
+		// Shift the smaller element left and the larger one right
+		for j := i - 1; j > a && data[j].Key < data[j-1].Key; j-- {
+			data[j], data[j-1] = data[j-1], data[j]
+		}
+		for j := i + 1; j < b && data[j].Key < data[j-1].Key; j++ {
+			data[j], data[j-1] = data[j-1], data[j]
+		}
+	}
+	return false
+}
+
+func taggedBreakPatterns(data []taggedKey, a, b int) {
+	length := b - a
+	if length < 8 {
+		return
+	}
+
+	random := uint64(length)
+	mask := uint(1)<<bits.Len(uint(length)) - 1
+	idx := a + (length/4)*2 - 1
+	for i := 0; i < 3; i++ {
+		// xorshift64
+		random ^= random << 13
+		random ^= random >> 7
+		random ^= random << 17
+
+		other := int(uint(random) & mask)
+		if other >= length {
+			other -= length
+		}
+		data[idx-1+i], data[a+other] = data[a+other], data[idx-1+i]
+	}
+}
+
+func taggedChoosePivot(data []taggedKey, a, b int) (int, sortedHint) {
+	const (
+		shortestNinther = 50
+		maxSwaps        = 4 * 3
+	)
+
+	length := b - a
+	swaps := 0
+	i := a + length/4*1
+	j := a + length/4*2
+	k := a + length/4*3
+
+	if length >= 8 {
+		if length >= shortestNinther {
+			i = taggedMedianAdjacent(data, i, &swaps)
+			j = taggedMedianAdjacent(data, j, &swaps)
+			k = taggedMedianAdjacent(data, k, &swaps)
+		}
+		j = taggedMedianOfThree(data, i, j, k, &swaps)
+	}
+
+	switch swaps {
+	case 0:
+		return j, increasingHint
+	case maxSwaps:
+		return j, decreasingHint
+	default:
+		return j, unknownHint
+	}
+}
+
+func taggedOrder2(data []taggedKey, a, b int, swaps *int) (int, int) {
+	if data[b].Key < data[a].Key {
+		*swaps++
+		return b, a
+	}
+	return a, b
+}
+
+func taggedMedianOfThree(data []taggedKey, a, b, c int, swaps *int) int {
+	a, b = taggedOrder2(data, a, b, swaps)
+	b, c = taggedOrder2(data, b, c, swaps)
+	_, b = taggedOrder2(data, a, b, swaps)
+	return b
+}
+
+func taggedMedianAdjacent(data []taggedKey, a int, swaps *int) int {
+	return taggedMedianOfThree(data, a-1, a, a+1, swaps)
+}
+
+func taggedBranchlessQuickSort(data []taggedKey) {
+	taggedBranchlessQuickSortRange(data, 0, len(data)-1)
+}
+
+func taggedBranchlessQuickSortRange(data []taggedKey, lo, hi int) {
+	for hi-lo >= insertionSortCutoff {
+		p := taggedBranchlessPartition(data, lo, hi)
+
+		// Recurse into the smaller half to bound stack depth
+		if p-lo < hi-p {
+			taggedBranchlessQuickSortRange(data, lo, p-1)
+			lo = p + 1
+		} else {
+			taggedBranchlessQuickSortRange(data, p+1, hi)
+			hi = p - 1
+		}
+	}
+	taggedInsertionSortRange(data, lo, hi)
+}
+
+func taggedBranchlessPartition(data []taggedKey, lo, hi int) int {
+	mid := lo + (hi-lo)/2
+
+	// Order lo, mid, hi so the median ends up at mid, then park it at hi
+	if data[mid].Key < data[lo].Key {
+		data[mid], data[lo] = data[lo], data[mid]
+	}
+	if data[hi].Key < data[lo].Key {
+		data[hi], data[lo] = data[lo], data[hi]
+	}
+	if data[hi].Key < data[mid].Key {
+		data[hi], data[mid] = data[mid], data[hi]
+	}
+	data[mid], data[hi] = data[hi], data[mid]
+	pivot := data[hi]
+
+	// Every element is swapped with data[i], and i only advances past
+	// elements smaller than the pivot
+	i := lo
+	for j := lo; j < hi; j++ {
+		v := data[j]
+		data[j] = data[i]
+		data[i] = v
+		i += lessAsInt(v.Key, pivot.Key)
+	}
+	data[i], data[hi] = data[hi], data[i]
+	return i
+}
@@ -0,0 +1,508 @@
+package main
+
+// Timsort, ported from the run-detecting, galloping-merge algorithm used by
+// CPython's list.sort and Java's Arrays.sort for objects, which is also what
+// V8 uses for Array.prototype.sort. Includes the 2015 fix to mergeCollapse
+// that keeps the run-length invariant on the top three runs.
+
+const (
+	// timsortMinMerge is the smallest array that gets merged; anything
+	// shorter is sorted with a single binary insertion sort
+	timsortMinMerge = 32
+
+	// timsortMinGallop is the initial threshold for entering galloping mode
+	timsortMinGallop = 7
+)
+
+type timSorter struct {
+	data      []int
+	minGallop int
+	tmp       []int
+
+	// Stack of pending runs yet to be merged
+	runBase []int
+	runLen  []int
+}
+
+func timSort(data []int) {
+	n := len(data)
+	if n < 2 {
+		return
+	}
+
+	// Small arrays are sorted without merging
+	if n < timsortMinMerge {
+		initRunLen := countRunAndMakeAscending(data, 0, n)
+		binaryInsertionSort(data, 0, n, initRunLen)
+		return
+	}
+
+	ts := &timSorter{data: data, minGallop: timsortMinGallop}
+	minRun := minRunLength(n)
+	lo, remaining := 0, n
+	for remaining != 0 {
+		runLen := countRunAndMakeAscending(data, lo, lo+remaining)
+
+		// Extend short runs to minRun with binary insertion sort
+		if runLen < minRun {
+			force := min(remaining, minRun)
+			binaryInsertionSort(data, lo, lo+force, lo+runLen)
+			runLen = force
+		}
+
+		ts.pushRun(lo, runLen)
+		ts.mergeCollapse()
+
+		lo += runLen
+		remaining -= runLen
+	}
+	ts.mergeForceCollapse()
+}
+
+// minRunLength returns the minimum run length for an array of length n, such
+// that n/minRun is a power of two or slightly less than one
+func minRunLength(n int) int {
+	r := 0
+	for n >= timsortMinMerge {
+		r |= n & 1
+		n >>= 1
+	}
+	return n + r
+}
+
+// binaryInsertionSort sorts data[lo:hi] given that data[lo:start] is already
+// sorted. Equal elements are inserted after existing ones to stay stable.
+func binaryInsertionSort(data []int, lo, hi, start int) {
+	if start == lo {
+		start++
+	}
+	for ; start < hi; start++ {
+		pivot := data[start]
+
+		left, right := lo, start
+		for left < right {
+			mid := int(uint(left+right) >> 1)
+			if pivot < data[mid] {
+				right = mid
+			} else {
+				left = mid + 1
+			}
+		}
+
+		copy(data[left+1:start+1], data[left:start])
+		data[left] = pivot
+	}
+}
+
+// countRunAndMakeAscending returns the length of the run starting at lo,
+// reversing it in place if it is strictly descending
+func countRunAndMakeAscending(data []int, lo, hi int) int {
+	runHi := lo + 1
+	if runHi == hi {
+		return 1
+	}
+
+	if data[runHi] < data[lo] {
+		runHi++
+		for runHi < hi && data[runHi] < data[runHi-1] {
+			runHi++
+		}
+		reverseRange(data, lo, runHi)
+	} else {
+		runHi++
+		for runHi < hi && data[runHi] >= data[runHi-1] {
+			runHi++
+		}
+	}
+
+	return runHi - lo
+}
+
+func reverseRange(data []int, lo, hi int) {
+	for hi--; lo < hi; lo, hi = lo+1, hi-1 {
+		data[lo], data[hi] = data[hi], data[lo]
+	}
+}
+
+func (ts *timSorter) pushRun(base, length int) {
+	ts.runBase = append(ts.runBase, base)
+	ts.runLen = append(ts.runLen, length)
+}
+
+// mergeCollapse merges adjacent runs until the stack invariants hold:
+//
+//	runLen[i-3] > runLen[i-2] + runLen[i-1]
+//	runLen[i-2] > runLen[i-1]
+func (ts *timSorter) mergeCollapse() {
+	for len(ts.runLen) > 1 {
+		runLen := ts.runLen
+		n := len(runLen) - 2
+		if n > 0 && runLen[n-1] <= runLen[n]+runLen[n+1] ||
+			n > 1 && runLen[n-2] <= runLen[n]+runLen[n-1] {
+			if runLen[n-1] < runLen[n+1] {
+				n--
+			}
+		} else if runLen[n] > runLen[n+1] {
+			break
+		}
+		ts.mergeAt(n)
+	}
+}
+
+// mergeForceCollapse merges all remaining runs into one
+func (ts *timSorter) mergeForceCollapse() {
+	for len(ts.runLen) > 1 {
+		n := len(ts.runLen) - 2
+		if n > 0 && ts.runLen[n-1] < ts.runLen[n+1] {
+			n--
+		}
+		ts.mergeAt(n)
+	}
+}
+
+// mergeAt merges the runs at stack indices i and i+1
+func (ts *timSorter) mergeAt(i int) {
+	data := ts.data
+	base1, len1 := ts.runBase[i], ts.runLen[i]
+	base2, len2 := ts.runBase[i+1], ts.runLen[i+1]
+
+	ts.runLen[i] = len1 + len2
+	if i == len(ts.runLen)-3 {
+		ts.runBase[i+1] = ts.runBase[i+2]
+		ts.runLen[i+1] = ts.runLen[i+2]
+	}
+	ts.runBase = ts.runBase[:len(ts.runBase)-1]
+	ts.runLen = ts.runLen[:len(ts.runLen)-1]
+
+	// Elements of run1 that are already in place can be ignored
+	k := gallopRight(data[base2], data, base1, len1, 0)
+	base1 += k
+	len1 -= k
+	if len1 == 0 {
+		return
+	}
+
+	// Elements of run2 that are already in place can be ignored
+	len2 = gallopLeft(data[base1+len1-1], data, base2, len2, len2-1)
+	if len2 == 0 {
+		return
+	}
+
+	if len1 <= len2 {
+		ts.mergeLo(base1, len1, base2, len2)
+	} else {
+		ts.mergeHi(base1, len1, base2, len2)
+	}
+}
+
+// gallopLeft locates the leftmost position in data[base:base+length] at which
+// key could be inserted, starting the search at hint
+func gallopLeft(key int, data []int, base, length, hint int) int {
+	lastOfs, ofs := 0, 1
+	if key > data[base+hint] {
+		// Gallop right until data[base+hint+lastOfs] < key <= data[base+hint+ofs]
+		maxOfs := length - hint
+		for ofs < maxOfs && key > data[base+hint+ofs] {
+			lastOfs = ofs
+			ofs = ofs<<1 + 1
+		}
+		ofs = min(ofs, maxOfs)
+		lastOfs += hint
+		ofs += hint
+	} else {
+		// Gallop left until data[base+hint-ofs] < key <= data[base+hint-lastOfs]
+		maxOfs := hint + 1
+		for ofs < maxOfs && key <= data[base+hint-ofs] {
+			lastOfs = ofs
+			ofs = ofs<<1 + 1
+		}
+		ofs = min(ofs, maxOfs)
+		lastOfs, ofs = hint-ofs, hint-lastOfs
+	}
+
+	// Binary search within data[base+lastOfs+1:base+ofs]
+	lastOfs++
+	for lastOfs < ofs {
+		m := lastOfs + (ofs-lastOfs)>>1
+		if key > data[base+m] {
+			lastOfs = m + 1
+		} else {
+			ofs = m
+		}
+	}
+	return ofs
+}
+
+// gallopRight is like gallopLeft but returns the rightmost insertion point,
+// i.e. after any elements equal to key
+func gallopRight(key int, data []int, base, length, hint int) int {
+	lastOfs, ofs := 0, 1
+	if key < data[base+hint] {
+		maxOfs := hint + 1
+		for ofs < maxOfs && key < data[base+hint-ofs] {
+			lastOfs = ofs
+			ofs = ofs<<1 + 1
+		}
+		ofs = min(ofs, maxOfs)
+		lastOfs, ofs = hint-ofs, hint-lastOfs
+	} else {
+		maxOfs := length - hint
+		for ofs < maxOfs && key >= data[base+hint+ofs] {
+			lastOfs = ofs
+			ofs = ofs<<1 + 1
+		}
+		ofs = min(ofs, maxOfs)
+		lastOfs += hint
+		ofs += hint
+	}
+
+	lastOfs++
+	for lastOfs < ofs {
+		m := lastOfs + (ofs-lastOfs)>>1
+		if key < data[base+m] {
+			ofs = m
+		} else {
+			lastOfs = m + 1
+		}
+	}
+	return ofs
+}
+
+// ensureCapacity returns a scratch buffer of at least n elements
+func (ts *timSorter) ensureCapacity(n int) []int {
+	if cap(ts.tmp) < n {
+		ts.tmp = make([]int, max(n, min(2*cap(ts.tmp), len(ts.data)/2)))
+	}
+	return ts.tmp[:n]
+}
+
+// mergeLo merges two adjacent runs in place when the first is no longer than
+// the second, copying the first run into the scratch buffer
+func (ts *timSorter) mergeLo(base1, len1, base2, len2 int) {
+	data := ts.data
+	tmp := ts.ensureCapacity(len1)
+	copy(tmp, data[base1:base1+len1])
+
+	cursor1, cursor2, dest := 0, base2, base1
+
+	data[dest] = data[cursor2]
+	dest++
+	cursor2++
+	len2--
+	if len2 == 0 {
+		copy(data[dest:dest+len1], tmp[cursor1:cursor1+len1])
+		return
+	}
+	if len1 == 1 {
+		copy(data[dest:dest+len2], data[cursor2:cursor2+len2])
+		data[dest+len2] = tmp[cursor1]
+		return
+	}
+
+	minGallop := ts.minGallop
+outer:
+	for {
+		count1, count2 := 0, 0
+
+		// Merge one element at a time until one run starts winning consistently
+		for {
+			if data[cursor2] < tmp[cursor1] {
+				data[dest] = data[cursor2]
+				dest++
+				cursor2++
+				count2++
+				count1 = 0
+				len2--
+				if len2 == 0 {
+					break outer
+				}
+			} else {
+				data[dest] = tmp[cursor1]
+				dest++
+				cursor1++
+				count1++
+				count2 = 0
+				len1--
+				if len1 == 1 {
+					break outer
+				}
+			}
+			if count1|count2 >= minGallop {
+				break
+			}
+		}
+
+		// Gallop until neither run is winning consistently anymore
+		for {
+			count1 = gallopRight(data[cursor2], tmp, cursor1, len1, 0)
+			if count1 != 0 {
+				copy(data[dest:dest+count1], tmp[cursor1:cursor1+count1])
+				dest += count1
+				cursor1 += count1
+				len1 -= count1
+				if len1 <= 1 {
+					break outer
+				}
+			}
+			data[dest] = data[cursor2]
+			dest++
+			cursor2++
+			len2--
+			if len2 == 0 {
+				break outer
+			}
+
+			count2 = gallopLeft(tmp[cursor1], data, cursor2, len2, 0)
+			if count2 != 0 {
+				copy(data[dest:dest+count2], data[cursor2:cursor2+count2])
+				dest += count2
+				cursor2 += count2
+				len2 -= count2
+				if len2 == 0 {
+					break outer
+				}
+			}
+			data[dest] = tmp[cursor1]
+			dest++
+			cursor1++
+			len1--
+			if len1 == 1 {
+				break outer
+			}
+
+			minGallop--
+			if count1 < timsortMinGallop && count2 < timsortMinGallop {
+				break
+			}
+		}
+
+		// Penalize leaving galloping mode
+		minGallop = max(minGallop, 0) + 2
+	}
+	ts.minGallop = max(minGallop, 1)
+
+	if len1 == 1 {
+		copy(data[dest:dest+len2], data[cursor2:cursor2+len2])
+		data[dest+len2] = tmp[cursor1]
+	} else if len1 == 0 {
+		panic("timsort: comparison violates its general contract")
+	} else {
+		copy(data[dest:dest+len1], tmp[cursor1:cursor1+len1])
+	}
+}
+
+// mergeHi is the mirror image of mergeLo, used when the first run is longer.
+// It copies the second run into the scratch buffer and merges from the end.
+func (ts *timSorter) mergeHi(base1, len1, base2, len2 int) {
+	data := ts.data
+	tmp := ts.ensureCapacity(len2)
+	copy(tmp, data[base2:base2+len2])
+
+	cursor1, cursor2, dest := base1+len1-1, len2-1, base2+len2-1
+
+	data[dest] = data[cursor1]
+	dest--
+	cursor1--
+	len1--
+	if len1 == 0 {
+		copy(data[dest-(len2-1):dest+1], tmp[:len2])
+		return
+	}
+	if len2 == 1 {
+		dest -= len1
+		cursor1 -= len1
+		copy(data[dest+1:dest+1+len1], data[cursor1+1:cursor1+1+len1])
+		data[dest] = tmp[cursor2]
+		return
+	}
+
+	minGallop := ts.minGallop
+outer:
+	for {
+		count1, count2 := 0, 0
+
+		for {
+			if tmp[cursor2] < data[cursor1] {
+				data[dest] = data[cursor1]
+				dest--
+				cursor1--
+				count1++
+				count2 = 0
+				len1--
+				if len1 == 0 {
+					break outer
+				}
+			} else {
+				data[dest] = tmp[cursor2]
+				dest--
+				cursor2--
+				count2++
+				count1 = 0
+				len2--
+				if len2 == 1 {
+					break outer
+				}
+			}
+			if count1|count2 >= minGallop {
+				break
+			}
+		}
+
+		for {
+			count1 = len1 - gallopRight(tmp[cursor2], data, base1, len1, len1-1)
+			if count1 != 0 {
+				dest -= count1
+				cursor1 -= count1
+				len1 -= count1
+				copy(data[dest+1:dest+1+count1], data[cursor1+1:cursor1+1+count1])
+				if len1 == 0 {
+					break outer
+				}
+			}
+			data[dest] = tmp[cursor2]
+			dest--
+			cursor2--
+			len2--
+			if len2 == 1 {
+				break outer
+			}
+
+			count2 = len2 - gallopLeft(data[cursor1], tmp, 0, len2, len2-1)
+			if count2 != 0 {
+				dest -= count2
+				cursor2 -= count2
+				len2 -= count2
+				copy(data[dest+1:dest+1+count2], tmp[cursor2+1:cursor2+1+count2])
+				if len2 <= 1 {
+					break outer
+				}
+			}
+			data[dest] = data[cursor1]
+			dest--
+			cursor1--
+			len1--
+			if len1 == 0 {
+				break outer
+			}
+
+			minGallop--
+			if count1 < timsortMinGallop && count2 < timsortMinGallop {
+				break
+			}
+		}
+
+		minGallop = max(minGallop, 0) + 2
+	}
+	ts.minGallop = max(minGallop, 1)
+
+	if len2 == 1 {
+		dest -= len1
+		cursor1 -= len1
+		copy(data[dest+1:dest+1+len1], data[cursor1+1:cursor1+1+len1])
+		data[dest] = tmp[cursor2]
+	} else if len2 == 0 {
+		panic("timsort: comparison violates its general contract")
+	} else {
+		copy(data[dest-(len2-1):dest+1], tmp[:len2])
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// timsortInputs returns named inputs that exercise timsort's run detection,
+// galloping and merge paths
+func timsortInputs(n int, rng *rand.Rand) map[string][]int {
+	random := make([]int, n)
+	fewUnique := make([]int, n)
+	ascending := make([]int, n)
+	descending := make([]int, n)
+	sawtooth := make([]int, n)
+	organPipe := make([]int, n)
+	equal := make([]int, n)
+	for i := 0; i < n; i++ {
+		random[i] = rng.Intn(1 << 20)
+		fewUnique[i] = rng.Intn(4)
+		ascending[i] = i
+		descending[i] = n - i
+		sawtooth[i] = i % 97
+		organPipe[i] = min(i, n-i)
+		equal[i] = 7
+	}
+
+	// Long sorted runs with a few random elements sprinkled in, which is
+	// where galloping kicks in
+	nearlySorted := slices.Clone(ascending)
+	for i := 0; n > 0 && i < n/50+1; i++ {
+		nearlySorted[rng.Intn(n)] = rng.Intn(n)
+	}
+
+	// Ascending blocks in descending order of block
+	blocks := make([]int, 0, n)
+	for start := n; len(blocks) < n; start -= 1000 {
+		for i := 0; i < 1000 && len(blocks) < n; i++ {
+			blocks = append(blocks, start+i)
+		}
+	}
+
+	return map[string][]int{
+		"random":        random,
+		"few unique":    fewUnique,
+		"ascending":     ascending,
+		"descending":    descending,
+		"sawtooth":      sawtooth,
+		"organ pipe":    organPipe,
+		"all equal":     equal,
+		"nearly sorted": nearlySorted,
+		"blocks":        blocks,
+	}
+}
+
+func TestTimSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sizes := []int{0, 1, 2, 3, 31, 32, 33, 63, 64, 65, 100, 1000, 4097, 50000}
+	for _, n := range sizes {
+		for name, input := range timsortInputs(n, rng) {
+			expected := slices.Clone(input)
+			slices.Sort(expected)
+
+			got := slices.Clone(input)
+			timSort(got)
+			if !slices.Equal(got, expected) {
+				t.Errorf("timSort(%s, n=%d) did not sort its input", name, n)
+			}
+		}
+	}
+}
+
+func TestMinRunLength(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{0, 0},
+		{31, 31},
+		{32, 16},
+		{33, 17},
+		{64, 16},
+		{65, 17},
+		{2112, 17},
+		{100000, 25},
+	}
+	for _, tt := range tests {
+		if got := minRunLength(tt.n); got != tt.want {
+			t.Errorf("minRunLength(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCountRunAndMakeAscending(t *testing.T) {
+	data := []int{5, 4, 3, 3, 1}
+	if got := countRunAndMakeAscending(data, 0, len(data)); got != 3 {
+		t.Fatalf("descending run length = %d, want 3", got)
+	}
+	if !slices.Equal(data[:3], []int{3, 4, 5}) {
+		t.Errorf("descending run not reversed: %v", data)
+	}
+
+	data = []int{1, 2, 2, 3, 0}
+	if got := countRunAndMakeAscending(data, 0, len(data)); got != 4 {
+		t.Errorf("ascending run length = %d, want 4", got)
+	}
+}
+
+func TestGallop(t *testing.T) {
+	data := []int{1, 2, 2, 2, 5, 8, 8, 13}
+	for hint := 0; hint < len(data); hint++ {
+		if got := gallopLeft(2, data, 0, len(data), hint); got != 1 {
+			t.Errorf("gallopLeft(2, hint=%d) = %d, want 1", hint, got)
+		}
+		if got := gallopRight(2, data, 0, len(data), hint); got != 4 {
+			t.Errorf("gallopRight(2, hint=%d) = %d, want 4", hint, got)
+		}
+		if got := gallopLeft(20, data, 0, len(data), hint); got != len(data) {
+			t.Errorf("gallopLeft(20, hint=%d) = %d, want %d", hint, got, len(data))
+		}
+		if got := gallopRight(0, data, 0, len(data), hint); got != 0 {
+			t.Errorf("gallopRight(0, hint=%d) = %d, want 0", hint, got)
+		}
+	}
+}
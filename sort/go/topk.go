@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// defaultTopK is used when config.json doesn't set topK
+const defaultTopK = 100
+
+// Both top-K sorts leave the k largest elements of data in ascending order
+// in its last k slots, with the rest of data in no particular order.
+
+// heapTopK keeps a min-heap of the k largest elements seen so far in the
+// tail of data, replacing its root whenever a larger element turns up. It
+// makes one pass over data and suits k much smaller than n.
+func heapTopK(data []int, k int) {
+	n := len(data)
+	k = min(k, n)
+	top := data[n-k:]
+	for i := k/2 - 1; i >= 0; i-- {
+		minSiftDown(top, i, k)
+	}
+
+	for i := 0; i < n-k; i++ {
+		if k > 0 && data[i] > top[0] {
+			data[i], top[0] = top[0], data[i]
+			minSiftDown(top, 0, k)
+		}
+	}
+
+	// Popping the min-heap yields descending order
+	for end := k - 1; end > 0; end-- {
+		top[0], top[end] = top[end], top[0]
+		minSiftDown(top, 0, end)
+	}
+	slices.Reverse(top)
+}
+
+func minSiftDown(data []int, root, n int) {
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && data[child+1] < data[child] {
+			child++
+		}
+		if data[root] <= data[child] {
+			return
+		}
+		data[root], data[child] = data[child], data[root]
+		root = child
+	}
+}
+
+// quickSelectTopK partitions data with quicksort's partition until the
+// boundary before the k largest is in place, then sorts only those k
+func quickSelectTopK(data []int, k int) {
+	n := len(data)
+	k = min(k, n)
+	if k == 0 {
+		return
+	}
+
+	target := n - k
+	lo, hi := 0, n-1
+	for hi-lo >= insertionSortCutoff {
+		p := partition(data, lo, hi)
+		if p == target {
+			break
+		}
+		if target < p {
+			hi = p - 1
+		} else {
+			lo = p + 1
+		}
+	}
+	if hi-lo < insertionSortCutoff {
+		insertionSortRange(data, lo, hi)
+	}
+
+	quickSort(data[target:])
+}
+
+// runTopKBenchmarks checks the tail of each output against the tail of the
+// fully sorted data
+func runTopKBenchmarks(config Config, data, expected []int, k int) {
+	k = min(k, len(data))
+	check := func(result []int) {
+		checkResults(result[len(result)-k:], expected[len(expected)-k:])
+	}
+
+	runAlgorithmWithCheck(config, "topk-heap", fmt.Sprintf("Heap top-K (k=%d)", k), data, func(data []int) {
+		heapTopK(data, k)
+	}, check)
+	runAlgorithmWithCheck(config, "topk-select", fmt.Sprintf("Quickselect top-K (k=%d)", k), data, func(data []int) {
+		quickSelectTopK(data, k)
+	}, check)
+}
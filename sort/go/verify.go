@@ -0,0 +1,73 @@
+package main
+
+import (
+	"cmp"
+	"math"
+)
+
+// verifyPolicies are the accepted values of the verify setting
+var verifyPolicies = []string{"every", "once", "checksum", "off"}
+
+// verifyPolicy is the verify setting from config.json, defaulting to
+// "every"
+var verifyPolicy = "every"
+
+// shouldVerify reports whether the output of the given zero-based
+// iteration is checked under verifyPolicy
+func shouldVerify(iteration int) bool {
+	switch verifyPolicy {
+	case "off":
+		return false
+	case "once":
+		return iteration == 0
+	default:
+		return true
+	}
+}
+
+// checksumPrime is the multiplier of the polynomial rolling hash used by
+// checksum
+const checksumPrime = 1099511628211
+
+// checksum returns a rolling hash of data in order, so any two slices with
+// equal elements in the same order hash the same
+func checksum[T cmp.Ordered](data []T) uint64 {
+	var sum uint64
+	switch data := any(data).(type) {
+	case []int:
+		for _, v := range data {
+			sum = sum*checksumPrime + uint64(v)
+		}
+	case []int32:
+		for _, v := range data {
+			sum = sum*checksumPrime + uint64(v)
+		}
+	case []int64:
+		for _, v := range data {
+			sum = sum*checksumPrime + uint64(v)
+		}
+	case []uint64:
+		for _, v := range data {
+			sum = sum*checksumPrime + v
+		}
+	case []float64:
+		for _, v := range data {
+			if math.IsNaN(v) {
+				// All NaNs compare equal in checkResults
+				v = math.NaN()
+			}
+			sum = sum*checksumPrime + math.Float64bits(v)
+		}
+	case []string:
+		for _, v := range data {
+			for i := 0; i < len(v); i++ {
+				sum = sum*checksumPrime + uint64(v[i])
+			}
+			// Separate elements so "a", "bc" and "ab", "c" differ
+			sum = sum*checksumPrime + math.MaxUint64
+		}
+	default:
+		panic("checksum: unsupported element type")
+	}
+	return sum
+}
@@ -0,0 +1,105 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"jsconf/internal/bench"
+)
+
+// main exports runSortBenchmark and keeps the Go program alive so JS can
+// call it, the same way the ast-wasm build exposes the parser
+func main() {
+	bench.SetupLogging("text", false, false)
+	js.Global().Set("runSortBenchmark", js.FuncOf(runSortBenchmark))
+	select {}
+}
+
+// runSortBenchmark takes a config object with the same shape as
+// config.json and returns a promise for
+// [{ name, size, results: [{ name, median, mean, stddev, min, max, p90, p95,
+// p99, outliers, ciLow, ciHigh, ciRelativeWidth, timedOut, verifyError,
+// failure }] }] with times in milliseconds. Results that timed out, panicked
+// or failed verification only have name, median, timedOut, verifyError and
+// failure, which describes where they failed.
+// Progress is logged to the console as in the native build. Without a
+// dataset generator, data.json is read relative to the working directory,
+// which only works under Node.
+func runSortBenchmark(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeObject {
+		return js.ValueOf("Error: missing config argument")
+	}
+
+	configJSON := js.Global().Get("JSON").Call("stringify", args[0]).String()
+	config, err := parseConfig([]byte(configJSON))
+	if err != nil {
+		return js.ValueOf(fmt.Sprintf("Error: parsing config: %v", err))
+	}
+
+	var executor js.Func
+	executor = js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve, reject := promiseArgs[0], promiseArgs[1]
+		executor.Release()
+
+		// The suite blocks for as long as it runs, so it gets its own
+		// goroutine rather than holding up the JS event loop inside the
+		// callback
+		go func() {
+			defer func() {
+				// checkResults panics on a mismatch
+				if r := recover(); r != nil {
+					reject.Invoke(js.Global().Get("Error").New(fmt.Sprint(r)))
+				}
+			}()
+
+			sweep, err := runConfig(config)
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke(sweepToJS(sweep))
+		}()
+		return nil
+	})
+	return js.Global().Get("Promise").New(executor)
+}
+
+func sweepToJS(sweep []sizeResults) js.Value {
+	sizes := make([]interface{}, len(sweep))
+	for i, results := range sweep {
+		medians := make([]interface{}, len(results.results))
+		for j, m := range results.results {
+			result := map[string]interface{}{
+				"name":     m.name,
+				"median":   bench.Milliseconds(m.median),
+				"timedOut": m.timedOut,
+			}
+			if m.failure != nil {
+				result["verifyError"] = m.verifyError
+				result["failure"] = m.failure.String()
+			} else if !m.timedOut {
+				stats := m.stats
+				result["outliers"] = m.outliers
+				result["ciLow"] = bench.Milliseconds(m.ci.Low)
+				result["ciHigh"] = bench.Milliseconds(m.ci.High)
+				result["ciRelativeWidth"] = m.ci.RelativeWidth
+				result["mean"] = stats.Mean
+				result["stddev"] = stats.StdDev
+				result["min"] = stats.Min
+				result["max"] = stats.Max
+				result["p90"] = stats.P90
+				result["p95"] = stats.P95
+				result["p99"] = stats.P99
+			}
+			medians[j] = result
+		}
+		sizes[i] = map[string]interface{}{
+			"name":    results.name,
+			"size":    results.size,
+			"results": medians,
+		}
+	}
+	return js.ValueOf(sizes)
+}
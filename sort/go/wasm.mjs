@@ -0,0 +1,22 @@
+import { readFileSync } from "node:fs";
+import * as fs from "node:fs";
+import { dirname, join } from "node:path";
+import { fileURLToPath } from "node:url";
+
+const DIRNAME = dirname(fileURLToPath(import.meta.url));
+
+// wasm_exec.js falls back to a stub file system unless one is provided,
+// which the data.json fallback needs
+globalThis.fs = fs;
+await import("./wasm_exec.js");
+
+const go = new globalThis.Go();
+const { instance } = await WebAssembly.instantiate(
+  readFileSync(join(DIRNAME, "sort.wasm")),
+  go.importObject
+);
+go.run(instance);
+
+const config = JSON.parse(readFileSync(join(DIRNAME, "../config.json"), "utf-8"));
+await globalThis.runSortBenchmark(config);
+process.exit(0);
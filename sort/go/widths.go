@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// element is the set of fixed-width integer types the width benchmarks sort,
+// matching the typed arrays the JS comparison uses
+type element interface {
+	int32 | int64 | uint64
+}
+
+// convertInts converts the integer dataset to T. Values outside T's range
+// wrap, which still leaves a valid dataset to sort.
+func convertInts[T element](data []int) []T {
+	converted := make([]T, len(data))
+	for i, v := range data {
+		converted[i] = T(v)
+	}
+	return converted
+}
+
+// runWidthBenchmarks runs the generic comparison sorts on data converted to
+// T, so the only difference from the []int numbers is the element width
+func runWidthBenchmarks[T element](config Config, typeName string, ints []int) {
+	data := convertInts[T](ints)
+	expected := copySlice(data)
+	slices.Sort(expected)
+
+	runAlgorithm(config, "quick", fmt.Sprintf("Quicksort (%s)", typeName), data, expected, genericQuickSort[T])
+	runAlgorithm(config, "merge", fmt.Sprintf("Merge sort (%s)", typeName), data, expected, orderedMergeSort[T])
+	runAlgorithm(config, "heap", fmt.Sprintf("Heap sort (%s)", typeName), data, expected, orderedHeapSort[T])
+	runAlgorithm(config, "builtin", fmt.Sprintf("Built-in sort (%s)", typeName), data, expected, slices.Sort[[]T])
+}
@@ -1,12 +1,33 @@
-import { readFileSync } from 'fs';
+import { execFileSync } from 'child_process';
+import { readFileSync, writeFileSync } from 'fs';
+import { cpus, hostname, release, totalmem } from 'os';
 import { fileURLToPath } from 'url';
 import { dirname } from 'path';
 import { join } from 'path';
 
 const DIRNAME = dirname(fileURLToPath(import.meta.url));
 
-const data = JSON.parse(readFileSync(join(DIRNAME, '../data.json'), 'utf-8'));
+// SORT_DATA points at a dataset written by the Go harness's -emit-data, which
+// wraps the array with the generator settings that produced it
+const dataPath = process.env.SORT_DATA ?? join(DIRNAME, '../data.json');
+const dataFile = JSON.parse(readFileSync(dataPath, 'utf-8'));
+const data = Array.isArray(dataFile) ? dataFile : dataFile.data;
+let datasetDescription = `file ${dataPath}, ${data.length} elements`;
+if (!Array.isArray(dataFile)) {
+  const { generator, min, max, seed } = dataFile.dataset;
+  datasetDescription = `${generator}, ${data.length} elements in [${min}, ${max}), seed ${seed}`;
+  console.log(`Dataset: ${datasetDescription}`);
+}
 const config = JSON.parse(readFileSync(join(DIRNAME, '../config.json'), 'utf-8'));
+// BENCH_ITERATIONS overrides config.json, as it does for the Go harness
+if (process.env.BENCH_ITERATIONS) {
+  const iterations = Number(process.env.BENCH_ITERATIONS);
+  if (!Number.isInteger(iterations) || iterations < 1) {
+    throw new Error(`BENCH_ITERATIONS: expected an integer of at least 1, got ${process.env.BENCH_ITERATIONS}`);
+  }
+  console.error(`Overriding iterations with BENCH_ITERATIONS=${iterations}`);
+  config.iterations = iterations;
+}
 
 const expectedData = [...data].sort((a, b) => a > b ? 1 : -1);
 function checkResults(data) {
@@ -21,9 +42,58 @@ function checkResults(data) {
   }
 }
 
-function runBenchmark(name, cb) {
+// SORT_RESULTS, or BENCH_OUT as for the Go harness, writes the results
+// document described by results.schema.json, the same format as the Go
+// harness's -results
+const resultsPath = process.env.SORT_RESULTS ?? process.env.BENCH_OUT;
+const benchmarks = [];
+
+// The first SIGINT or SIGTERM stops the run after the current iteration,
+// leaving that benchmark out of the results, which are marked partial. A
+// second one quits at once.
+let interrupted = false;
+for (const signal of ['SIGINT', 'SIGTERM']) {
+  process.on(signal, () => {
+    if (interrupted) {
+      process.exit(1);
+    }
+    interrupted = true;
+    console.error('Interrupted, stopping after the benchmarks that completed; interrupt again to quit at once');
+  });
+}
+
+// Nearest-rank percentile, matching the Go harness
+function percentile(sorted, p) {
+  return sorted[Math.ceil(p * sorted.length) - 1];
+}
+
+function summarize(name, iterations) {
+  const sorted = [...iterations].sort((a, b) => a - b);
+  const mean = iterations.reduce((sum, d) => sum + d, 0) / iterations.length;
+  const variance = iterations.reduce((sum, d) => sum + (d - mean) ** 2, 0) / Math.max(iterations.length - 1, 1);
+  return {
+    name,
+    iterations,
+    median: sorted[Math.floor(sorted.length / 2)],
+    mean,
+    stddev: Math.sqrt(variance),
+    min: sorted[0],
+    max: sorted[sorted.length - 1],
+    p90: percentile(sorted, 0.9),
+    p95: percentile(sorted, 0.95),
+    p99: percentile(sorted, 0.99),
+  };
+}
+
+// runBenchmark yields to the event loop between iterations, outside the
+// timed region, so signals are handled while the suite runs
+async function runBenchmark(name, cb) {
   const iterations = [];
   for (let i = 0; i < config.iterations; i++) {
+    await new Promise(resolve => setImmediate(resolve));
+    if (interrupted) {
+      return;
+    }
     const clonedData = [...data];
     const start = performance.now();
     cb(clonedData);
@@ -33,11 +103,13 @@ function runBenchmark(name, cb) {
     iterations.push(duration);
     console.log(`${name} iteration ${i + 1} completed in ${duration.toFixed(2)}ms`);
   }
-  console.log(`${name}: ${iterations.sort()[Math.floor(iterations.length / 2)].toFixed(2)}ms`);
+  const summary = summarize(name, iterations);
+  benchmarks.push(summary);
+  console.log(`${name}: ${summary.median.toFixed(2)}ms`);
 }
 
 // Bubble sort
-runBenchmark("Bubble sort", (data) => {
+await runBenchmark("Bubble sort", (data) => {
   let temp;
   for (let i = 0; i < data.length; i++) {
     for (let j = 0; j < data.length - i - 1; j++) {
@@ -51,7 +123,7 @@ runBenchmark("Bubble sort", (data) => {
 });
 
 // Radix sort
-runBenchmark("Radix sort", (data) => {
+await runBenchmark("Radix sort", (data) => {
   function countingSort(arr, exp) {
     const output = new Array(arr.length);
     const count = new Array(10).fill(0);
@@ -86,6 +158,51 @@ runBenchmark("Radix sort", (data) => {
 });
 
 // Built-in sort
-runBenchmark("Built-in sort", (data) => {
+await runBenchmark("Built-in sort", (data) => {
   data.sort((a, b) => a > b ? 1 : -1);
 });
+
+// source is the commit checked out here, or undefined outside a git checkout
+function source() {
+  const git = (...args) => execFileSync('git', args, { encoding: 'utf-8', stdio: ['ignore', 'pipe', 'ignore'] }).trim();
+  try {
+    const branch = git('rev-parse', '--abbrev-ref', 'HEAD');
+    return {
+      commit: git('rev-parse', 'HEAD'),
+      dirty: git('status', '--porcelain') !== '' || undefined,
+      branch: branch !== 'HEAD' ? branch : undefined,
+    };
+  } catch {
+    return undefined;
+  }
+}
+
+if (resultsPath) {
+  const cpuList = cpus();
+  const results = {
+    schemaVersion: 1,
+    suite: 'sort',
+    language: 'js',
+    createdAt: new Date().toISOString().replace(/\.\d+Z$/, 'Z'),
+    machine: {
+      os: process.platform,
+      arch: process.arch,
+      cpus: cpuList.length,
+      cpu: cpuList[0]?.model,
+      memoryBytes: totalmem(),
+      kernel: release(),
+      runtime: `node ${process.version}`,
+      hostname: hostname(),
+    },
+    source: source(),
+    execution: 'serial',
+    partial: interrupted || undefined,
+    config,
+    runs: [{ size: data.length, dataset: datasetDescription, benchmarks }],
+  };
+  writeFileSync(resultsPath, JSON.stringify(results, null, 2) + '\n');
+}
+if (interrupted) {
+  console.error('Error: interrupted');
+  process.exitCode = 1;
+}
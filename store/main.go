@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jsconf/internal/bench"
+)
+
+const usage = `Usage:
+  store record [flags] results.json...
+  store history [flags] <benchmark>
+
+record stores results files in an SQLite database, tagged with the current
+commit. history prints every recorded median of a benchmark, oldest first,
+with the change from the one before, to track trends across commits and
+runtimes.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "record":
+		err = runRecord(os.Args[2:])
+	case "history":
+		err = runHistory(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runRecord(args []string) error {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	dbPath := flags.String("db", "results.db", "SQLite database to record into, created if missing")
+	commit := flags.String("commit", "", "commit the results were measured at, defaulting to the one they record or else git rev-parse HEAD")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("record: expected one or more results files")
+	}
+
+	db, err := openStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	recordedAt := time.Now().UTC().Format(time.RFC3339)
+	for _, path := range flags.Args() {
+		results, err := bench.ReadResults(path)
+		if err != nil {
+			return err
+		}
+		resultsCommit := *commit
+		if resultsCommit == "" && results.Source != nil {
+			resultsCommit = results.Source.Commit
+		}
+		if resultsCommit == "" {
+			resultsCommit = currentCommit()
+		}
+		id, err := record(db, results, resultsCommit, recordedAt)
+		if err != nil {
+			return fmt.Errorf("recording %s: %w", path, err)
+		}
+		fmt.Printf("Recorded %s as run %d\n", path, id)
+	}
+	return nil
+}
+
+// currentCommit is the commit checked out in the working directory, or ""
+// outside a git repository
+func currentCommit() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func runHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	dbPath := flags.String("db", "results.db", "SQLite database to read")
+	var filter historyFilter
+	flags.StringVar(&filter.suite, "suite", "", "only show this suite, e.g. sort or ast")
+	flags.StringVar(&filter.language, "language", "", "only show this implementation language, e.g. go or js")
+	flags.IntVar(&filter.size, "size", 0, "only show runs at this dataset size")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("history: expected a benchmark name, e.g. \"Bubble sort\"")
+	}
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		return err
+	}
+	db, err := openStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entries, err := history(db, flags.Arg(0), filter)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no recorded runs of %q", flags.Arg(0))
+	}
+	writeHistory(os.Stdout, entries)
+	return nil
+}
+
+// writeHistory writes a row per entry, with each median's change from the
+// previous one of the same language and run
+func writeHistory(w io.Writer, entries []entry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Recorded\tCommit\tLanguage\tRuntime\tRun\tMedian\tChange\t")
+	previous := map[string]sql.NullFloat64{}
+	for _, e := range entries {
+		median, change := "-", "-"
+		switch {
+		case e.timedOut:
+			median = "timed out"
+		case e.verifyError != "":
+			median = "failed"
+		case e.median.Valid:
+			median = bench.FormatMilliseconds(e.median.Float64)
+		}
+
+		key := e.language + "\x00" + e.run
+		if last := previous[key]; last.Valid && e.median.Valid && last.Float64 > 0 {
+			change = fmt.Sprintf("%+.1f%%", (e.median.Float64/last.Float64-1)*100)
+		}
+		previous[key] = e.median
+
+		commit := e.commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", e.recordedAt, commit, e.language, e.runtime, e.run, median, change)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"jsconf/internal/bench"
+
+	// Registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the tables on first use. Each recorded results document is
+// a row of runs, keeping the whole document, with a row of benchmarks per
+// benchmark in each of its runs for querying trends.
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY,
+	recorded_at TEXT NOT NULL,
+	created_at  TEXT NOT NULL,
+	commit_hash TEXT NOT NULL,
+	suite       TEXT NOT NULL,
+	language    TEXT NOT NULL,
+	runtime     TEXT NOT NULL,
+	os          TEXT NOT NULL,
+	arch        TEXT NOT NULL,
+	cpu         TEXT NOT NULL,
+	hostname    TEXT NOT NULL,
+	document    TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS benchmarks (
+	run_id       INTEGER NOT NULL REFERENCES runs(id),
+	dataset_name TEXT NOT NULL,
+	size         INTEGER NOT NULL,
+	dataset      TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	median       REAL,
+	mean         REAL,
+	stddev       REAL,
+	min          REAL,
+	max          REAL,
+	timed_out    INTEGER NOT NULL,
+	verify_error TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS benchmarks_by_name ON benchmarks(name);
+`
+
+// openStore opens the SQLite database at path, creating it and its tables if
+// needed
+func openStore(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating tables in %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// record stores results as run at commit, returning the new run's id
+func record(db *sql.DB, results bench.Results, commit, recordedAt string) (int64, error) {
+	document, err := json.Marshal(results)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	machine := results.Machine
+	res, err := tx.Exec(`INSERT INTO runs
+		(recorded_at, created_at, commit_hash, suite, language, runtime, os, arch, cpu, hostname, document)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		recordedAt, results.CreatedAt, commit, results.Suite, results.Language,
+		machine.Runtime, machine.OS, machine.Arch, machine.CPU, machine.Hostname, string(document))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO benchmarks
+		(run_id, dataset_name, size, dataset, name, median, mean, stddev, min, max, timed_out, verify_error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+	for _, run := range results.Runs {
+		for _, b := range run.Benchmarks {
+			// Statistics are left out of benchmarks that didn't complete
+			var median, mean, stddev, fastest, slowest sql.NullFloat64
+			if !b.TimedOut && b.Failed() == "" {
+				median, mean, stddev = validFloat(b.Median), validFloat(b.Mean), validFloat(b.StdDev)
+				fastest, slowest = validFloat(b.Min), validFloat(b.Max)
+			}
+			if _, err := insert.Exec(id, run.Name, run.Size, run.Dataset, b.Name,
+				median, mean, stddev, fastest, slowest, b.TimedOut, b.Failed()); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return id, tx.Commit()
+}
+
+func validFloat(f float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: f, Valid: true}
+}
+
+// historyFilter narrows a benchmark's history, with empty fields matching
+// anything
+type historyFilter struct {
+	suite    string
+	language string
+	// size is 0 to match every size
+	size int
+}
+
+// entry is one recorded run of a benchmark. median is null when it timed out
+// or failed verification.
+type entry struct {
+	recordedAt  string
+	commit      string
+	language    string
+	runtime     string
+	run         string
+	median      sql.NullFloat64
+	timedOut    bool
+	verifyError string
+}
+
+// history returns every recorded run of the named benchmark matching filter,
+// oldest first
+func history(db *sql.DB, name string, filter historyFilter) ([]entry, error) {
+	rows, err := db.Query(`SELECT r.recorded_at, r.commit_hash, r.suite, r.language, r.runtime,
+			b.dataset_name, b.size, b.dataset, b.median, b.timed_out, b.verify_error
+		FROM benchmarks b JOIN runs r ON r.id = b.run_id
+		WHERE b.name = ? AND (? = '' OR r.suite = ?) AND (? = '' OR r.language = ?) AND (? = 0 OR b.size = ?)
+		ORDER BY r.recorded_at, r.id, b.size`,
+		name, filter.suite, filter.suite, filter.language, filter.language, filter.size, filter.size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		var suite string
+		var run bench.ResultsRun
+		if err := rows.Scan(&e.recordedAt, &e.commit, &suite, &e.language, &e.runtime,
+			&run.Name, &run.Size, &run.Dataset, &e.median, &e.timedOut, &e.verifyError); err != nil {
+			return nil, err
+		}
+		e.run = run.Title(suite)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
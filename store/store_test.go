@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jsconf/internal/bench"
+)
+
+func TestRecordHistory(t *testing.T) {
+	db, err := openStore(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	results := func(language string, median float64) bench.Results {
+		return bench.Results{Suite: "sort", Language: language, Runs: []bench.ResultsRun{{Size: 1000, Benchmarks: []bench.BenchmarkSummary{
+			{Name: "Bubble sort", Median: median},
+			{Name: "Quicksort", Median: 1},
+		}}}}
+	}
+	for i, r := range []bench.Results{results("go", 4), results("js", 8), results("go", 5)} {
+		if _, err := record(db, r, "abc", fmt.Sprintf("2025-10-%02d", i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := history(db, "Bubble sort", historyFilter{language: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].median.Float64 != 4 || entries[1].median.Float64 != 5 {
+		t.Fatalf("history = %+v, want the go medians 4 then 5", entries)
+	}
+	if entries[0].run != "sort, 1000 elements" {
+		t.Errorf("run = %q, want %q", entries[0].run, "sort, 1000 elements")
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	entries := []entry{
+		{recordedAt: "2025-10-01", commit: "0123456789abcdef", language: "go", runtime: "go1.25.1", run: "sort", median: sql.NullFloat64{Float64: 4, Valid: true}},
+		{recordedAt: "2025-10-02", commit: "fedcba9876543210", language: "go", runtime: "go1.25.1", run: "sort", median: sql.NullFloat64{Float64: 5, Valid: true}},
+		{recordedAt: "2025-10-03", commit: "fedcba9876543210", language: "go", runtime: "go1.25.1", run: "sort", timedOut: true},
+	}
+	var out strings.Builder
+	writeHistory(&out, entries)
+	want := `Recorded    Commit        Language  Runtime   Run   Median     Change  
+2025-10-01  0123456789ab  go        go1.25.1  sort  4.00ms     -       
+2025-10-02  fedcba987654  go        go1.25.1  sort  5.00ms     +25.0%  
+2025-10-03  fedcba987654  go        go1.25.1  sort  timed out  -       
+`
+	if out.String() != want {
+		t.Errorf("writeHistory wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}